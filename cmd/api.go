@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/cmd/app"
+)
+
+var apiListen, apiToken, apiMetrics string
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "启动本地 REST API 服务",
+	Long: `使用 dedao-dl api 启动本地 REST API 服务，供桌面客户端或其他工具调用
+可查询课程、文章、电子书、话题，提交异步下载任务并通过 Server-Sent Events 获取进度
+请求需携带 Authorization: Bearer <token>，接口说明见 docs/api.md`,
+	Example: "dedao-dl api --listen 127.0.0.1:8090 --token secret",
+	Args:    cobra.NoArgs,
+	PreRunE: AuthFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := &app.APIServer{
+			Listen:  apiListen,
+			Token:   apiToken,
+			Metrics: apiMetrics,
+		}
+		return s.Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(apiCmd)
+
+	apiCmd.Flags().StringVarP(&apiListen, "listen", "l", "127.0.0.1:8090", "监听地址")
+	apiCmd.Flags().StringVar(&apiToken, "token", "", "访问令牌, 为空时随机生成")
+	apiCmd.Flags().StringVar(&apiMetrics, "metrics", "", "Prometheus 指标监听地址, 如 127.0.0.1:9090, 为空时不启用")
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// APIServer 本地 REST API 服务，接口说明见 docs/api.md
+type APIServer struct {
+	Listen  string
+	Token   string // 访问令牌，为空时随机生成
+	Metrics string // Prometheus 指标监听地址，为空时不启用
+
+	jobs *JobQueue
+}
+
+// Run 启动 API 服务
+func (s *APIServer) Run() error {
+	if s.Token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		s.Token = hex.EncodeToString(buf)
+		fmt.Printf("未指定 --token，已生成访问令牌：%s\n", s.Token)
+	}
+	s.jobs = NewJobQueue()
+	if s.Metrics != "" {
+		ServeMetrics(s.Metrics)
+	}
+	defer startWebhooks()()
+
+	fmt.Printf("API 服务已启动：http://%s/api/v1\n", s.Listen)
+	return http.ListenAndServe(s.Listen, s.Handler())
+}
+
+// Handler API 路由
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/categories", s.handleCategories)
+	mux.HandleFunc("GET /api/v1/courses", s.handleCourses)
+	mux.HandleFunc("GET /api/v1/courses/{id}", s.handleCourse)
+	mux.HandleFunc("GET /api/v1/courses/{id}/articles", s.handleArticles)
+	mux.HandleFunc("GET /api/v1/ebooks", s.handleEbooks)
+	mux.HandleFunc("GET /api/v1/ebooks/{id}", s.handleEbook)
+	mux.HandleFunc("GET /api/v1/topics", s.handleTopics)
+	mux.HandleFunc("GET /api/v1/topics/{id}", s.handleTopic)
+	mux.HandleFunc("GET /api/v1/jobs", s.handleJobs)
+	mux.HandleFunc("POST /api/v1/jobs", s.handleSubmitJob)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", s.handleJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/cancel", s.handleCancelJob)
+	mux.HandleFunc("GET /api/v1/events", s.handleEvents)
+	return observeAPI(s.auth(mux))
+}
+
+// auth 校验 Authorization: Bearer <token>，EventSource 无法设置请求头，也可以使用 ?token=
+func (s *APIServer) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("token 错误"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *APIServer) handleCategories(w http.ResponseWriter, r *http.Request) {
+	list, err := CourseType()
+	writeResult(w, list, err)
+}
+
+func (s *APIServer) handleCourses(w http.ResponseWriter, r *http.Request) {
+	category := r.URL.Query().Get("category")
+	if category == "" {
+		category = CateCourse
+	}
+	switch category {
+	case CateCourse, CateAudioBook, CateEbook, CateAce:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("不支持的分类: %s", category))
+		return
+	}
+	list, err := CourseList(category)
+	writeResult(w, list, err)
+}
+
+func (s *APIServer) handleCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	info, err := CourseInfo(id)
+	writeResult(w, info, err)
+}
+
+func (s *APIServer) handleArticles(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	list, err := ArticleList(id, r.URL.Query().Get("chapter_id"))
+	writeResult(w, list, err)
+}
+
+func (s *APIServer) handleEbooks(w http.ResponseWriter, r *http.Request) {
+	list, err := CourseList(CateEbook)
+	writeResult(w, list, err)
+}
+
+func (s *APIServer) handleEbook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	detail, err := EbookDetail(id)
+	writeResult(w, detail, err)
+}
+
+func (s *APIServer) handleTopics(w http.ResponseWriter, r *http.Request) {
+	list, err := TopicAll()
+	writeResult(w, list, err)
+}
+
+func (s *APIServer) handleTopic(w http.ResponseWriter, r *http.Request) {
+	detail, err := TopicDetail(r.PathValue("id"))
+	writeResult(w, detail, err)
+}
+
+func (s *APIServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.List())
+}
+
+func (s *APIServer) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := jsoniter.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("请求参数错误: %w", err))
+		return
+	}
+	job, err := s.jobs.Submit(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *APIServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("任务不存在"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *APIServer) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Cancel(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleEvents 通过 Server-Sent Events 推送下载进度，?job= 只推送指定任务
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("不支持 Server-Sent Events"))
+		return
+	}
+	jobID := r.URL.Query().Get("job")
+	events := make(chan Event, 64)
+	unsubscribe := Subscribe(func(e Event) {
+		if jobID != "" && e.JobID != jobID {
+			return
+		}
+		// 客户端处理过慢时丢弃事件，避免阻塞下载
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e := <-events:
+			data, err := jsoniter.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, errors.New("ID 错误"))
+		return 0, false
+	}
+	return id, true
+}
+
+func writeResult(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := jsoniter.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("输出 JSON 失败: %v\n", err)
+	}
+}
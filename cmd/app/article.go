@@ -94,6 +94,24 @@ func ArticleCommentList(enId, sort string, page, limit int) (list *services.Arti
 
 }
 
+// ArticleComments 逐页获取文章留言，sort 为 like 或 create，limit 小于等于 0 时获取全部
+func ArticleComments(enId, sort string, limit int) (comments []services.ArticleComment, err error) {
+	for page := 1; ; page++ {
+		var list *services.ArticleCommentList
+		list, err = ArticleCommentList(enId, sort, page, commentPageSize)
+		if err != nil {
+			return
+		}
+		comments = append(comments, list.List...)
+		if limit > 0 && len(comments) >= limit {
+			return comments[:limit], nil
+		}
+		if list.IsMore == 0 || len(list.List) == 0 {
+			return
+		}
+	}
+}
+
 // OdobArticleInfo article info
 // get article token, audio token, media security token etc.
 func OdobArticleInfo(aEnid string) (info *services.ArticleInfo, err error) {
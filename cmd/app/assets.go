@@ -0,0 +1,231 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yann0917/dedao-dl/request"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// AssetsDir 图片目录，位于文章所在目录下
+const AssetsDir = "assets"
+
+// Assets 将文章中的图片下载到本地并改写图片地址，离线也能查看
+// 图片以地址的 MD5 命名，同一张图片只下载一次
+type Assets struct {
+	Dir     string // 图片保存目录
+	Ref     string // 改写后的图片地址前缀，markdown 使用相对路径，PDF 使用 file:// 绝对路径
+	Convert string // 将 webp、avif 转换为 jpg 或 png，为空时不转换
+
+	files   map[string]string // 图片地址 -> 文件名
+	missing []missingAsset
+}
+
+type missingAsset struct {
+	title string
+	url   string
+	err   error
+}
+
+// NewAssets 创建图片下载，图片保存在 dir 下的 assets 目录
+// absolute 为 true 时改写为 file:// 绝对路径，用于 wkhtmltopdf 生成 PDF
+func NewAssets(dir string, absolute bool, convert string) (*Assets, error) {
+	a := &Assets{
+		Dir:     filepath.Join(dir, AssetsDir),
+		Ref:     AssetsDir,
+		Convert: convert,
+		files:   make(map[string]string),
+	}
+	if absolute {
+		abs, err := filepath.Abs(a.Dir)
+		if err != nil {
+			return nil, err
+		}
+		a.Ref = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+	return a, os.MkdirAll(a.Dir, 0755)
+}
+
+// newLocalAssets 图片地址改写为相对于程序运行目录的路径，用于生成时嵌入图片的 EPUB 和单文件 HTML
+func newLocalAssets(dir, convert string) (*Assets, error) {
+	a, err := NewAssets(dir, false, convert)
+	if err != nil {
+		return nil, err
+	}
+	a.Ref = filepath.ToSlash(a.Dir)
+	return a, nil
+}
+
+// ImageFormat 校验图片转换格式，jpeg 视为 jpg
+func ImageFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "":
+		return "", nil
+	case "jpg", "jpeg":
+		return "jpg", nil
+	case "png":
+		return "png", nil
+	}
+	return "", fmt.Errorf("不支持的图片格式: %s，可选 jpg、png", format)
+}
+
+// Localize 下载文章中的图片并改写图片地址，下载失败的图片保留原地址
+func (a *Assets) Localize(title string, doc *services.Document) {
+	tasks := request.NewDownloadTasks()
+	pending := make(map[string]bool)
+	for _, block := range doc.Blocks {
+		img, ok := block.(services.ImageBlock)
+		if !ok || !strings.HasPrefix(img.URL, "http") {
+			continue
+		}
+		if _, ok := a.files[img.URL]; ok || pending[img.URL] {
+			continue
+		}
+		if name := a.existing(img.URL); name != "" {
+			a.files[img.URL] = name
+			continue
+		}
+		pending[img.URL] = true
+		tasks.Add(img.URL, filepath.Join(a.Dir, utils.MD5str(img.URL)+".download"))
+	}
+	request.Batch(tasks, 3, time.Minute*2).ForEach(func(t *request.DownloadTask) {
+		name, err := a.finish(t)
+		if err != nil {
+			a.missing = append(a.missing, missingAsset{title: title, url: t.Link, err: err})
+			return
+		}
+		a.files[t.Link] = name
+	})
+
+	for i, block := range doc.Blocks {
+		if img, ok := block.(services.ImageBlock); ok {
+			if name, ok := a.files[img.URL]; ok {
+				img.URL = a.Ref + "/" + name
+				doc.Blocks[i] = img
+			}
+		}
+	}
+}
+
+var srcAttrRegexp = regexp.MustCompile(`\bsrc="([^"]+)"`)
+
+// EmbedHTML 将 HTML 中已下载的图片改写为 data URI，用于单文件 HTML
+func (a *Assets) EmbedHTML(s string) string {
+	return srcAttrRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		src := html.UnescapeString(srcAttrRegexp.FindStringSubmatch(m)[1])
+		name, ok := strings.CutPrefix(src, a.Ref+"/")
+		if !ok {
+			return m
+		}
+		data, err := os.ReadFile(filepath.Join(a.Dir, name))
+		if err != nil {
+			return m
+		}
+		typ := mime.TypeByExtension(filepath.Ext(name))
+		if typ == "" {
+			typ = http.DetectContentType(data)
+		}
+		return `src="data:` + typ + ";base64," + base64.StdEncoding.EncodeToString(data) + `"`
+	})
+}
+
+// PrintSummary 输出下载失败的图片
+func (a *Assets) PrintSummary() {
+	if len(a.missing) == 0 {
+		return
+	}
+	fmt.Printf("\033[31;1m%d 张图片下载失败，文中保留原地址：\033[0m\n", len(a.missing))
+	for _, m := range a.missing {
+		fmt.Printf("  【%s】%s\n    错误：%v\n", m.title, m.url, m.err)
+	}
+}
+
+// existing 已下载的图片文件名
+func (a *Assets) existing(link string) string {
+	matches, _ := filepath.Glob(filepath.Join(a.Dir, utils.MD5str(link)+".*"))
+	for _, m := range matches {
+		if ext := filepath.Ext(m); ext != ".download" && ext != ".ok" {
+			return filepath.Base(m)
+		}
+	}
+	return ""
+}
+
+// finish 按文件内容确定扩展名，需要时转换格式
+func (a *Assets) finish(t *request.DownloadTask) (name string, err error) {
+	defer func() {
+		os.Remove(t.Path)         // nolint
+		os.Remove(t.Path + ".ok") // nolint
+	}()
+	if t.Err != nil {
+		return "", t.Err
+	}
+	ext, err := imageExt(t.Path, t.Link)
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimSuffix(t.Path, ".download")
+	if a.Convert != "" && (ext == ".webp" || ext == ".avif") {
+		dst := base + "." + a.Convert
+		if err = utils.ConvertImage(t.Path, dst); err == nil {
+			return filepath.Base(dst), nil
+		}
+		// 转换失败时保留原格式
+		fmt.Printf("\033[33;1m图片转换失败，保留 %s 格式：%s %v\033[0m\n", ext, t.Link, err)
+	}
+	if err = os.Rename(t.Path, base+ext); err != nil {
+		return "", err
+	}
+	return filepath.Base(base + ext), nil
+}
+
+// imageExt 根据文件内容判断图片格式，无法判断时使用地址中的扩展名
+func imageExt(file, link string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	head = head[:n]
+	if n == 0 {
+		return "", fmt.Errorf("图片为空")
+	}
+
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" && (string(head[8:12]) == "avif" || string(head[8:12]) == "avis") {
+		return ".avif", nil
+	}
+	switch http.DetectContentType(head) {
+	case "image/jpeg":
+		return ".jpg", nil
+	case "image/png":
+		return ".png", nil
+	case "image/gif":
+		return ".gif", nil
+	case "image/webp":
+		return ".webp", nil
+	case "image/bmp":
+		return ".bmp", nil
+	}
+	if bytes.Contains(head, []byte("<svg")) {
+		return ".svg", nil
+	}
+	if u, err := url.Parse(link); err == nil && path.Ext(u.Path) != "" {
+		return strings.ToLower(path.Ext(u.Path)), nil
+	}
+	return ".img", nil
+}
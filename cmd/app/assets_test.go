@@ -0,0 +1,175 @@
+package app
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+func TestImageExt(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		data, link, want string
+	}{
+		{"\x89PNG\r\n\x1a\n0000", "https://example.com/a.jpg", ".png"},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 0000", "https://example.com/a", ".webp"},
+		{"\x00\x00\x00\x1cftypavif0000", "https://example.com/a", ".avif"},
+		{"\x00\x00\x00\x1cftypavis0000", "https://example.com/a", ".avif"},
+		{`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`, "https://example.com/a", ".svg"},
+		{"unknown", "https://example.com/a.JPEG?x=1", ".jpeg"},
+		{"unknown", "https://example.com/a", ".img"},
+	}
+	for i, tt := range tests {
+		file := filepath.Join(dir, strings.Repeat("f", i+1))
+		if err := os.WriteFile(file, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := imageExt(file, tt.link); err != nil || got != tt.want {
+			t.Errorf("imageExt(%q) = %q, %v, want %q", tt.data, got, err, tt.want)
+		}
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imageExt(empty, ""); err == nil {
+		t.Error("empty image should fail")
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/a":
+			io.WriteString(w, "\x89PNG\r\n\x1a\n0000") // nolint
+		case "/b.webp":
+			io.WriteString(w, "RIFF\x00\x00\x00\x00WEBPVP8 0000") // nolint
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	a, err := NewAssets(t.TempDir(), false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 已下载的图片不再下载
+	cached := srv.URL + "/cached"
+	if err := os.WriteFile(filepath.Join(a.Dir, utils.MD5str(cached)+".gif"), []byte("GIF89a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc := &services.Document{Blocks: []services.Block{
+		services.ImageBlock{URL: srv.URL + "/a"},
+		services.ParagraphBlock{Runs: []services.Run{{Text: "正文"}}},
+		services.ImageBlock{URL: srv.URL + "/a", Legend: "同一张图"},
+		services.ImageBlock{URL: srv.URL + "/b.webp"},
+		services.ImageBlock{URL: cached},
+		services.ImageBlock{URL: srv.URL + "/missing.png"},
+		services.ImageBlock{URL: "assets/local.png"},
+	}}
+	a.Localize("文章", doc)
+
+	want := []string{
+		"assets/" + utils.MD5str(srv.URL+"/a") + ".png",
+		"",
+		"assets/" + utils.MD5str(srv.URL+"/a") + ".png",
+		"assets/" + utils.MD5str(srv.URL+"/b.webp") + ".webp",
+		"assets/" + utils.MD5str(cached) + ".gif",
+		srv.URL + "/missing.png",
+		"assets/local.png",
+	}
+	for i, block := range doc.Blocks {
+		if img, ok := block.(services.ImageBlock); ok && img.URL != want[i] {
+			t.Errorf("block %d url = %q, want %q", i, img.URL, want[i])
+		}
+	}
+	if hits["/a"] != 1 || hits["/cached"] != 0 {
+		t.Errorf("hits = %v", hits)
+	}
+	if _, err := os.Stat(filepath.Join(a.Dir, utils.MD5str(srv.URL+"/a")+".png")); err != nil {
+		t.Error(err)
+	}
+	// 下载的临时文件已删除
+	if matches, _ := filepath.Glob(filepath.Join(a.Dir, "*.download*")); len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+
+	// 第二篇文章使用同一张图片时不再下载
+	a.Localize("第二篇", &services.Document{Blocks: []services.Block{services.ImageBlock{URL: srv.URL + "/a"}}})
+	if hits["/a"] != 1 {
+		t.Errorf("image downloaded again: %v", hits)
+	}
+
+	if len(a.missing) != 1 || a.missing[0].title != "文章" || a.missing[0].url != srv.URL+"/missing.png" {
+		t.Fatalf("missing = %+v", a.missing)
+	}
+	out := captureStdout(t, a.PrintSummary)
+	for _, s := range []string{"1 张图片下载失败", "【文章】" + srv.URL + "/missing.png", "404"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("summary missing %q:\n%s", s, out)
+		}
+	}
+	if out := captureStdout(t, (&Assets{}).PrintSummary); out != "" {
+		t.Errorf("summary without missing images = %q", out)
+	}
+}
+
+// captureStdout 返回 f 输出到标准输出的内容
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
+
+func TestEmbedHTML(t *testing.T) {
+	a, err := newLocalAssets(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	gif := []byte("GIF89a0000")
+	if err := os.WriteFile(filepath.Join(a.Dir, "a.png"), png, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 没有扩展名时按内容判断类型
+	if err := os.WriteFile(filepath.Join(a.Dir, "b"), gif, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ in, want string }{
+		{`<img src="` + a.Ref + `/a.png" alt="图">`, `<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `" alt="图">`},
+		{`<img src="` + a.Ref + `/b">`, `<img src="data:image/gif;base64,` + base64.StdEncoding.EncodeToString(gif) + `">`},
+		{`<img src="` + a.Ref + `/missing.png">`, `<img src="` + a.Ref + `/missing.png">`},
+		{`<img src="https://example.com/a.png?x=1&amp;y=2">`, `<img src="https://example.com/a.png?x=1&amp;y=2">`},
+		{`<p>src="text"</p>`, `<p>src="text"</p>`},
+	}
+	for _, tt := range tests {
+		if got := a.EmbedHTML(tt.in); got != tt.want {
+			t.Errorf("EmbedHTML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+const (
+	// CommentsDirName 留言导出目录名
+	CommentsDirName = "Comments"
+	// CommentsAll 获取全部留言
+	CommentsAll = -1
+	// DefaultCommentLimit 未指定数量时获取的留言数量
+	DefaultCommentLimit = 20
+
+	commentPageSize = 20
+)
+
+// ParseCommentLimit 解析留言数量，all 为全部，为空时使用默认数量
+func ParseCommentLimit(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, nil
+	case "all":
+		return CommentsAll, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("留言数量错误: %s，可选 all 或正整数", s)
+	}
+	return n, nil
+}
+
+// ParseCommentSort 解析留言排序，like 按点赞数，create 按时间，为空时按点赞数
+func ParseCommentSort(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", "like":
+		return "like", nil
+	case "create":
+		return "create", nil
+	}
+	return "", fmt.Errorf("留言排序错误: %s，可选 like、create", s)
+}
+
+// fetchComments 获取文章留言，获取失败时提示并返回已获取的部分
+func fetchComments(enId, sort string, limit int) []services.ArticleComment {
+	if limit == 0 {
+		limit = DefaultCommentLimit
+	}
+	if sort == "" {
+		sort = "like"
+	}
+	comments, err := ArticleComments(enId, sort, limit)
+	if err != nil {
+		fmt.Printf("\033[33;1m获取留言失败：%v\033[0m\n", err)
+	}
+	return comments
+}
+
+// commentLikes 留言的点赞数，没有点赞时为空
+func commentLikes(c services.ArticleComment) string {
+	if c.NotesCount.LikeCount > 0 {
+		return fmt.Sprintf("（赞 %d）", c.NotesCount.LikeCount)
+	}
+	return ""
+}
+
+// CommentsExport 导出课程文章的留言，不包含文章内容
+type CommentsExport struct {
+	Task
+	ID      int
+	AID     int    // 只导出某篇文章的留言
+	Limit   int    // 每篇文章的留言数量，0 为默认数量，CommentsAll 为全部
+	Sort    string // like 或 create
+	Format  string // md 或 json
+	IsOrder bool
+}
+
+// JSONComments 一篇文章的留言
+type JSONComments struct {
+	Schema   string           `json:"schema"`
+	Version  int              `json:"version"`
+	Course   JSONCourseRef    `json:"course"`
+	Article  JSONArticleEntry `json:"article"`
+	Sort     string           `json:"sort"`
+	Comments []JSONComment    `json:"comments"`
+}
+
+// Download 每篇文章生成一个留言文件，留言会不断更新，已存在的文件也重新生成
+func (e *CommentsExport) Download() error {
+	course, err := CourseInfo(e.ID)
+	if err != nil {
+		return err
+	}
+	list, err := ArticleList(e.ID, "")
+	if err != nil {
+		return err
+	}
+	if e.Limit == 0 {
+		e.Limit = DefaultCommentLimit
+	}
+	if e.Sort == "" {
+		e.Sort = "like"
+	}
+	info := course.ClassInfo
+	path, err := utils.Mkdir(OutputDir, utils.FileName(info.Name, ""), CommentsDirName)
+	if err != nil {
+		return err
+	}
+	ext := "md"
+	if e.Format == "json" {
+		ext = "json"
+	}
+	for i, v := range list.List {
+		if e.AID > 0 && v.ID != e.AID {
+			continue
+		}
+		if err := e.canceled(); err != nil {
+			return err
+		}
+		name := ArticleFileTitle(v, e.IsOrder) + "." + ext
+		event := Event{
+			Kind:     KindCourse,
+			ID:       e.ID,
+			Enid:     info.Enid,
+			Title:    info.Name,
+			Author:   info.LecturerName,
+			Item:     v.Title,
+			ItemID:   v.ID,
+			ItemEnid: v.Enid,
+			Order:    v.OrderNum,
+			Format:   "comments",
+			Path:     filepath.Join(path, name),
+			Done:     i + 1,
+			Total:    len(list.List),
+		}
+		comments, err := ArticleComments(v.Enid, e.Sort, e.Limit)
+		if err != nil {
+			e.emitItem(event, err)
+			return err
+		}
+		if len(comments) == 0 {
+			fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "无留言")
+			event.Type = EventItemSkipped
+			e.emitItem(event, nil)
+			continue
+		}
+
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+		if ext == "json" {
+			err = writeJSONFile(event.Path, JSONComments{
+				Schema:   JSONSchemaComments,
+				Version:  JSONSchemaVersion,
+				Course:   JSONCourseRef{ID: info.ID, Enid: info.Enid, Name: info.Name},
+				Article:  JSONArticleEntry{ID: v.ID, Enid: v.Enid, Title: v.Title, ChapterID: v.ChapterID, OrderNum: v.OrderNum},
+				Sort:     e.Sort,
+				Comments: jsonComments(comments),
+			})
+		} else {
+			err = printResult(utils.WriteFileWithTrunc(event.Path, commentsMarkdown(v.Title, comments)))
+		}
+		e.emitItem(event, err)
+		if err != nil {
+			return err
+		}
+	}
+	if err := SaveCourseIndex(course, list, e.IsOrder); err != nil {
+		fmt.Printf("生成课程首页失败: %v\n", err)
+	}
+	return nil
+}
+
+// commentsMarkdown 留言文件，每条留言包含作者、点赞数、时间和讲师回复
+func commentsMarkdown(title string, comments []services.ArticleComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n共 %d 条留言\n", escapeInline(title), len(comments))
+	for _, c := range comments {
+		meta := []string{"**" + escapeInline(c.NotesOwner.Name) + "**"}
+		if c.NotesCount.LikeCount > 0 {
+			meta = append(meta, fmt.Sprintf("赞 %d", c.NotesCount.LikeCount))
+		}
+		if c.CreateTime > 0 {
+			meta = append(meta, utils.Unix2String(int64(c.CreateTime)))
+		}
+		fmt.Fprintf(&b, "\n---\n\n%s\n\n%s\n", strings.Join(meta, " · "), textToMarkdown(c.Note))
+		if c.CommentReply != "" {
+			fmt.Fprintf(&b, "\n> %s\n", escapeInline(c.CommentReplyUser.Name+"("+c.CommentReplyUser.Role+") 回复："+strings.ReplaceAll(c.CommentReply, "\n", " ")))
+		}
+	}
+	return b.String()
+}
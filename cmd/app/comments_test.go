@@ -0,0 +1,54 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestParseComments(t *testing.T) {
+	for s, want := range map[string]int{"": 0, "all": CommentsAll, "ALL": CommentsAll, "50": 50} {
+		if got, err := ParseCommentLimit(s); err != nil || got != want {
+			t.Errorf("ParseCommentLimit(%q) = %d, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"0", "-1", "many"} {
+		if _, err := ParseCommentLimit(s); err == nil {
+			t.Errorf("ParseCommentLimit(%q) should fail", s)
+		}
+	}
+	if sort, err := ParseCommentSort(""); err != nil || sort != "like" {
+		t.Errorf("ParseCommentSort = %q, %v", sort, err)
+	}
+	if _, err := ParseCommentSort("hot"); err == nil {
+		t.Error("ParseCommentSort(hot) should fail")
+	}
+}
+
+func TestCommentsMarkdown(t *testing.T) {
+	var c services.ArticleComment
+	c.NotesOwner.Name = "读者"
+	c.Note = "第一行\n第二行"
+	c.NotesCount.LikeCount = 12
+	c.CommentReply = "谢谢"
+	c.CommentReplyUser.Name = "讲师"
+	c.CommentReplyUser.Role = "作者"
+	md := commentsMarkdown("文章", []services.ArticleComment{c, {Note: "没有赞"}})
+	for _, want := range []string{"# 文章\n\n共 2 条留言\n", "**读者** · 赞 12\n\n第一行\n\n第二行\n", "> 讲师(作者) 回复：谢谢\n"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "赞 0") {
+		t.Errorf("zero likes should be omitted:\n%s", md)
+	}
+	if html := CommentsToHTML([]services.ArticleComment{c}); !strings.HasPrefix(html, "<h2>留言</h2>\n") || !strings.Contains(html, "读者：第一行\n第二行（赞 12）") {
+		t.Errorf("html = %s", html)
+	}
+	// 按时间排序时不是热门留言，标题不区分排序
+	if md := CommentsToMarkdown([]services.ArticleComment{c}, config.MarkdownOptions{}); !strings.HasPrefix(md, "## 留言\n\n") {
+		t.Errorf("comments heading = %q", md)
+	}
+}
@@ -75,3 +75,37 @@ func CourseDetail(category string, id int) (course *services.CourseV2, err error
 	course = detail
 	return
 }
+
+// CourseChapter 章节及其文章
+type CourseChapter struct {
+	Name     string // 没有章节信息的课程为空
+	Articles []services.ArticleIntro
+}
+
+// GroupByChapter 按 ChapterList 顺序将文章分组，章节内保持文章顺序，不属于任何章节的文章放在最后
+func GroupByChapter(chapters []services.Chapter, articles []services.ArticleIntro) (groups []CourseChapter) {
+	grouped := make(map[int][]services.ArticleIntro)
+	for _, article := range articles {
+		grouped[article.ChapterID] = append(grouped[article.ChapterID], article)
+	}
+	for _, chapter := range chapters {
+		if list := grouped[chapter.ID]; len(list) > 0 {
+			groups = append(groups, CourseChapter{Name: chapter.Name, Articles: list})
+			delete(grouped, chapter.ID)
+		}
+	}
+	var rest []services.ArticleIntro
+	for _, article := range articles {
+		if _, ok := grouped[article.ChapterID]; ok {
+			rest = append(rest, article)
+		}
+	}
+	if len(rest) > 0 {
+		name := ""
+		if len(groups) > 0 {
+			name = "其他"
+		}
+		groups = append(groups, CourseChapter{Name: name, Articles: rest})
+	}
+	return
+}
@@ -0,0 +1,388 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// CourseIndexName 课程目录下的首页文件名，不含扩展名
+const CourseIndexName = "index"
+
+// courseArticleExport 每篇文章生成一个文件的导出格式
+type courseArticleExport struct {
+	Name string
+	Dir  string
+	Ext  string
+}
+
+var courseArticleExports = []courseArticleExport{
+	{"Markdown", "MD", "md"},
+	{"PDF", "PDF", "pdf"},
+	{"Word", DocxDirName, "docx"},
+	{"JSON", JSONDirName, "json"},
+	{"MP3", "MP3", "mp3"},
+	{"留言", CommentsDirName, "md"},
+	{"留言 JSON", CommentsDirName, "json"},
+}
+
+// courseFileExts 首页中列出的整门课程文件的扩展名
+var courseFileExts = []string{".md", ".pdf", ".docx", ".epub", ".html", ".json", ".jsonl"}
+
+// CourseIndex 课程首页的数据
+type CourseIndex struct {
+	Class      services.ClassInfo
+	Status     string // 已完结或更新中
+	Logo       string // 图片地址，已下载时为相对于课程目录的路径
+	SquareImg  string
+	Avatar     string
+	Intro      *CourseIndexArticle // 发刊词
+	Chapters   []CourseIndexChapter
+	Files      []CourseIndexLink // 整门课程的文件，如 EPUB、合并的 PDF、重点摘要
+	Articles   int               // 文章总数
+	Downloaded int               // 已导出文件的文章数
+}
+
+// CourseIndexChapter 章节及其文章，没有章节信息的课程 Name 为空
+type CourseIndexChapter struct {
+	Name     string
+	Articles []CourseIndexArticle
+}
+
+// CourseIndexArticle 文章及其已导出的文件
+type CourseIndexArticle struct {
+	Title   string
+	Summary string
+	Links   []CourseIndexLink
+}
+
+// CourseIndexLink 课程目录下的文件
+type CourseIndexLink struct {
+	Name string
+	URL  string // 相对于课程目录，已转义
+}
+
+// BuildCourseIndex 根据元数据和课程目录下已导出的文件生成首页数据
+func BuildCourseIndex(dir string, meta *CourseMeta) *CourseIndex {
+	info := meta.ClassInfo
+	index := &CourseIndex{
+		Class:     info,
+		Status:    "更新中",
+		Logo:      info.Logo,
+		SquareImg: info.SquareImg,
+		Avatar:    info.LecturerAvatar,
+		Articles:  len(meta.Articles),
+	}
+	if info.IsFinished == 1 {
+		index.Status = "已完结"
+	}
+
+	linked := make(map[string]bool)
+	article := func(v services.ArticleIntro) CourseIndexArticle {
+		a := CourseIndexArticle{Title: v.Title, Summary: v.Summary}
+		for _, e := range courseArticleExports {
+			for _, isOrder := range []bool{meta.IsOrder, !meta.IsOrder} {
+				rel := filepath.Join(e.Dir, ArticleFileTitle(v, isOrder)+"."+e.Ext)
+				if utils.CheckFileExist(filepath.Join(dir, rel)) {
+					a.Links = append(a.Links, courseIndexLink(e.Name, rel))
+					linked[rel] = true
+					break
+				}
+			}
+		}
+		return a
+	}
+
+	// 发刊词优先使用课程信息中的文章，旧版本的元数据使用 IntroArticleIds
+	articles := meta.SortedArticles()
+	intro := meta.IntroArticle
+	for i, v := range articles {
+		if (intro != nil && v.ID == intro.ID) || (intro == nil && slices.Contains(info.IntroArticleIds, v.ID)) {
+			intro = &v
+			// 发刊词单独列出，不放在目录中
+			articles = slices.Delete(articles, i, i+1)
+			break
+		}
+	}
+	if intro != nil {
+		a := article(*intro)
+		if len(a.Links) > 0 {
+			index.Downloaded++
+		}
+		index.Intro = &a
+	}
+
+	for _, group := range GroupByChapter(meta.ChapterList, articles) {
+		chapter := CourseIndexChapter{Name: group.Name}
+		for _, v := range group.Articles {
+			a := article(v)
+			if len(a.Links) > 0 {
+				index.Downloaded++
+			}
+			chapter.Articles = append(chapter.Articles, a)
+		}
+		index.Chapters = append(index.Chapters, chapter)
+	}
+
+	if moc := filepath.Join(ObsidianDirName, utils.FileName(obsidianNote(info.Name, 0), "md")); utils.CheckFileExist(filepath.Join(dir, moc)) {
+		index.Files = append(index.Files, courseIndexLink("Obsidian", moc))
+		linked[moc] = true
+	}
+	index.Files = append(index.Files, courseFiles(dir, linked)...)
+	return index
+}
+
+// courseFiles 课程目录和各格式目录下没有对应文章的文件，Obsidian 笔记和 MP3 只链接到文章
+func courseFiles(dir string, linked map[string]bool) (links []CourseIndexLink) {
+	for _, sub := range []string{"", "PDF", "MD", DocxDirName, EpubDirName, HTMLDirName, JSONDirName} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			rel := filepath.Join(sub, name)
+			if entry.IsDir() || strings.HasPrefix(name, ".") || linked[rel] || !slices.Contains(courseFileExts, filepath.Ext(name)) {
+				continue
+			}
+			if sub == "" && (name == MetaFileName || strings.TrimSuffix(name, filepath.Ext(name)) == CourseIndexName) {
+				continue
+			}
+			links = append(links, courseIndexLink(rel, rel))
+		}
+	}
+	return
+}
+
+func courseIndexLink(name, rel string) CourseIndexLink {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return CourseIndexLink{Name: filepath.ToSlash(name), URL: strings.Join(parts, "/")}
+}
+
+// localizeArtwork 下载封面和讲师头像到课程目录的 assets 下，下载失败时保留原地址
+func (c *CourseIndex) localizeArtwork(dir string) error {
+	assets, err := NewAssets(dir, false, "")
+	if err != nil {
+		return err
+	}
+	images := []*string{&c.Logo, &c.SquareImg, &c.Avatar}
+	doc := &services.Document{}
+	for _, img := range images {
+		doc.Blocks = append(doc.Blocks, services.ImageBlock{URL: *img})
+	}
+	assets.Localize(c.Class.Name, doc)
+	assets.PrintSummary()
+	for i, img := range images {
+		*img = doc.Blocks[i].(services.ImageBlock).URL
+	}
+	return nil
+}
+
+// WriteCourseIndex 在课程目录生成 index.md 和 index.html，包含课程信息、发刊词、章节目录和已导出文件的链接
+func WriteCourseIndex(dir string, meta *CourseMeta) error {
+	index := BuildCourseIndex(dir, meta)
+	if err := index.localizeArtwork(dir); err != nil {
+		return err
+	}
+	md, err := index.Markdown()
+	if err != nil {
+		return err
+	}
+	page, err := index.HTML()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", filepath.Join(filepath.Base(dir), CourseIndexName+".md"))
+	err = utils.WriteFileWithTrunc(filepath.Join(dir, CourseIndexName+".md"), md)
+	if err == nil {
+		err = utils.WriteFileWithTrunc(filepath.Join(dir, CourseIndexName+".html"), page)
+	}
+	return printResult(err)
+}
+
+// SaveCourseIndex 下载完成后更新课程首页
+func SaveCourseIndex(course *services.CourseInfo, articles *services.ArticleList, isOrder bool) error {
+	dir, err := utils.Mkdir(CourseDir(course.ClassInfo.Name))
+	if err != nil {
+		return err
+	}
+	return WriteCourseIndex(dir, newCourseMeta(course, articles, isOrder))
+}
+
+// ExportCourseIndex 根据已下载课程的元数据重新生成首页，id 为 0 时生成所有课程
+func ExportCourseIndex(id int) error {
+	dirs, err := CourseDirs()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, dir := range dirs {
+		meta, err := LoadCourseMeta(dir)
+		if err != nil || meta == nil {
+			fmt.Printf("读取课程元数据 %s 失败: %v\n", dir, err)
+			continue
+		}
+		if id > 0 && meta.ClassInfo.ID != id {
+			continue
+		}
+		found = true
+		if err = WriteCourseIndex(dir, meta); err != nil {
+			return err
+		}
+	}
+	if !found {
+		if id > 0 {
+			return fmt.Errorf("课程 %d 未下载，请先使用 dedao-dl dl %d 下载", id, id)
+		}
+		return errors.New("没有已下载的课程")
+	}
+	return nil
+}
+
+// Markdown 渲染 index.md
+func (c *CourseIndex) Markdown() (string, error) {
+	var buf bytes.Buffer
+	err := courseIndexMarkdown.Execute(&buf, c)
+	return buf.String(), err
+}
+
+// HTML 渲染 index.html
+func (c *CourseIndex) HTML() (string, error) {
+	var buf bytes.Buffer
+	err := courseIndexHTML.Execute(&buf, c)
+	return buf.String(), err
+}
+
+func courseIndexTime(t int) string {
+	if t <= 0 {
+		return ""
+	}
+	return strings.SplitN(utils.Unix2String(int64(t)), " ", 2)[0]
+}
+
+var courseIndexMarkdown = texttemplate.Must(texttemplate.New(CourseIndexName + ".md").Funcs(texttemplate.FuncMap{
+	"text":   textToMarkdown,
+	"inline": escapeInline,
+	"date":   courseIndexTime,
+}).Parse(`# {{inline .Class.Name}}
+{{with .SquareImg}}
+![封面]({{.}})
+{{else}}{{with .Logo}}
+![封面]({{.}})
+{{end}}{{end}}{{with .Class.Highlight}}
+> {{inline .}}
+{{end}}
+- 讲师：{{inline .Class.LecturerNameAndTitle}}
+- 状态：{{.Status}}
+- 文章：已更新 {{.Class.CurrentArticleCount}} 篇{{if .Class.PhaseNum}}，共 {{.Class.PhaseNum}} 篇{{end}}，已导出 {{.Downloaded}}/{{.Articles}} 篇
+{{- with date .Class.PublishTime}}
+- 上线时间：{{.}}{{end}}
+{{- with date .Class.UpdateTime}}
+- 更新时间：{{.}}{{end}}
+{{- with .Class.Intro}}
+
+## 课程简介
+
+{{text .}}{{end}}
+{{- if or .Class.LecturerIntro .Avatar}}
+
+## 讲师介绍
+{{with .Avatar}}
+![{{inline $.Class.LecturerName}}]({{.}})
+{{end}}{{with .Class.LecturerIntro}}
+{{text .}}{{end}}{{end}}
+{{- with .Intro}}
+
+## 发刊词
+
+{{if .Links}}[{{inline .Title}}]({{(index .Links 0).URL}}){{else}}{{inline .Title}}{{end}}{{with .Summary}}
+
+{{text .}}{{end}}{{end}}
+
+## 目录
+{{range .Chapters}}{{with .Name}}
+### {{inline .}}
+{{end}}
+{{range .Articles}}- {{if .Links}}[{{inline .Title}}]({{(index .Links 0).URL}}){{range slice .Links 1}} · [{{.Name}}]({{.URL}}){{end}}{{else}}{{inline .Title}}{{end}}
+{{end}}{{end}}
+{{- with .Files}}
+## 其他文件
+
+{{range .}}- [{{inline .Name}}]({{.URL}})
+{{end}}{{end}}`))
+
+var courseIndexHTML = htmltemplate.Must(htmltemplate.New(CourseIndexName + ".html").Funcs(htmltemplate.FuncMap{
+	"date": courseIndexTime,
+	"paras": func(s string) (paras []string) {
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				paras = append(paras, line)
+			}
+		}
+		return
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Class.Name}}</title>
+<style>
+body { max-width: 860px; margin: 0 auto; padding: 16px 16px 48px; font-family: "PingFang SC", "Microsoft YaHei", Arial, sans-serif; color: #333; line-height: 1.8; }
+a { color: rgb(255, 96, 2); text-decoration: none; }
+img { max-width: 100%; }
+.cover { max-width: 320px; border-radius: 8px; }
+.avatar { width: 96px; height: 96px; border-radius: 50%; object-fit: cover; }
+.highlight { color: #666; border-left: 4px solid rgb(255, 96, 2); padding-left: 12px; }
+li small, .missing { color: #aaa; }
+</style>
+</head>
+<body>
+<h1>{{.Class.Name}}</h1>
+{{if .SquareImg}}<img class="cover" src="{{.SquareImg}}" alt="封面">{{else if .Logo}}<img class="cover" src="{{.Logo}}" alt="封面">{{end}}
+{{with .Class.Highlight}}<p class="highlight">{{.}}</p>{{end}}
+<ul>
+<li>讲师：{{.Class.LecturerNameAndTitle}}</li>
+<li>状态：{{.Status}}</li>
+<li>文章：已更新 {{.Class.CurrentArticleCount}} 篇{{if .Class.PhaseNum}}，共 {{.Class.PhaseNum}} 篇{{end}}，已导出 {{.Downloaded}}/{{.Articles}} 篇</li>
+{{with date .Class.PublishTime}}<li>上线时间：{{.}}</li>{{end}}
+{{with date .Class.UpdateTime}}<li>更新时间：{{.}}</li>{{end}}
+</ul>
+{{with .Class.Intro}}<h2>课程简介</h2>
+{{range paras .}}<p>{{.}}</p>
+{{end}}{{end}}
+{{if or .Class.LecturerIntro .Avatar}}<h2>讲师介绍</h2>
+{{with .Avatar}}<img class="avatar" src="{{.}}" alt="{{$.Class.LecturerName}}">{{end}}
+{{range paras .Class.LecturerIntro}}<p>{{.}}</p>
+{{end}}{{end}}
+{{with .Intro}}<h2>发刊词</h2>
+<p>{{if .Links}}<a href="{{(index .Links 0).URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</p>
+{{range paras .Summary}}<p>{{.}}</p>
+{{end}}{{end}}
+<h2>目录</h2>
+{{range .Chapters}}{{with .Name}}<h3>{{.}}</h3>
+{{end}}<ul>
+{{range .Articles}}<li>{{if .Links}}<a href="{{(index .Links 0).URL}}">{{.Title}}</a>{{range slice .Links 1}} <small><a href="{{.URL}}">{{.Name}}</a></small>{{end}}{{else}}<span class="missing">{{.Title}}</span>{{end}}</li>
+{{end}}</ul>
+{{end}}
+{{with .Files}}<h2>其他文件</h2>
+<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{end}}
+</body>
+</html>
+`))
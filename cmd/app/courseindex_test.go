@@ -0,0 +1,73 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestBuildCourseIndex(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"MD/发刊词.md", "PDF/001.第一讲.pdf", "MD/第一讲.md", "EPUB/课程.epub", "重点摘要.md", "meta.json", "index.md", "MP3/第一讲.mp3"} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	intro := services.ArticleIntro{ArticleBase: services.ArticleBase{ID: 1, Title: "发刊词", Summary: "为什么学", OrderNum: 0}}
+	meta := &CourseMeta{
+		ClassInfo: services.ClassInfo{Name: "课程", IsFinished: 1, Highlight: "亮点", LecturerNameAndTitle: "讲师", CurrentArticleCount: 3, PhaseNum: 3},
+		ChapterList: []services.Chapter{
+			{ID: 10, Name: "第一章"},
+		},
+		Articles: []services.ArticleIntro{
+			intro,
+			{ArticleBase: services.ArticleBase{ID: 2, Title: "第一讲", ChapterID: 10, OrderNum: 1}},
+			{ArticleBase: services.ArticleBase{ID: 3, Title: "第二讲", ChapterID: 10, OrderNum: 2}},
+		},
+		IntroArticle: &intro,
+	}
+	index := BuildCourseIndex(dir, meta)
+	if index.Status != "已完结" || index.Articles != 3 || index.Downloaded != 2 {
+		t.Errorf("index = %+v", index)
+	}
+	if index.Intro == nil || index.Intro.Title != "发刊词" || len(index.Intro.Links) != 1 {
+		t.Fatalf("intro = %+v", index.Intro)
+	}
+	links := index.Chapters[0].Articles[0].Links
+	if len(links) != 3 || links[0].Name != "Markdown" || links[1].URL != "PDF/001.%E7%AC%AC%E4%B8%80%E8%AE%B2.pdf" || links[2].Name != "MP3" {
+		t.Errorf("links = %+v", links)
+	}
+	var files []string
+	for _, f := range index.Files {
+		files = append(files, f.Name)
+	}
+	if strings.Join(files, ",") != "重点摘要.md,EPUB/课程.epub" {
+		t.Errorf("files = %v", files)
+	}
+
+	md, err := index.Markdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# 课程\n", "> 亮点\n", "- 状态：已完结\n", "## 发刊词\n\n[发刊词](MD/", "### 第一章\n\n- [第一讲](MD/", " · [PDF](PDF/", "- 第二讲\n", "## 其他文件\n"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	page, err := index.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<title>课程</title>", `<a href="MD/`, `<span class="missing">第二讲</span>`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("html missing %q:\n%s", want, page)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+)
+
+// Daemon 按计划定时同步已购的课程、听书和电子书
+type Daemon struct {
+	Config  config.DaemonConfig
+	RunNow  bool   // 启动时立即同步一次
+	Metrics string // Prometheus 指标监听地址，为空时不启用
+
+	quiet *quietHours
+	mu    sync.Mutex
+	seq   int
+}
+
+// Run 启动定时同步，阻塞运行
+func (d *Daemon) Run() error {
+	if len(d.Config.CourseFormats) == 0 && len(d.Config.OdobFormats) == 0 && len(d.Config.EbookFormats) == 0 {
+		return errors.New("未配置同步内容，请在 config.json 的 Daemon 字段中设置 course_formats、odob_formats 或 ebook_formats")
+	}
+	quiet, err := parseQuietHours(d.Config.QuietHours)
+	if err != nil {
+		return err
+	}
+	d.quiet = quiet
+	if d.Config.ConvertImages, err = ImageFormat(d.Config.ConvertImages); err != nil {
+		return err
+	}
+
+	c := cron.New()
+	id, err := c.AddFunc(d.Config.Schedule, d.Sync)
+	if err != nil {
+		return fmt.Errorf("同步计划 %q 错误: %w", d.Config.Schedule, err)
+	}
+	if d.Config.KeepAlive != "" {
+		interval, err := time.ParseDuration(d.Config.KeepAlive)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("保持登录间隔 %q 错误", d.Config.KeepAlive)
+		}
+		go d.keepAlive(interval)
+	}
+	if d.Metrics != "" {
+		ServeMetrics(d.Metrics)
+	}
+	defer startWebhooks()()
+	c.Start()
+	defer c.Stop()
+
+	fmt.Printf("定时同步已启动，计划：%s，下次同步时间：%s\n",
+		d.Config.Schedule, c.Entry(id).Next.Format("2006-01-02 15:04:05"))
+	if d.RunNow {
+		d.Sync()
+	}
+	select {}
+}
+
+// Sync 同步一次，上一次同步未完成时跳过
+func (d *Daemon) Sync() {
+	if !d.mu.TryLock() {
+		fmt.Println("上一次同步尚未完成，跳过本次同步")
+		return
+	}
+	defer d.mu.Unlock()
+	if d.quiet.contains(time.Now()) {
+		fmt.Printf("当前处于静默时段 %s，跳过本次同步\n", d.Config.QuietHours)
+		return
+	}
+
+	// 进入静默时段后停止同步，当前文件完成后退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if d.quiet.contains(now) {
+					fmt.Println("进入静默时段，停止同步")
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	start := time.Now()
+	fmt.Printf("开始同步：%s\n", start.Format("2006-01-02 15:04:05"))
+	if len(d.Config.CourseFormats) > 0 {
+		d.syncCourses(ctx)
+	}
+	if len(d.Config.OdobFormats) > 0 {
+		d.syncOdob(ctx)
+	}
+	if len(d.Config.EbookFormats) > 0 {
+		d.syncEbooks(ctx)
+	}
+	fmt.Printf("同步结束，耗时 %s\n", time.Since(start).Round(time.Second))
+}
+
+// syncCourses 同步课程新发布的文章
+// 以课程的 PublishNum 判断是否有更新，再按文章记录同步状态，已同步的文章不会重复下载
+func (d *Daemon) syncCourses(ctx context.Context) {
+	list, err := CourseList(CateCourse)
+	if err != nil {
+		fmt.Printf("获取课程列表失败: %v\n", err)
+		return
+	}
+	for _, course := range list.List {
+		for _, t := range d.Config.CourseFormats {
+			if ctx.Err() != nil {
+				return
+			}
+			format := CourseFormatName(t)
+			published := format + "@" + strconv.Itoa(course.PublishNum)
+			if config.Instance.IsSynced(CateCourse, course.ClassID, published) {
+				continue
+			}
+			if err := d.syncCourse(ctx, course, t); err != nil {
+				fmt.Printf("同步课程【%s】失败: %v\n", course.Title, err)
+				continue
+			}
+			_ = config.Instance.SetSynced(CateCourse, course.ClassID, published)
+		}
+	}
+}
+
+func (d *Daemon) syncCourse(ctx context.Context, course services.CourseV2, downloadType int) (err error) {
+	services.WaitForNextRequest()
+	articles, err := ArticleList(course.ClassID, "")
+	if err != nil {
+		return err
+	}
+	format := CourseFormatName(downloadType)
+	var pending []services.ArticleIntro
+	for _, article := range articles.List {
+		if !config.Instance.IsSynced(KindCourse, article.ID, format) {
+			pending = append(pending, article)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	fmt.Printf("课程【%s】有 %d 篇文章待同步\n", course.Title, len(pending))
+
+	task := d.newTask(ctx)
+	defer publishJob(task, Event{
+		Kind:   KindCourse,
+		ID:     course.ClassID,
+		Title:  course.Title,
+		Format: format,
+		Total:  len(pending),
+	}, &err)()
+	failed := 0
+	unsubscribe := Subscribe(func(e Event) {
+		if e.JobID != task.JobID || e.ItemID == 0 {
+			return
+		}
+		switch e.Type {
+		case EventItemFinished, EventItemSkipped:
+			_ = config.Instance.SetSynced(KindCourse, e.ItemID, format)
+		case EventItemFailed:
+			failed++
+		}
+	})
+	defer unsubscribe()
+	// 有文章下载失败时不记录课程已同步，下次同步重试
+	defer func() {
+		if err == nil && failed > 0 {
+			err = fmt.Errorf("%d 篇文章下载失败", failed)
+		}
+	}()
+
+	dl := &CourseDownload{
+		Task:          task,
+		DownloadType:  downloadType,
+		ID:            course.ClassID,
+		IsComment:     d.Config.Comment,
+		IsOrder:       d.Config.Order,
+		OfflineAssets: d.Config.OfflineAssets,
+		ConvertImages: d.Config.ConvertImages,
+		articles:      articles,
+	}
+	// 待同步的文章较多时整门课程下载，已存在的文件会跳过
+	if len(pending)*2 > len(articles.List) {
+		return Download(dl)
+	}
+	// 逐篇下载时复用同一个下载器，课程信息和文章列表只获取一次
+	var errs []error
+	for _, article := range pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		services.WaitForNextRequest()
+		dl.AID = article.ID
+		if err := Download(dl); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncOdob 同步听书书架中新加入的书
+func (d *Daemon) syncOdob(ctx context.Context) {
+	list, err := CourseList(CateAudioBook)
+	if err != nil {
+		fmt.Printf("获取听书书架失败: %v\n", err)
+		return
+	}
+	for _, book := range list.List {
+		for _, t := range d.Config.OdobFormats {
+			d.syncItem(ctx, CateAudioBook, book.ID, CourseFormatName(t), book.Title, &OdobDownload{
+				DownloadType:  t,
+				ID:            book.ID,
+				OfflineAssets: d.Config.OfflineAssets,
+				ConvertImages: d.Config.ConvertImages,
+			})
+		}
+	}
+}
+
+// syncEbooks 同步电子书架中新加入的书
+func (d *Daemon) syncEbooks(ctx context.Context) {
+	list, err := CourseList(CateEbook)
+	if err != nil {
+		fmt.Printf("获取电子书架失败: %v\n", err)
+		return
+	}
+	for _, book := range list.List {
+		for _, t := range d.Config.EbookFormats {
+			d.syncItem(ctx, CateEbook, book.ID, EbookFormatName(t), book.Title, &EBookDownloadByID{
+				DownloadType: t,
+				ID:           book.ID,
+			})
+		}
+	}
+}
+
+func (d *Daemon) syncItem(ctx context.Context, category string, id int, format, title string, dl DeDaoDownloader) {
+	if ctx.Err() != nil || config.Instance.IsSynced(category, id, format) {
+		return
+	}
+	task := d.newTask(ctx)
+	if t, ok := dl.(interface{ task() *Task }); ok {
+		*t.task() = task
+	}
+	kind := KindOdob
+	if category == CateEbook {
+		kind = KindEbook
+	}
+	var err error
+	finish := publishJob(task, Event{Kind: kind, ID: id, Title: title, Format: format, Total: 1}, &err)
+	services.WaitForNextRequest()
+	err = Download(dl)
+	finish()
+	if err != nil {
+		fmt.Printf("同步【%s】失败: %v\n", title, err)
+		return
+	}
+	_ = config.Instance.SetSynced(category, id, format)
+}
+
+// publishJob 发布同步任务开始事件，返回的函数发布结束事件，webhook 可据此得知课程或书籍同步完成
+// 结束事件的 paths 为任务生成或已存在的文件，整门课程下载完成时 path 为课程输出目录
+func publishJob(task Task, e Event, err *error) (finish func()) {
+	e.Type = EventJobStarted
+	task.emit(e)
+
+	var mu sync.Mutex
+	unsubscribe := Subscribe(func(item Event) {
+		if item.JobID != task.JobID {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch item.Type {
+		case EventItemFinished, EventItemSkipped:
+			if item.Path != "" {
+				e.Paths = append(e.Paths, item.Path)
+			}
+		case EventCourseFinished:
+			e.Path = item.Path
+		default:
+			return
+		}
+		if e.Title == "" {
+			e.Title = item.Title
+		}
+	})
+	return func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		e.Type = EventJobFinished
+		switch {
+		case errors.Is(*err, context.Canceled):
+			e.Type = EventJobCanceled
+		case *err != nil:
+			e.Type = EventJobFailed
+			e.Error = (*err).Error()
+		}
+		task.emit(e)
+	}
+}
+
+func (d *Daemon) newTask(ctx context.Context) Task {
+	d.seq++
+	return Task{Ctx: ctx, JobID: fmt.Sprintf("daemon-%d", d.seq)}
+}
+
+// keepAlive 定期请求用户信息保持登录状态
+func (d *Daemon) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := config.Instance.RefreshSession(); err != nil {
+			fmt.Printf("登录状态检查失败，请重新登录: %v\n", err)
+		}
+	}
+}
+
+// quietHours 静默时段，支持跨零点，如 23:00-07:00
+type quietHours struct {
+	start, end int // 当天的分钟数
+}
+
+func parseQuietHours(s string) (*quietHours, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("静默时段 %q 格式错误，应为 23:00-07:00", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, err
+	}
+	return &quietHours{start: start, end: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("时间 %q 格式错误，应为 HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (q *quietHours) contains(t time.Time) bool {
+	if q == nil || q.start == q.end {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	for _, s := range []string{"23:00", "23:00-", "25:00-07:00", "23:00-7"} {
+		if _, err := parseQuietHours(s); err == nil {
+			t.Errorf("parseQuietHours(%q) should fail", s)
+		}
+	}
+	if q, err := parseQuietHours(" "); err != nil || q != nil || q.contains(time.Now()) {
+		t.Errorf("empty quiet hours = %v, %v", q, err)
+	}
+
+	at := func(clock string) time.Time {
+		v, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		quiet string
+		in    []string
+		out   []string
+	}{
+		// 跨零点
+		{"23:00-07:00", []string{"23:00", "23:59", "00:00", "06:59"}, []string{"07:00", "12:00", "22:59"}},
+		{" 12:30 - 13:30 ", []string{"12:30", "13:29"}, []string{"12:29", "13:30", "00:00"}},
+		// 开始和结束相同视为不设置
+		{"08:00-08:00", nil, []string{"08:00", "20:00"}},
+	}
+	for _, c := range cases {
+		q, err := parseQuietHours(c.quiet)
+		if err != nil {
+			t.Fatalf("parseQuietHours(%q): %v", c.quiet, err)
+		}
+		for _, clock := range c.in {
+			if !q.contains(at(clock)) {
+				t.Errorf("%q should contain %s", c.quiet, clock)
+			}
+		}
+		for _, clock := range c.out {
+			if q.contains(at(clock)) {
+				t.Errorf("%q should not contain %s", c.quiet, clock)
+			}
+		}
+	}
+}
+
+func TestPublishJob(t *testing.T) {
+	var events []Event
+	unsubscribe := Subscribe(func(e Event) {
+		if e.JobID == "daemon-test" {
+			events = append(events, e)
+		}
+	})
+	defer unsubscribe()
+
+	task := Task{JobID: "daemon-test"}
+	var err error
+	finish := publishJob(task, Event{Kind: KindCourse, ID: 1, Format: "md"}, &err)
+	task.emitItem(Event{Title: "课程", Path: "output/课程/MD/a.md"}, nil)
+	task.emitItem(Event{Title: "课程", Type: EventItemSkipped, Path: "output/课程/MD/b.md"}, nil)
+	task.emitItem(Event{Title: "课程", Path: "output/课程/MD/c.md"}, errors.New("超时"))
+	Publish(Event{Type: EventItemFinished, JobID: "other", Path: "output/其他.md"})
+	task.emit(Event{Type: EventCourseFinished, Path: "output/课程"})
+	err = errors.New("1 篇文章下载失败")
+	finish()
+
+	if len(events) != 6 || events[0].Type != EventJobStarted {
+		t.Fatalf("events = %+v", events)
+	}
+	e := events[5]
+	if e.Type != EventJobFailed || e.Error != err.Error() || e.Title != "课程" || e.Path != "output/课程" {
+		t.Errorf("finish event = %+v", e)
+	}
+	if len(e.Paths) != 2 || e.Paths[0] != "output/课程/MD/a.md" || e.Paths[1] != "output/课程/MD/b.md" {
+		t.Errorf("paths = %q", e.Paths)
+	}
+}
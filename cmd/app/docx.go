@@ -0,0 +1,624 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+// runFootnote 脚注，Run.Text 为脚注内容，DOCX 生成 Word 脚注，其他格式按文字输出
+const runFootnote = "footnote"
+
+// docx 页面为 A4，页边距与 Word 中文默认一致，单位 twip（1/20 pt）
+const (
+	docxPageW    = 11906
+	docxPageH    = 16838
+	docxMarginTB = 1440
+	docxMarginLR = 1800
+	// docxContentW 正文宽度，单位 EMU（1/914400 英寸），图片不超过正文宽度
+	docxContentW = (docxPageW - 2*docxMarginLR) * 635
+)
+
+// docxWriter 生成 Word 文档（Office Open XML），直接排版文章内容块，不依赖 LibreOffice
+// 标题使用 Word 内置的标题 1-6 样式，导航窗格和目录域都能识别
+type docxWriter struct {
+	title    string
+	author   string
+	body     strings.Builder
+	rels     []docxRel             // document.xml.rels 中的图片和链接
+	links    map[string]string     // 链接地址 -> rId
+	images   map[string]*docxImage // 本地图片路径 -> 已添加的图片
+	nums     []bool                // 各列表的编号实例，true 为有序列表
+	notes    []string              // 脚注，已转换为 XML
+	headings []docxHeading         // 目录中的标题
+	tocAt    int                   // 目录在 body 中的位置，-1 时没有目录
+	tocLevel int                   // 目录包含的标题级数
+	drawings int
+}
+
+type docxRel struct {
+	id       string
+	typ      string
+	target   string
+	external bool
+}
+
+type docxImage struct {
+	rel  string
+	name string
+	data []byte
+	w, h int64 // 按 96 dpi 换算的尺寸，单位 EMU
+}
+
+type docxHeading struct {
+	level    int
+	text     string
+	bookmark string
+}
+
+func newDocxWriter(title, author string) *docxWriter {
+	return &docxWriter{
+		title:  title,
+		author: author,
+		links:  make(map[string]string),
+		images: make(map[string]*docxImage),
+		tocAt:  -1,
+	}
+}
+
+// docxText 转义 XML，无效字符替换为 U+FFFD
+func docxText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// rel 添加关系，返回 rId，前 4 个为样式、编号、设置和脚注
+func (w *docxWriter) rel(typ, target string, external bool) string {
+	id := "rId" + strconv.Itoa(len(w.rels)+5)
+	w.rels = append(w.rels, docxRel{id: id, typ: typ, target: target, external: external})
+	return id
+}
+
+// paragraph 输出一个段落，style 为段落样式，props 为其他段落属性
+func (w *docxWriter) paragraph(style, props, runs string) {
+	w.body.WriteString("<w:p>")
+	if style != "" || props != "" {
+		w.body.WriteString("<w:pPr>")
+		if style != "" {
+			fmt.Fprintf(&w.body, `<w:pStyle w:val="%s"/>`, style)
+		}
+		w.body.WriteString(props + "</w:pPr>")
+	}
+	w.body.WriteString(runs + "</w:p>")
+}
+
+// textRun 一段文字，换行转换为 <w:br/>
+func textRun(text, props string) string {
+	var b strings.Builder
+	b.WriteString("<w:r>")
+	if props != "" {
+		b.WriteString("<w:rPr>" + props + "</w:rPr>")
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteString("<w:br/>")
+		}
+		if line != "" {
+			b.WriteString(`<w:t xml:space="preserve">` + docxText(line) + "</w:t>")
+		}
+	}
+	b.WriteString("</w:r>")
+	return b.String()
+}
+
+// runs 段落中的文字，粗体加粗，高亮与内置 PDF 相同使用橙色，链接使用超链接样式
+func (w *docxWriter) runs(runs []services.Run) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.Text == "" {
+			continue
+		}
+		if run.Type == runFootnote {
+			b.WriteString(w.footnote(run.Text))
+			continue
+		}
+		var props string
+		if run.Bold {
+			props += "<w:b/>"
+		}
+		if run.Jump != "" && strings.HasPrefix(run.Jump, "http") {
+			fmt.Fprintf(&b, `<w:hyperlink r:id="%s">%s</w:hyperlink>`, w.link(run.Jump), textRun(run.Text, `<w:rStyle w:val="Hyperlink"/>`+props))
+			continue
+		}
+		if run.Highlight {
+			props += `<w:color w:val="FF6002"/>`
+		}
+		b.WriteString(textRun(run.Text, props))
+	}
+	return b.String()
+}
+
+func (w *docxWriter) link(url string) string {
+	if id, ok := w.links[url]; ok {
+		return id
+	}
+	id := w.rel("http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink", url, true)
+	w.links[url] = id
+	return id
+}
+
+// footnote 添加脚注，返回正文中的脚注引用
+func (w *docxWriter) footnote(text string) string {
+	w.notes = append(w.notes, `<w:p><w:pPr><w:pStyle w:val="FootnoteText"/></w:pPr>`+
+		`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteRef/></w:r>`+
+		textRun(" "+strings.TrimSpace(text), "")+"</w:p>")
+	return fmt.Sprintf(`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteReference w:id="%d"/></w:r>`, len(w.notes))
+}
+
+// heading 标题，添加书签用于目录跳转，pageBreak 为 true 时另起一页
+func (w *docxWriter) heading(level int, text string, pageBreak bool) {
+	level = min(max(level, 1), 6)
+	props := ""
+	if pageBreak {
+		props = "<w:pageBreakBefore/>"
+	}
+	bookmark := fmt.Sprintf("_Toc%06d", len(w.headings)+1)
+	w.headings = append(w.headings, docxHeading{level: level, text: text, bookmark: bookmark})
+	id := len(w.headings)
+	w.paragraph("Heading"+strconv.Itoa(level), props, fmt.Sprintf(`<w:bookmarkStart w:id="%d" w:name="%s"/>%s<w:bookmarkEnd w:id="%d"/>`,
+		id, bookmark, textRun(text, ""), id))
+}
+
+// document 排版文章内容，offset 为标题降级的级数
+func (w *docxWriter) document(doc *services.Document, offset int) {
+	for _, block := range doc.Blocks {
+		w.block(block, offset)
+	}
+}
+
+func (w *docxWriter) block(block services.Block, offset int) {
+	switch v := block.(type) {
+	case services.HeadingBlock:
+		if v.Text != "" {
+			w.heading(v.Level+offset, v.Text, false)
+		}
+	case services.ParagraphBlock:
+		if runs := w.runs(v.Runs); runs != "" {
+			props := ""
+			switch v.Justify {
+			case "center", "right":
+				props = `<w:jc w:val="` + v.Justify + `"/>`
+			}
+			w.paragraph("", props, runs)
+		}
+	case services.ListBlock:
+		w.list(v, 0)
+	case services.QuoteBlock:
+		for _, line := range strings.Split(strings.TrimSpace(v.Text), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				w.paragraph("Quote", "", textRun(line, ""))
+			}
+		}
+	case services.EliteBlock:
+		// 同样边框的相邻段落在 Word 中显示为一个底纹框
+		w.paragraph("KeyPoints", "", textRun("划重点", "<w:b/>"))
+		for _, line := range strings.Split(strings.TrimSpace(v.Text), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				w.paragraph("KeyPoints", "", textRun(line, ""))
+			}
+		}
+	case services.ImageBlock:
+		w.image(v)
+	case services.LabelGroupBlock:
+		w.paragraph("", "", textRun(v.Text, `<w:b/><w:color w:val="FF6002"/>`))
+	case services.TableBlock:
+		w.table(v)
+	case services.CodeBlock:
+		w.paragraph("Code", "", textRun(strings.ReplaceAll(strings.TrimRight(v.Text, "\n"), "\t", "    "), ""))
+	case services.UnknownBlock:
+		if text := strings.TrimSpace(v.Text); text != "" {
+			w.paragraph("", "", textRun(text, ""))
+		}
+	}
+	// 音频标题与文章标题重复，不输出
+}
+
+// list 列表，每个列表使用单独的编号实例，有序列表从 1 开始
+func (w *docxWriter) list(list services.ListBlock, depth int) {
+	w.nums = append(w.nums, list.Ordered)
+	num := len(w.nums)
+	for _, item := range list.Items {
+		props := fmt.Sprintf(`<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, min(depth, 8), num)
+		w.paragraph("ListParagraph", props, w.runs(item.Runs))
+		if item.Children != nil {
+			w.list(*item.Children, depth+1)
+		}
+	}
+}
+
+// table 表格，各列等宽，第一行为表头，跨页时重复表头
+func (w *docxWriter) table(table services.TableBlock) {
+	cols := 0
+	for _, row := range table.Rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	colW := (docxPageW - 2*docxMarginLR) / cols
+	w.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < cols; i++ {
+		fmt.Fprintf(&w.body, `<w:gridCol w:w="%d"/>`, colW)
+	}
+	w.body.WriteString("</w:tblGrid>")
+	for i, row := range table.Rows {
+		w.body.WriteString("<w:tr>")
+		if i == 0 {
+			w.body.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for j := 0; j < cols; j++ {
+			fmt.Fprintf(&w.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, colW)
+			if i == 0 {
+				w.body.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F6F6F6"/>`)
+			}
+			w.body.WriteString("</w:tcPr><w:p>")
+			if j < len(row) {
+				runs := row[j].Runs
+				if i == 0 {
+					runs = append([]services.Run(nil), runs...)
+					for k := range runs {
+						runs[k].Bold = true
+					}
+				}
+				w.body.WriteString(w.runs(runs))
+			}
+			w.body.WriteString("</w:p></w:tc>")
+		}
+		w.body.WriteString("</w:tr>")
+	}
+	w.body.WriteString("</w:tbl>")
+	// 相邻的表格之间需要段落分隔
+	w.paragraph("", "", "")
+}
+
+// image 图片居中显示，宽度不超过正文，无法嵌入时显示图片说明
+func (w *docxWriter) image(block services.ImageBlock) {
+	drawing, err := w.drawing(block.URL, docxContentW)
+	if err != nil {
+		text := block.Legend
+		if text == "" {
+			text = block.URL
+		}
+		w.paragraph("Caption", "", textRun("[图片] "+text, ""))
+		return
+	}
+	if block.Jump != "" && strings.HasPrefix(block.Jump, "http") {
+		drawing = fmt.Sprintf(`<w:hyperlink r:id="%s">%s</w:hyperlink>`, w.link(block.Jump), drawing)
+	}
+	w.paragraph("", `<w:keepNext/><w:jc w:val="center"/>`, drawing)
+	if block.Legend != "" {
+		w.paragraph("Caption", "", textRun(block.Legend, ""))
+	}
+}
+
+// drawing 嵌入本地图片，返回图片所在的 run，maxW 为最大宽度，单位 EMU
+func (w *docxWriter) drawing(file string, maxW int64) (string, error) {
+	img, ok := w.images[file]
+	if !ok {
+		data, typ, width, height, err := readImage(file)
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("image%d.%s", len(w.images)+1, typ)
+		img = &docxImage{name: name, data: data, w: int64(width) * 9525, h: int64(height) * 9525}
+		img.rel = w.rel("http://schemas.openxmlformats.org/officeDocument/2006/relationships/image", "media/"+name, false)
+		w.images[file] = img
+	}
+	cx, cy := img.w, img.h
+	if cx > maxW {
+		cx, cy = maxW, img.h*maxW/img.w
+	}
+	w.drawings++
+	return fmt.Sprintf(`<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%d" cy="%d"/><wp:docPr id="%d" name="图片 %d"/>`+
+		`<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect="1"/></wp:cNvGraphicFramePr>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic>`+
+		`<pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		cx, cy, w.drawings, w.drawings, w.drawings, img.name, img.rel, cx, cy), nil
+}
+
+// comments 留言
+func (w *docxWriter) comments(comments []services.ArticleComment, offset int) {
+	if len(comments) == 0 {
+		return
+	}
+	w.heading(2+offset, "留言", false)
+	for _, c := range comments {
+		w.paragraph("", "", textRun(c.NotesOwner.Name+"：", "<w:b/>")+textRun(c.Note+commentLikes(c), ""))
+		if c.CommentReply != "" {
+			w.paragraph("Quote", "", textRun(c.CommentReplyUser.Name+"("+c.CommentReplyUser.Role+") 回复："+c.CommentReply, ""))
+		}
+	}
+}
+
+// cover 封面：图片、标题、作者和简介，之后另起一页
+func (w *docxWriter) cover(logo, name, author, intro string) {
+	if drawing, err := w.drawing(logo, docxContentW*3/5); err == nil {
+		w.paragraph("", `<w:spacing w:before="1200" w:after="600"/><w:jc w:val="center"/>`, drawing)
+	}
+	w.paragraph("Title", "", textRun(name, ""))
+	if author != "" {
+		w.paragraph("Subtitle", "", textRun(author, ""))
+	}
+	for _, line := range strings.Split(strings.TrimSpace(intro), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.paragraph("Intro", "", textRun(line, ""))
+		}
+	}
+	w.body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+}
+
+// toc 在当前位置插入目录域，包含 1 到 level 级标题
+// 目录项在保存时按已排版的标题生成，打开文档时 Word 会提示更新域以填入页码
+func (w *docxWriter) toc(level int) {
+	w.tocAt, w.tocLevel = w.body.Len(), level
+}
+
+// tocXML 目录域，域结果为链接到各标题书签的目录项
+func (w *docxWriter) tocXML() string {
+	var b strings.Builder
+	b.WriteString(`<w:p><w:pPr><w:pStyle w:val="TOCHeading"/></w:pPr>` + textRun("目 录", "") + "</w:p>")
+	fmt.Fprintf(&b, `<w:p><w:r><w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>`+
+		`<w:r><w:instrText xml:space="preserve"> TOC \o "1-%d" \h \z \u </w:instrText></w:r>`+
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r></w:p>`, w.tocLevel)
+	for _, h := range w.headings {
+		if h.level > w.tocLevel {
+			continue
+		}
+		fmt.Fprintf(&b, `<w:p><w:pPr><w:pStyle w:val="TOC%d"/></w:pPr><w:hyperlink w:anchor="%s" w:history="1">%s</w:hyperlink></w:p>`,
+			h.level, h.bookmark, textRun(h.text, ""))
+	}
+	b.WriteString(`<w:p><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`)
+	return b.String()
+}
+
+// save 写入文件并输出结果
+func (w *docxWriter) save(fileName string) error {
+	err := w.write(fileName)
+	if err != nil {
+		_ = os.Remove(fileName)
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return err
+	}
+	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	return nil
+}
+
+func (w *docxWriter) write(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	z := zip.NewWriter(f)
+	body := w.body.String()
+	if w.tocAt >= 0 {
+		body = body[:w.tocAt] + w.tocXML() + body[w.tocAt:]
+	}
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxPackageRels},
+		{"docProps/core.xml", w.coreXML()},
+		{"docProps/app.xml", docxAppXML},
+		{"word/document.xml", docxDocumentHead + body + docxSectPr + "</w:body></w:document>"},
+		{"word/styles.xml", docxStylesXML},
+		{"word/numbering.xml", w.numberingXML()},
+		{"word/settings.xml", docxSettingsXML},
+		{"word/footnotes.xml", w.footnotesXML()},
+		{"word/_rels/document.xml.rels", w.relsXML()},
+	}
+	for _, p := range parts {
+		if err = writeZipFile(z, p.name, []byte(p.content)); err != nil {
+			break
+		}
+	}
+	for _, img := range w.images {
+		if err != nil {
+			break
+		}
+		err = writeZipFile(z, "word/media/"+img.name, img.data)
+	}
+	if err == nil {
+		err = z.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeZipFile(z *zip.Writer, name string, data []byte) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *docxWriter) coreXML() string {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return xml.Header + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		"<dc:title>" + docxText(w.title) + "</dc:title><dc:creator>" + docxText(w.author) + "</dc:creator>" +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + now + `</dcterms:created>` +
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">` + now + `</dcterms:modified></cp:coreProperties>`
+}
+
+func (w *docxWriter) relsXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>` +
+		`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/>` +
+		`<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes" Target="footnotes.xml"/>`)
+	for _, r := range w.rels {
+		mode := ""
+		if r.external {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, r.id, r.typ, docxText(r.target), mode)
+	}
+	b.WriteString("</Relationships>")
+	return b.String()
+}
+
+// numberingXML 无序、有序两种编号定义，每个列表一个编号实例
+func (w *docxWriter) numberingXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	bullets := []string{"•", "◦", "▪"}
+	for id, ordered := range []bool{false, true} {
+		fmt.Fprintf(&b, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, id)
+		for lvl := 0; lvl < 9; lvl++ {
+			format, text := "bullet", bullets[lvl%len(bullets)]
+			if ordered {
+				format, text = "decimal", "%"+strconv.Itoa(lvl+1)+"."
+			}
+			fmt.Fprintf(&b, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%s"/><w:lvlJc w:val="left"/>`+
+				`<w:pPr><w:ind w:left="%d" w:hanging="420"/></w:pPr></w:lvl>`, lvl, format, text, 420*(lvl+1))
+		}
+		b.WriteString("</w:abstractNum>")
+	}
+	for i, ordered := range w.nums {
+		abstract := 0
+		if ordered {
+			abstract = 1
+		}
+		fmt.Fprintf(&b, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`, i+1, abstract)
+		for lvl := 0; lvl < 9; lvl++ {
+			fmt.Fprintf(&b, `<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="1"/></w:lvlOverride>`, lvl)
+		}
+		b.WriteString("</w:num>")
+	}
+	b.WriteString("</w:numbering>")
+	return b.String()
+}
+
+func (w *docxWriter) footnotesXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<w:footnotes xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:type="continuationSeparator" w:id="0"><w:p><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>`)
+	for i, note := range w.notes {
+		fmt.Fprintf(&b, `<w:footnote w:id="%d">%s</w:footnote>`, i+1, note)
+	}
+	b.WriteString("</w:footnotes>")
+	return b.String()
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Default Extension="png" ContentType="image/png"/>` +
+	`<Default Extension="jpg" ContentType="image/jpeg"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>` +
+	`<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>` +
+	`<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`<Override PartName="/docProps/app.xml" ContentType="application/vnd.openxmlformats-officedocument.extended-properties+xml"/>` +
+	`</Types>`
+
+const docxPackageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties" Target="docProps/app.xml"/>` +
+	`</Relationships>`
+
+const docxAppXML = xml.Header + `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>dedao-dl</Application></Properties>`
+
+const docxDocumentHead = xml.Header + `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"><w:body>`
+
+var docxSectPr = fmt.Sprintf(`<w:sectPr><w:pgSz w:w="%d" w:h="%d"/>`+
+	`<w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="851" w:footer="992" w:gutter="0"/></w:sectPr>`,
+	docxPageW, docxPageH, docxMarginTB, docxMarginLR, docxMarginTB, docxMarginLR)
+
+// docxSettingsXML 打开文档时提示更新域，填入目录页码
+const docxSettingsXML = xml.Header + `<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:defaultTabStop w:val="420"/><w:updateFields w:val="true"/>` +
+	`<w:footnotePr><w:footnote w:id="-1"/><w:footnote w:id="0"/></w:footnotePr>` +
+	`<w:compat><w:compatSetting w:name="compatibilityMode" w:uri="http://schemas.microsoft.com/office/word" w:val="15"/></w:compat>` +
+	`</w:settings>`
+
+// docxStylesXML 文档样式，标题 1-6 使用内置样式 ID，划重点为带底纹的边框段落
+var docxStylesXML = xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Times New Roman" w:hAnsi="Times New Roman" w:eastAsia="宋体" w:cs="Times New Roman"/>` +
+	`<w:color w:val="333333"/><w:sz w:val="22"/><w:szCs w:val="22"/><w:lang w:val="en-US" w:eastAsia="zh-CN"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="360" w:lineRule="auto"/><w:jc w:val="both"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	`<w:style w:type="character" w:default="1" w:styleId="DefaultParagraphFont"><w:name w:val="Default Paragraph Font"/><w:uiPriority w:val="1"/><w:semiHidden/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:before="480" w:after="240"/><w:jc w:val="center"/></w:pPr><w:rPr><w:rFonts w:eastAsia="黑体"/><w:b/><w:color w:val="000000"/><w:sz w:val="52"/><w:szCs w:val="52"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="480"/><w:jc w:val="center"/></w:pPr><w:rPr><w:sz w:val="30"/><w:szCs w:val="30"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:customStyle="1" w:styleId="Intro"><w:name w:val="简介"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:ind w:left="720" w:right="720"/></w:pPr><w:rPr><w:color w:val="888888"/></w:rPr></w:style>` +
+	docxHeadingStyles +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:pBdr><w:left w:val="single" w:sz="24" w:space="8" w:color="DDDDDD"/></w:pBdr><w:ind w:left="420"/></w:pPr><w:rPr><w:color w:val="888888"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:customStyle="1" w:styleId="KeyPoints"><w:name w:val="划重点"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:pBdr><w:top w:val="single" w:sz="4" w:space="6" w:color="FFD0B0"/><w:left w:val="single" w:sz="4" w:space="6" w:color="FFD0B0"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="6" w:color="FFD0B0"/><w:right w:val="single" w:sz="4" w:space="6" w:color="FFD0B0"/></w:pBdr>` +
+	`<w:shd w:val="clear" w:color="auto" w:fill="FFF4EC"/><w:spacing w:after="0"/><w:ind w:left="180" w:right="180"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:customStyle="1" w:styleId="Code"><w:name w:val="代码"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F6F6F6"/><w:spacing w:line="276" w:lineRule="auto"/><w:jc w:val="left"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/><w:sz w:val="19"/><w:szCs w:val="19"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Caption"><w:name w:val="caption"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:jc w:val="center"/></w:pPr><w:rPr><w:color w:val="888888"/><w:sz w:val="18"/><w:szCs w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="60"/><w:contextualSpacing/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOCHeading"><w:name w:val="TOC Heading"/><w:basedOn w:val="Heading1"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:jc w:val="center"/><w:outlineLvl w:val="9"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC1"><w:name w:val="toc 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="60"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC2"><w:name w:val="toc 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="60"/><w:ind w:left="420"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC3"><w:name w:val="toc 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="60"/><w:ind w:left="840"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="FootnoteText"><w:name w:val="footnote text"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:sz w:val="18"/><w:szCs w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="FootnoteReference"><w:name w:val="footnote reference"/><w:rPr><w:vertAlign w:val="superscript"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="0066CC"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:default="1" w:styleId="TableNormal"><w:name w:val="Normal Table"/><w:semiHidden/>` +
+	`<w:tblPr><w:tblInd w:w="0" w:type="dxa"/><w:tblCellMar><w:top w:w="0" w:type="dxa"/><w:left w:w="108" w:type="dxa"/><w:bottom w:w="0" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:basedOn w:val="TableNormal"/>` +
+	`<w:pPr><w:spacing w:after="0" w:line="276" w:lineRule="auto"/></w:pPr>` +
+	`<w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:space="0" w:color="DDDDDD"/><w:left w:val="single" w:sz="4" w:space="0" w:color="DDDDDD"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="DDDDDD"/><w:right w:val="single" w:sz="4" w:space="0" w:color="DDDDDD"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="DDDDDD"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="DDDDDD"/></w:tblBorders></w:tblPr></w:style>` +
+	`</w:styles>`
+
+// docxHeadingStyles 标题 1-6，字号与内置 PDF 的标题比例相近，大纲级别用于导航窗格和目录
+var docxHeadingStyles = func() string {
+	var b strings.Builder
+	for i, size := range []int{40, 34, 30, 26, 24, 22} {
+		fmt.Fprintf(&b, `<w:style w:type="paragraph" w:styleId="Heading%d"><w:name w:val="heading %d"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>`+
+			`<w:pPr><w:keepNext/><w:keepLines/><w:spacing w:before="%d" w:after="160"/><w:jc w:val="left"/><w:outlineLvl w:val="%d"/></w:pPr>`+
+			`<w:rPr><w:rFonts w:eastAsia="黑体"/><w:b/><w:color w:val="000000"/><w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr></w:style>`,
+			i+1, i+1, 240+(5-i)*40, i, size, size)
+	}
+	return b.String()
+}()
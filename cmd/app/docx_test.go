@@ -0,0 +1,121 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestDocxWriter(t *testing.T) {
+	dir := t.TempDir()
+	img := filepath.Join(dir, "a.png")
+	f, err := os.Create(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = png.Encode(f, image.NewGray(image.Rect(0, 0, 2000, 100))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	doc := &services.Document{Blocks: []services.Block{
+		services.HeadingBlock{Level: 1, Text: "一、<开始>"},
+		services.ParagraphBlock{Runs: []services.Run{{Text: "正文 & "}, {Text: "粗体", Bold: true}, {Text: "链接", Jump: "https://example.com/?a=1&b=2"}, {Type: runFootnote, Text: "注释"}}},
+		services.ListBlock{Ordered: true, Items: []services.ListItem{{Runs: []services.Run{{Text: "一"}}, Children: &services.ListBlock{Items: []services.ListItem{{Runs: []services.Run{{Text: "子项"}}}}}}}},
+		services.QuoteBlock{Text: "引用"},
+		services.EliteBlock{Text: "重点一\n重点二"},
+		services.ImageBlock{URL: img, Legend: "图"},
+		services.ImageBlock{URL: filepath.Join(dir, "missing.png")},
+		services.TableBlock{Rows: [][]services.TableCell{{{Runs: []services.Run{{Text: "表头"}}}}, {{}, {Runs: []services.Run{{Text: "单元格"}}}}}},
+		services.CodeBlock{Text: "func main() {\n\treturn\n}"},
+	}}
+	w := newDocxWriter("课程", "讲师")
+	w.cover(img, "课程", "讲师", "简介")
+	w.toc(2)
+	w.heading(1, "第一章", true)
+	w.document(doc, 1)
+	file := filepath.Join(dir, "a.docx")
+	if err := w.write(file); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	parts := make(map[string]string)
+	for _, zf := range r.File {
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[zf.Name] = string(data)
+		if !strings.HasSuffix(zf.Name, ".xml") && !strings.HasSuffix(zf.Name, ".rels") {
+			continue
+		}
+		// 每个部件都应是格式正确的 XML
+		dec := xml.NewDecoder(strings.NewReader(string(data)))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", zf.Name, err)
+			}
+		}
+	}
+	if _, ok := parts["word/media/image1.png"]; !ok || len(w.images) != 1 {
+		t.Errorf("image not embedded once: %v", len(w.images))
+	}
+	body := parts["word/document.xml"]
+	for _, want := range []string{`TOC \o "1-2"`, `w:anchor="_Toc000001"`, `<w:pStyle w:val="Heading2"/>`, `w:footnoteReference w:id="1"`, `[图片] `, `<w:pStyle w:val="KeyPoints"/>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("document.xml missing %q", want)
+		}
+	}
+	// 目录在封面之后、正文之前
+	if toc, first := strings.Index(body, "TOC \\o"), strings.Index(body, "第一章"); toc < 0 || toc > first || toc < strings.Index(body, "简介") {
+		t.Errorf("toc at wrong position")
+	}
+	if n := strings.Count(parts["word/numbering.xml"], "<w:num "); n != 2 {
+		t.Errorf("numbering instances = %d, want 2", n)
+	}
+	if !strings.Contains(parts["word/footnotes.xml"], "注释") {
+		t.Error("footnote missing")
+	}
+}
+
+func TestEbookDocument(t *testing.T) {
+	doc := ebookDocument(`<html><head><style>p{}</style></head><body><div id="c1">
+</div><div class='header1'><h2><span style="font-weight:bold">第一章</span></h2></div><div class="part">
+	<p><span style="display: block;text-align:center;">居中<b>粗体</b><sup><a class="duokan-footnote" href="#fn"><img src="n.png" alt="注释内容" class="epub-footnote zhangyue-footnote"/></a></sup></span></p>
+<div style="text-align:center"><img width="600" src="https://example.com/a.jpg" alt=""/></div>
+<aside epub:type="footnote" id="fn">注释内容</aside>
+	<p><span style="font-weight: bold">整段粗体</span></p><p> </p></div></body></html>`)
+	if len(doc.Blocks) != 4 {
+		t.Fatalf("blocks = %#v", doc.Blocks)
+	}
+	if h, ok := doc.Blocks[0].(services.HeadingBlock); !ok || h.Level != 2 || h.Text != "第一章" {
+		t.Errorf("heading = %#v", doc.Blocks[0])
+	}
+	p := doc.Blocks[1].(services.ParagraphBlock)
+	if p.Justify != "center" || len(p.Runs) != 3 || !p.Runs[1].Bold || p.Runs[2].Type != runFootnote || p.Runs[2].Text != "注释内容" {
+		t.Errorf("paragraph = %#v", p)
+	}
+	if img, ok := doc.Blocks[2].(services.ImageBlock); !ok || img.URL != "https://example.com/a.jpg" {
+		t.Errorf("image = %#v", doc.Blocks[2])
+	}
+	if p := doc.Blocks[3].(services.ParagraphBlock); !p.Runs[0].Bold {
+		t.Errorf("bold paragraph = %#v", p)
+	}
+}
@@ -0,0 +1,353 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DocxDirName 课程、听书 DOCX 目录名
+const DocxDirName = "DOCX"
+
+// DownloadDocxCourse 将课程生成 Word 文档，合并时整门课程一个文档，包含封面和目录，章节为标题 1，文章为标题 2
+// 不合并时每篇文章一个文档
+func DownloadDocxCourse(d *CourseDownload, course *services.CourseInfo, path string) error {
+	if d.IsMerge && d.AID == 0 {
+		return d.docxBook(course, path)
+	}
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	for i, v := range list.List {
+		if d.AID > 0 && v.ID != d.AID {
+			continue
+		}
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		event := d.articleEvent(v, i, len(list.List))
+		name := utils.FileName(v.Title, "docx")
+		if d.IsOrder {
+			name = fmt.Sprintf("%03d.%s", v.OrderNum, name)
+		}
+		event.Path = filepath.Join(path, name)
+		_, exist, err := utils.FileSize(event.Path)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		if exist {
+			fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+			event.Type = EventItemSkipped
+			d.emitItem(event, nil)
+			continue
+		}
+
+		doc, enId, err := d.articleDocument(v)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+		err = docxArticle(event.Path, d.articleData(v, enId, doc))
+		d.emitItem(event, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docxBook 整门课程生成一个 Word 文档
+func (d *CourseDownload) docxBook(course *services.CourseInfo, path string) error {
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	info := course.ClassInfo
+	title := d.ClassName + "-合集"
+	name := utils.FileName(title, "docx")
+	event := Event{
+		Kind:   KindCourse,
+		ID:     d.ID,
+		Enid:   info.Enid,
+		Title:  d.ClassName,
+		Author: d.lecturer,
+		Item:   title,
+		Format: CourseFormatName(d.DownloadType),
+		Path:   filepath.Join(path, name),
+		Done:   1,
+		Total:  1,
+	}
+	_, exist, err := utils.FileSize(event.Path)
+	if err != nil {
+		d.emitItem(event, err)
+		return err
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		event.Type = EventItemSkipped
+		d.emitItem(event, nil)
+		return nil
+	}
+
+	chapters := GroupByChapter(course.ChapterList, list.List)
+	w := newDocxWriter(info.Name, d.lecturer)
+	w.cover(localizeImage(d.assets, info.Name, info.Logo), info.Name, classLecturer(info), info.Intro)
+	// 目录包含章节和文章标题
+	level := 1
+	for _, chapter := range chapters {
+		if chapter.Name != "" {
+			level = 2
+		}
+	}
+	w.toc(level)
+	for _, chapter := range chapters {
+		offset := 1
+		if chapter.Name != "" {
+			w.heading(1, chapter.Name, true)
+			offset = 2
+		}
+		for i, v := range chapter.Articles {
+			if err := d.canceled(); err != nil {
+				return err
+			}
+			doc, enId, err := d.articleDocument(v)
+			if err != nil {
+				d.emitItem(event, err)
+				return err
+			}
+			data := d.articleData(v, enId, doc)
+			// 章节下的第一篇文章紧接章节标题
+			w.heading(offset, v.Title, chapter.Name == "" || i > 0)
+			w.document(doc, offset)
+			w.comments(data.Comments, offset)
+		}
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+	err = w.save(event.Path)
+	d.emitItem(event, err)
+	return err
+}
+
+// docxArticle 一篇文章生成 Word 文档，文章标题为文档标题，文中标题依次降一级
+func docxArticle(fileName string, data ArticleData) error {
+	w := newDocxWriter(data.Title, data.Author)
+	w.paragraph("Title", "", textRun(data.Title, ""))
+	if data.Author != "" {
+		w.paragraph("Subtitle", "", textRun(data.Author, ""))
+	}
+	w.document(data.Document, 0)
+	w.comments(data.Comments, 0)
+	return w.save(fileName)
+}
+
+// DownloadDocxAudioBook 将每天听本书文稿生成 Word 文档
+func DownloadDocxAudioBook(assets *Assets, aliasID, path string, book *services.CourseV2) (fileName string, err error) {
+	name := utils.FileName(book.Title, "docx")
+	fileName = filepath.Join(path, name)
+	_, exist, err := utils.FileSize(fileName)
+	if err != nil {
+		return
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		return
+	}
+
+	doc, err := getArticleDetail(aliasID)
+	if err != nil {
+		return
+	}
+	reportUnknown(book.Title, doc)
+	assets.Localize(book.Title, doc)
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+	err = docxArticle(fileName, odobArticleData(book, doc))
+	return
+}
+
+// DownloadDocxEbook 将电子书生成 Word 文档，内容取自生成 EPUB 的章节 HTML
+// 原书目录中的标题为标题 1-6，注释为 Word 脚注，图片下载到 Ebook/assets 后嵌入
+func DownloadDocxEbook(title string, detail *services.EbookDetail, svgContents []*utils.SvgContent, toc []utils.EbookToc) (err error) {
+	path, err := utils.Mkdir(OutputDir, EbookDirName)
+	if err != nil {
+		return err
+	}
+	fileName, err := utils.FilePath(filepath.Join(path, utils.FileName(title, "")), "docx", false)
+	if err != nil {
+		return err
+	}
+	chapters, cover, err := utils.EbookChapters(svgContents, toc)
+	if err != nil {
+		return err
+	}
+	assets, err := newLocalAssets(path, "")
+	if err != nil {
+		return err
+	}
+	defer assets.PrintSummary()
+
+	levels := 1
+	for _, t := range toc {
+		levels = max(levels, t.Level+1)
+	}
+	name := detail.Title
+	if name == "" {
+		name = detail.OperatingTitle
+	}
+	w := newDocxWriter(name, detail.BookAuthor)
+	w.cover(localizeImage(assets, title, cover), name, detail.BookAuthor, detail.BookIntro)
+	w.toc(min(levels, 3))
+	for i, chapter := range chapters {
+		doc := ebookDocument(chapter.Content)
+		assets.Localize(title, doc)
+		if i > 0 && len(doc.Blocks) > 0 {
+			w.body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+		}
+		w.document(doc, 0)
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", fileName)
+	return w.save(fileName)
+}
+
+var (
+	textAlignRegexp  = regexp.MustCompile(`text-align:\s*(center|right)`)
+	fontWeightRegexp = regexp.MustCompile(`font-weight:\s*(bold|[6-9]00)`)
+)
+
+// ebookDocument 将电子书章节的 HTML 转换为内容块，只保留标题、段落、图片和注释
+func ebookDocument(s string) *services.Document {
+	doc := &services.Document{}
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return doc
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Head, atom.Style, atom.Script, atom.Aside:
+				// 注释内容已在注释图片的 alt 中
+			case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				level, _ := strconv.Atoi(c.Data[1:])
+				if text := strings.TrimSpace(strings.ReplaceAll(nodeText(c), "\u00a0", " ")); text != "" {
+					doc.Blocks = append(doc.Blocks, services.HeadingBlock{Level: level, Text: text})
+				}
+			case atom.P:
+				p := services.ParagraphBlock{Runs: ebookRuns(c, services.Run{})}
+				if m := textAlignRegexp.FindStringSubmatch(nodeStyles(c)); m != nil {
+					p.Justify = m[1]
+				}
+				if strings.TrimSpace(runsText(p.Runs)) != "" {
+					doc.Blocks = append(doc.Blocks, p)
+				}
+			case atom.Img:
+				if src := attr(c, "src"); src != "" {
+					doc.Blocks = append(doc.Blocks, services.ImageBlock{URL: src})
+				}
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+	return doc
+}
+
+// ebookRuns 段落中的文字，b 为粗体，i 为强调，注释图片转换为脚注，其他行内图片保留说明文字
+func ebookRuns(n *html.Node, style services.Run) (runs []services.Run) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			run := style
+			run.Text = c.Data
+			runs = append(runs, run)
+		case html.ElementNode:
+			s := style
+			switch c.DataAtom {
+			case atom.B, atom.Strong:
+				s.Bold = true
+			case atom.I, atom.Em:
+				s.Highlight = true
+			case atom.A:
+				if href := attr(c, "href"); strings.HasPrefix(href, "http") {
+					s.Jump = href
+				}
+			case atom.Br:
+				run := style
+				run.Text = "\n"
+				runs = append(runs, run)
+				continue
+			case atom.Img:
+				alt := strings.TrimSpace(attr(c, "alt"))
+				if alt == "" {
+					continue
+				}
+				if strings.Contains(attr(c, "class"), "footnote") {
+					runs = append(runs, services.Run{Type: runFootnote, Text: alt})
+				} else {
+					run := style
+					run.Text = alt
+					runs = append(runs, run)
+				}
+				continue
+			}
+			if fontWeightRegexp.MatchString(attr(c, "style")) {
+				s.Bold = true
+			}
+			runs = append(runs, ebookRuns(c, s)...)
+		}
+	}
+	return
+}
+
+// nodeText 节点中的全部文字
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
+
+// nodeStyles 节点及其子节点的 style 属性
+func nodeStyles(n *html.Node) string {
+	s := attr(n, "style")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			s += ";" + nodeStyles(c)
+		}
+	}
+	return s
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func runsText(runs []services.Run) string {
+	var b strings.Builder
+	for _, run := range runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
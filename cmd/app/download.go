@@ -8,8 +8,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/yann0917/dedao-dl/config"
 	"github.com/yann0917/dedao-dl/downloader"
 	"github.com/yann0917/dedao-dl/services"
@@ -23,59 +23,224 @@ type DeDaoDownloader interface {
 }
 
 type CourseDownload struct {
-	DownloadType int // 1:mp3, 2:PDF文档, 3:markdown文档
+	Task
+	DownloadType int // 1:mp3, 2:PDF文档, 3:markdown文档, 4:Obsidian 笔记, 5:epub, 6:单文件 HTML, 7:docx, 8:json
 	ID           int
 	AID          int
 	IsMerge      bool
+	MergeChapter bool // 合并 PDF 时每章生成一个文件
 	IsComment    bool
+	CommentLimit int    // 留言数量，0 为默认数量，CommentsAll 为全部
+	CommentSort  string // 留言排序，like 或 create，为空时为 like
 	IsOrder      bool
 	ClassName    string
+	// OfflineAssets 下载文章图片到 assets 目录并改写图片地址
+	OfflineAssets bool
+	ConvertImages string // 将 webp、avif 图片转换为 jpg 或 png，为空时不转换
+	PdfEngine     string // PDF 生成方式：auto、native、wkhtmltopdf，为空时使用配置
+	PdfProfile    string // PDF 打印配置名称，为空时使用配置
+	EmbedFonts    bool   // EPUB 嵌入文中使用的字体，为 false 时使用配置
+	// Transcript 下载 mp3 时在同目录生成 LRC 和 SRT 字幕
+	Transcript        bool
+	TranscriptSilence bool // 使用 ffmpeg 检测静音校准字幕时间
+	// Points 在文章开头加入重点摘要
+	Points bool
+
+	course    *services.CourseInfo
+	articles  *services.ArticleList
+	lecturer  string
+	class     *services.ClassInfo
+	templates *Templates
+	assets    *Assets
+	pdfEngine string
+	profile   utils.PrintProfile
 }
 
 type OdobDownload struct {
-	DownloadType int // 1:mp3, 2:PDF文档, 3:markdown文档
-	ID           int
+	Task
+	DownloadType  int // 1:mp3, 2:PDF文档, 3:markdown文档, 4:Obsidian 笔记, 5:epub, 6:单文件 HTML, 7:docx, 8:json
+	ID            int
+	OfflineAssets bool
+	ConvertImages string
+	PdfEngine     string
+	PdfProfile    string
+	EmbedFonts    bool
+	// Transcript 下载 mp3 时在同目录生成 LRC 和 SRT 字幕
+	Transcript        bool
+	TranscriptSilence bool
 }
 
 type EBookDownloadByID struct {
-	DownloadType int // 1:html, 2:PDF文档, 3:epub
+	Task
+	DownloadType int // 1:html, 2:PDF文档, 3:epub, 4:docx, 5:json
 	ID           int
+	PdfProfile   string // PDF 打印配置名称，为空时使用配置
+	EmbedFonts   bool   // EPUB 嵌入文中使用的字体，为 false 时使用配置
 }
 
 type EBookDownloadByEnID struct {
-	DownloadType int // 1:html, 2:PDF文档, 3:epub
+	Task
+	DownloadType int // 1:html, 2:PDF文档, 3:epub, 4:docx, 5:json
 	EnID         string
+	PdfProfile   string // PDF 打印配置名称，为空时使用配置
+	EmbedFonts   bool   // EPUB 嵌入文中使用的字体，为 false 时使用配置
 }
 
-func (d *CourseDownload) Download() error {
-	course, err := CourseInfo(d.ID)
+// CourseFormatName 课程、听书下载格式名称
+func CourseFormatName(downloadType int) string {
+	switch downloadType {
+	case 1:
+		return "mp3"
+	case 2:
+		return "pdf"
+	case 3:
+		return "md"
+	case 4:
+		return "obsidian"
+	case 5:
+		return "epub"
+	case 6:
+		return "html"
+	case 7:
+		return "docx"
+	case 8:
+		return "json"
+	}
+	return ""
+}
+
+// ParseCourseFormat 解析课程、听书下载格式，支持数字和格式名称，如 3、md、obsidian
+func ParseCourseFormat(s string) (int, error) {
+	if t, err := strconv.Atoi(s); err == nil {
+		if CourseFormatName(t) == "" {
+			return 0, fmt.Errorf("下载格式错误: %d", t)
+		}
+		return t, nil
+	}
+	switch strings.ToLower(s) {
+	case "mp3":
+		return 1, nil
+	case "pdf":
+		return 2, nil
+	case "md", "markdown":
+		return 3, nil
+	case "obsidian":
+		return 4, nil
+	case "epub":
+		return 5, nil
+	case "html":
+		return 6, nil
+	case "docx":
+		return 7, nil
+	case "json":
+		return 8, nil
+	}
+	return 0, fmt.Errorf("下载格式错误: %s", s)
+}
+
+// EbookFormatName 电子书下载格式名称
+func EbookFormatName(downloadType int) string {
+	switch downloadType {
+	case 1:
+		return "html"
+	case 2:
+		return "pdf"
+	case 3:
+		return "epub"
+	case 4:
+		return "docx"
+	case 5:
+		return "json"
+	}
+	return ""
+}
+
+func (d *CourseDownload) Download() (err error) {
+	course, err := d.courseInfo()
+	if err != nil {
+		return err
+	}
+
+	articles, err := d.articleList()
 	if err != nil {
 		return err
 	}
+	if err := SaveCourseMeta(course, articles, d.IsOrder); err != nil {
+		fmt.Printf("保存课程元数据失败: %v\n", err)
+	}
+	d.lecturer = course.ClassInfo.LecturerName
+	d.class = &course.ClassInfo
+	path := ""
+	defer func() {
+		// 更新课程首页，链接新生成的文件
+		if err == nil {
+			if err1 := SaveCourseIndex(course, articles, d.IsOrder); err1 != nil {
+				fmt.Printf("生成课程首页失败: %v\n", err1)
+			}
+		}
+		// 整门课程下载完成后通知 hooks、webhook 等
+		if err == nil && d.AID == 0 && path != "" {
+			d.emit(Event{
+				Type:   EventCourseFinished,
+				Kind:   KindCourse,
+				ID:     d.ID,
+				Enid:   course.ClassInfo.Enid,
+				Title:  course.ClassInfo.Name,
+				Author: d.lecturer,
+				Format: CourseFormatName(d.DownloadType),
+				Path:   path,
+				Total:  len(articles.List),
+			})
+		}
+	}()
 
 	switch d.DownloadType {
 	case 1: // mp3
-		articles, err := ArticleList(d.ID, "")
-		if err != nil {
-			return err
-		}
 		downloadData := extractDownloadData(course, articles, d.AID, 1, d.IsOrder)
 		errs := make([]error, 0)
 
-		path, err := utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), "MP3")
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), "MP3")
 		if err != nil {
 			return err
 		}
+		intros := make(map[int]services.ArticleIntro, len(articles.List))
+		for _, article := range articles.List {
+			intros[article.ID] = article
+		}
 
-		for _, datum := range downloadData.Data {
+		for i, datum := range downloadData.Data {
+			if err := d.canceled(); err != nil {
+				return err
+			}
 			if !datum.IsCanDL {
 				continue
 			}
 			stream := datum.Enid
-			if err := downloader.Download(datum, stream, path); err != nil {
+			mp3 := filepath.Join(path, utils.FileName(datum.Title, "mp3"))
+			err := downloader.Download(datum, stream, path)
+			if err != nil {
 				errs = append(errs, err)
 			}
-
+			if err == nil && d.Transcript {
+				if err := d.transcript(intros[datum.ID], mp3); err != nil {
+					fmt.Println(err)
+					errs = append(errs, err)
+				}
+			}
+			d.emitItem(Event{
+				Kind:     KindCourse,
+				ID:       d.ID,
+				Title:    course.ClassInfo.Name,
+				Author:   d.lecturer,
+				Item:     datum.Title,
+				ItemID:   datum.ID,
+				ItemEnid: datum.Enid,
+				Order:    intros[datum.ID].OrderNum,
+				Format:   CourseFormatName(d.DownloadType),
+				Path:     mp3,
+				Done:     i + 1,
+				Total:    len(downloadData.Data),
+			}, err)
 		}
 		if len(errs) > 0 {
 			return errs[0]
@@ -84,11 +249,35 @@ func (d *CourseDownload) Download() error {
 		// 下载 PDF
 		errs := make([]error, 0)
 
-		path, err := utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), "PDF")
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), "PDF")
 		if err != nil {
 			return err
 		}
 		d.ClassName = course.ClassInfo.Name
+		if d.templates, err = currentTemplates(); err != nil {
+			return err
+		}
+		if d.profile, err = config.Instance.PDF.PrintProfile(d.PdfProfile); err != nil {
+			return err
+		}
+		d.pdfEngine = pdfEngine(d.PdfEngine)
+		switch {
+		case d.pdfEngine == PdfEngineNative:
+			// 内置引擎从本地读取图片，总是下载
+			if d.assets, err = newLocalAssets(path, d.ConvertImages); err != nil {
+				return err
+			}
+			defer d.assets.PrintSummary()
+		case d.OfflineAssets:
+			if d.assets, err = NewAssets(path, true, d.ConvertImages); err != nil {
+				return err
+			}
+			defer d.assets.PrintSummary()
+		}
+		// 合并时整门课程或每章生成一个 PDF
+		if (d.IsMerge || d.MergeChapter) && d.AID == 0 {
+			return DownloadPdfBook(d, course, path)
+		}
 		if err := DownloadPdfCourse(d, path); err != nil {
 			return err
 		}
@@ -97,19 +286,169 @@ func (d *CourseDownload) Download() error {
 		}
 	case 3:
 		// 下载 Markdown
-		path, err := utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), "MD")
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), "MD")
 		if err != nil {
 			return err
 		}
 		d.ClassName = course.ClassInfo.Name
+		if d.templates, err = currentTemplates(); err != nil {
+			return err
+		}
+		if d.OfflineAssets {
+			if d.assets, err = NewAssets(path, false, d.ConvertImages); err != nil {
+				return err
+			}
+			defer d.assets.PrintSummary()
+		}
 		if err := DownloadMarkdownCourse(d, path); err != nil {
 			return err
 		}
+	case 4:
+		// 生成 Obsidian 笔记
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), ObsidianDirName)
+		if err != nil {
+			return err
+		}
+		d.ClassName = course.ClassInfo.Name
+		if d.OfflineAssets {
+			if d.assets, err = NewAssets(path, false, d.ConvertImages); err != nil {
+				return err
+			}
+			defer d.assets.PrintSummary()
+		}
+		if err := DownloadObsidianCourse(d, course, path); err != nil {
+			return err
+		}
+	case 5:
+		// 生成 EPUB
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), EpubDirName)
+		if err != nil {
+			return err
+		}
+		d.ClassName = course.ClassInfo.Name
+		if d.templates, err = currentTemplates(); err != nil {
+			return err
+		}
+		if d.OfflineAssets {
+			if d.assets, err = newLocalAssets(path, d.ConvertImages); err != nil {
+				return err
+			}
+			defer d.assets.PrintSummary()
+		}
+		if err := DownloadEpubCourse(d, course, path); err != nil {
+			return err
+		}
+	case 6:
+		// 生成单文件 HTML，图片总是下载后内嵌
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), HTMLDirName)
+		if err != nil {
+			return err
+		}
+		d.ClassName = course.ClassInfo.Name
+		if d.templates, err = currentTemplates(); err != nil {
+			return err
+		}
+		if d.assets, err = newLocalAssets(path, d.ConvertImages); err != nil {
+			return err
+		}
+		defer d.assets.PrintSummary()
+		if err := DownloadHTMLBook(d, course, path); err != nil {
+			return err
+		}
+	case 7:
+		// 生成 Word 文档，图片总是下载后嵌入
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), DocxDirName)
+		if err != nil {
+			return err
+		}
+		d.ClassName = course.ClassInfo.Name
+		if d.assets, err = newLocalAssets(path, d.ConvertImages); err != nil {
+			return err
+		}
+		defer d.assets.PrintSummary()
+		if err := DownloadDocxCourse(d, course, path); err != nil {
+			return err
+		}
+	case 8:
+		// 导出 JSON
+		path, err = utils.Mkdir(OutputDir, utils.FileName(course.ClassInfo.Name, ""), JSONDirName)
+		if err != nil {
+			return err
+		}
+		d.ClassName = course.ClassInfo.Name
+		if d.OfflineAssets {
+			if d.assets, err = NewAssets(path, false, d.ConvertImages); err != nil {
+				return err
+			}
+			defer d.assets.PrintSummary()
+		}
+		if err := DownloadJSONCourse(d, course, path); err != nil {
+			return err
+		}
 	}
 	return nil
 
 }
 
+// transcript 根据文章文稿生成课程音频的字幕
+func (d *CourseDownload) transcript(article services.ArticleIntro, mp3 string) error {
+	if TranscriptExists(mp3) {
+		return nil
+	}
+	doc, _, err := d.articleDocument(article)
+	if err != nil {
+		return err
+	}
+	var duration time.Duration
+	if article.Audio != nil {
+		duration = time.Duration(article.Audio.Duration) * time.Second
+	}
+	return WriteTranscripts(mp3, article.Title, d.lecturer, doc, duration, d.TranscriptSilence)
+}
+
+// articleEvent 课程文章的下载事件
+func (d *CourseDownload) articleEvent(article services.ArticleIntro, index, total int) Event {
+	return Event{
+		Kind:     KindCourse,
+		ID:       d.ID,
+		Title:    d.ClassName,
+		Author:   d.lecturer,
+		Item:     article.Title,
+		ItemID:   article.ID,
+		ItemEnid: article.Enid,
+		Order:    article.OrderNum,
+		Format:   CourseFormatName(d.DownloadType),
+		Done:     index + 1,
+		Total:    total,
+	}
+}
+
+// courseInfo 课程信息，同一个下载器逐篇下载文章时只获取一次
+func (d *CourseDownload) courseInfo() (*services.CourseInfo, error) {
+	if d.course != nil {
+		return d.course, nil
+	}
+	course, err := CourseInfo(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.course = course
+	return course, nil
+}
+
+// articleList 课程文章列表，同一次下载只请求一次
+func (d *CourseDownload) articleList() (*services.ArticleList, error) {
+	if d.articles != nil {
+		return d.articles, nil
+	}
+	articles, err := ArticleList(d.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	d.articles = articles
+	return articles, nil
+}
+
 func (d *OdobDownload) Download() error {
 	fileName := "每天听本书"
 	article := config.Instance.GetCourseCache(CateAudioBook, d.ID)
@@ -140,20 +479,49 @@ func (d *OdobDownload) Download() error {
 			Title: fileName,
 		}
 		downloadData.Type = "audio"
-		downloadData.Data = extractOdobDownloadData(d.ID, article)
+		var audios []OdobAudioMeta
+		downloadData.Data, audios = extractOdobDownloadData(d.ID, article)
+		if err := SaveOdobMeta(article, audios); err != nil {
+			fmt.Printf("保存听书元数据失败: %v\n", err)
+		}
 		errs := make([]error, 0)
 		path, err := utils.Mkdir(OutputDir, utils.FileName(fileName, ""), "MP3")
 		if err != nil {
 			return err
 		}
-		for _, datum := range downloadData.Data {
+		for i, datum := range downloadData.Data {
+			if err := d.canceled(); err != nil {
+				return err
+			}
 			if !datum.IsCanDL {
 				continue
 			}
 			stream := datum.Enid
-			if err := downloader.Download(datum, stream, path); err != nil {
+			mp3 := filepath.Join(path, utils.FileName(datum.Title, "mp3"))
+			err := downloader.Download(datum, stream, path)
+			if err != nil {
 				errs = append(errs, err)
 			}
+			if err == nil && d.Transcript {
+				if err := d.transcript(article, audios, datum, mp3); err != nil {
+					fmt.Println(err)
+					errs = append(errs, err)
+				}
+			}
+			d.emitItem(Event{
+				Kind:     KindOdob,
+				ID:       d.ID,
+				Enid:     article.Enid,
+				Title:    article.Title,
+				Author:   article.Author,
+				Item:     datum.Title,
+				ItemID:   datum.ID,
+				ItemEnid: datum.Enid,
+				Format:   CourseFormatName(d.DownloadType),
+				Path:     mp3,
+				Done:     i + 1,
+				Total:    len(downloadData.Data),
+			}, err)
 		}
 		if len(errs) > 0 {
 			return errs[0]
@@ -163,12 +531,47 @@ func (d *OdobDownload) Download() error {
 		if err != nil {
 			return err
 		}
-		content, err2 := getArticleDetail(aliasID)
+		doc, err2 := getArticleDetail(aliasID)
 		if err2 != nil {
 			return err2
 		}
-		res := ContentsToMarkdown(content)
-		return utils.Md2Pdf(path, article.Title, []byte(res))
+		reportUnknown(article.Title, doc)
+		tmpl, err := currentTemplates()
+		if err != nil {
+			return err
+		}
+		profile, err := config.Instance.PDF.PrintProfile(d.PdfProfile)
+		if err != nil {
+			return err
+		}
+		engine := pdfEngine(d.PdfEngine)
+		if engine == PdfEngineNative || d.OfflineAssets {
+			// 内置引擎从本地读取图片，总是下载
+			var assets *Assets
+			if engine == PdfEngineNative {
+				assets, err = newLocalAssets(path, d.ConvertImages)
+			} else {
+				assets, err = NewAssets(path, true, d.ConvertImages)
+			}
+			if err != nil {
+				return err
+			}
+			assets.Localize(article.Title, doc)
+			defer assets.PrintSummary()
+		}
+		pdfPath := filepath.Join(path, utils.FileName(article.Title, "pdf"))
+		if engine == PdfEngineNative {
+			fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", filepath.Base(pdfPath))
+			err = nativeArticlePdf(pdfPath, odobArticleData(article, doc), profile)
+		} else {
+			var res string
+			if res, err = tmpl.HTML(odobArticleData(article, doc)); err != nil {
+				return err
+			}
+			err = utils.Html2Pdf(path, article.Title, []byte(res), profile)
+		}
+		d.emitItem(d.odobEvent(article, pdfPath), err)
+		return err
 
 	case 3:
 		// 下载 Markdown
@@ -176,19 +579,184 @@ func (d *OdobDownload) Download() error {
 		if err != nil {
 			return err
 		}
-		if err := DownloadMarkdownAudioBook(aliasID, path, article); err != nil {
+		tmpl, err := currentTemplates()
+		if err != nil {
+			return err
+		}
+		var assets *Assets
+		if d.OfflineAssets {
+			if assets, err = NewAssets(path, false, d.ConvertImages); err != nil {
+				return err
+			}
+			defer assets.PrintSummary()
+		}
+		err = DownloadMarkdownAudioBook(tmpl, assets, aliasID, path, article)
+		d.emitItem(d.odobEvent(article, filepath.Join(path, utils.FileName(article.Title, "md"))), err)
+		if err != nil {
+			return err
+		}
+	case 4:
+		// 生成 Obsidian 文献笔记
+		path, err := utils.Mkdir(OutputDir, utils.FileName(fileName, ""), ObsidianDirName)
+		if err != nil {
+			return err
+		}
+		var assets *Assets
+		if d.OfflineAssets {
+			if assets, err = NewAssets(path, false, d.ConvertImages); err != nil {
+				return err
+			}
+			defer assets.PrintSummary()
+		}
+		notePath, err := DownloadObsidianAudioBook(assets, aliasID, path, article)
+		d.emitItem(d.odobEvent(article, notePath), err)
+		if err != nil {
+			return err
+		}
+	case 5:
+		// 生成 EPUB
+		path, err := utils.Mkdir(OutputDir, utils.FileName(fileName, ""), EpubDirName)
+		if err != nil {
+			return err
+		}
+		tmpl, err := currentTemplates()
+		if err != nil {
+			return err
+		}
+		var assets *Assets
+		if d.OfflineAssets {
+			if assets, err = newLocalAssets(path, d.ConvertImages); err != nil {
+				return err
+			}
+			defer assets.PrintSummary()
+		}
+		epubPath, err := DownloadEpubAudioBook(tmpl, assets, aliasID, path, article, d.EmbedFonts)
+		d.emitItem(d.odobEvent(article, epubPath), err)
+		if err != nil {
+			return err
+		}
+	case 6:
+		// 生成单文件 HTML，图片总是下载后内嵌
+		path, err := utils.Mkdir(OutputDir, utils.FileName(fileName, ""), HTMLDirName)
+		if err != nil {
+			return err
+		}
+		tmpl, err := currentTemplates()
+		if err != nil {
+			return err
+		}
+		assets, err := newLocalAssets(path, d.ConvertImages)
+		if err != nil {
+			return err
+		}
+		defer assets.PrintSummary()
+		htmlPath, err := DownloadHTMLAudioBook(tmpl, assets, aliasID, path, article)
+		d.emitItem(d.odobEvent(article, htmlPath), err)
+		if err != nil {
+			return err
+		}
+	case 7:
+		// 生成 Word 文档，图片总是下载后嵌入
+		path, err := utils.Mkdir(OutputDir, utils.FileName(fileName, ""), DocxDirName)
+		if err != nil {
+			return err
+		}
+		assets, err := newLocalAssets(path, d.ConvertImages)
+		if err != nil {
+			return err
+		}
+		defer assets.PrintSummary()
+		docxPath, err := DownloadDocxAudioBook(assets, aliasID, path, article)
+		d.emitItem(d.odobEvent(article, docxPath), err)
+		if err != nil {
+			return err
+		}
+	case 8:
+		// 导出 JSON
+		path, err := utils.Mkdir(OutputDir, utils.FileName(fileName, ""), JSONDirName)
+		if err != nil {
+			return err
+		}
+		var assets *Assets
+		if d.OfflineAssets {
+			if assets, err = NewAssets(path, false, d.ConvertImages); err != nil {
+				return err
+			}
+			defer assets.PrintSummary()
+		}
+		jsonPath, err := DownloadJSONAudioBook(assets, aliasID, path, article)
+		d.emitItem(d.odobEvent(article, jsonPath), err)
+		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// transcript 根据文稿生成听书音频的字幕，合集中的每个音频使用各自的文稿
+func (d *OdobDownload) transcript(book *services.CourseV2, audios []OdobAudioMeta, datum downloader.Datum, mp3 string) error {
+	if TranscriptExists(mp3) {
+		return nil
+	}
+	audio := book.AudioDetail
+	for _, a := range audios {
+		if a.FileTitle == utils.FileName(datum.Title, "") {
+			audio = a.Audio
+			break
+		}
+	}
+	doc, err := getArticleDetail(audio.AliasID)
+	if err != nil {
+		return err
+	}
+	title := audio.Title
+	if title == "" {
+		title = book.Title
+	}
+	return WriteTranscripts(mp3, title, book.Author, doc, time.Duration(audio.Duration)*time.Second, d.TranscriptSilence)
+}
+
+// odobArticleData 每天听本书的模板数据
+func odobArticleData(article *services.CourseV2, doc *services.Document) ArticleData {
+	return ArticleData{Book: article, Title: article.Title, Author: article.Author, Document: doc}
+}
+
+// articleData 课程文章的模板数据，开启留言时获取留言
+func (d *CourseDownload) articleData(article services.ArticleIntro, enId string, doc *services.Document) ArticleData {
+	data := ArticleData{
+		Class:    d.class,
+		Article:  &article,
+		Title:    article.Title,
+		Author:   d.lecturer,
+		Document: doc,
+	}
+	if d.IsComment {
+		data.Comments = fetchComments(enId, d.CommentSort, d.CommentLimit)
+	}
+	return data
+}
+
+func (d *OdobDownload) odobEvent(article *services.CourseV2, path string) Event {
+	return Event{
+		Kind:   KindOdob,
+		ID:     d.ID,
+		Enid:   article.Enid,
+		Title:  article.Title,
+		Author: article.Author,
+		Item:   article.Title,
+		Format: CourseFormatName(d.DownloadType),
+		Path:   path,
+		Done:   1,
+		Total:  1,
+	}
+}
+
 func (d *EBookDownloadByEnID) Download() error {
 	detail, err := EbookDetailByEnID(d.EnID)
 	if err != nil {
 		return err
 	}
-	return downloadEBook(detail, d.DownloadType)
+	return d.downloadEBook(detail, d.DownloadType, d.PdfProfile, d.EmbedFonts)
 }
 
 func (d *EBookDownloadByID) Download() error {
@@ -196,10 +764,10 @@ func (d *EBookDownloadByID) Download() error {
 	if err != nil {
 		return err
 	}
-	return downloadEBook(detail, d.DownloadType)
+	return d.downloadEBook(detail, d.DownloadType, d.PdfProfile, d.EmbedFonts)
 }
 
-func downloadEBook(detail *services.EbookDetail, downloadType int) error {
+func (t *Task) downloadEBook(detail *services.EbookDetail, downloadType int, pdfProfile string, embed bool) (err error) {
 	title := strconv.Itoa(detail.ID) + "_"
 	if detail.Title != "" {
 		title += detail.Title
@@ -208,11 +776,29 @@ func downloadEBook(detail *services.EbookDetail, downloadType int) error {
 	}
 
 	title += "_" + detail.BookAuthor
+	format := EbookFormatName(downloadType)
+	defer func() {
+		t.emitItem(Event{
+			Kind:   KindEbook,
+			ID:     detail.ID,
+			Enid:   detail.Enid,
+			Title:  title,
+			Author: detail.BookAuthor,
+			Item:   title,
+			Format: format,
+			Path:   filepath.Join(OutputDir, EbookDirName, utils.FileName(title, format)),
+			Done:   1,
+			Total:  1,
+		}, err)
+	}()
 	info, svgContent, err := EbookPage(detail.Enid)
 	if err != nil {
 		return err
 	}
 	sort.Sort(svgContent)
+	if err := SaveEbookMeta(detail, title); err != nil {
+		fmt.Printf("保存电子书元数据失败: %v\n", err)
+	}
 
 	switch downloadType {
 	case 1:
@@ -221,7 +807,11 @@ func downloadEBook(detail *services.EbookDetail, downloadType int) error {
 		}
 
 	case 2:
-		if err = utils.Svg2Pdf(title, svgContent, info.BookInfo.Toc); err != nil {
+		var profile utils.PrintProfile
+		if profile, err = config.Instance.PDF.PrintProfile(pdfProfile); err != nil {
+			return err
+		}
+		if err = utils.Svg2Pdf(title, svgContent, info.BookInfo.Toc, profile); err != nil {
 			return err
 		}
 
@@ -231,6 +821,7 @@ func downloadEBook(detail *services.EbookDetail, downloadType int) error {
 		opts.Author = detail.BookAuthor
 		opts.Description = detail.BookIntro
 		opts.Toc = info.BookInfo.Toc
+		opts.EmbedFonts = embedFonts(embed)
 
 		if err = utils.Svg2Epub(title, svgContent, opts); err != nil {
 			return err
@@ -240,15 +831,54 @@ func downloadEBook(detail *services.EbookDetail, downloadType int) error {
 		if clearErr := services.ClearBookCache(detail.Enid); clearErr != nil {
 			fmt.Printf("Warning: Failed to clear book cache: %v\n", clearErr)
 		}
+
+	case 4:
+		if err = DownloadDocxEbook(title, detail, svgContent, info.BookInfo.Toc); err != nil {
+			return err
+		}
+	case 5:
+		if err = DownloadJSONEbook(title, detail, svgContent, info.BookInfo.Toc); err != nil {
+			return err
+		}
 	}
 
 	return err
 }
 
-func Download(downloader DeDaoDownloader) error {
+// Download 执行下载，期间执行 hooks 并推送 webhook
+// 命令行下载没有任务队列，在此发布任务开始和结束事件
+func Download(downloader DeDaoDownloader) (err error) {
+	jobID := ""
+	if t, ok := downloader.(interface{ task() *Task }); ok {
+		jobID = t.task().JobID
+	}
+	stopWebhooks := startWebhooks()
+	defer stopWebhooks()
+	hooks := &HookRunner{Hooks: config.Instance.Hooks}
+	stop := hooks.Start(jobID)
+	defer hooks.PrintSummary()
+	defer stop()
+	if jobID == "" {
+		defer publishJob(Task{}, downloadEvent(downloader), &err)()
+	}
 	return downloader.Download()
 }
 
+// downloadEvent 命令行下载任务的分类、ID 和格式
+func downloadEvent(downloader DeDaoDownloader) (e Event) {
+	switch d := downloader.(type) {
+	case *CourseDownload:
+		e = Event{Kind: KindCourse, ID: d.ID, Format: CourseFormatName(d.DownloadType)}
+	case *OdobDownload:
+		e = Event{Kind: KindOdob, ID: d.ID, Format: CourseFormatName(d.DownloadType)}
+	case *EBookDownloadByID:
+		e = Event{Kind: KindEbook, ID: d.ID, Format: EbookFormatName(d.DownloadType)}
+	case *EBookDownloadByEnID:
+		e = Event{Kind: KindEbook, Enid: d.EnID, Format: EbookFormatName(d.DownloadType)}
+	}
+	return
+}
+
 // 生成下载数据
 func extractDownloadData(course *services.CourseInfo, articles *services.ArticleList, aid int, flag int, isOrder bool) downloader.Data {
 
@@ -323,8 +953,8 @@ func extractCourseDownloadData(articles *services.ArticleList, aid int, flag int
 }
 
 // 生成 AudioBook 下载数据
-func extractOdobDownloadData(aid int, article *services.CourseV2) []downloader.Datum {
-	data := downloader.EmptyData
+func extractOdobDownloadData(aid int, article *services.CourseV2) (data []downloader.Datum, audios []OdobAudioMeta) {
+	data = downloader.EmptyData
 	audioIds := map[int]string{}
 	audioData := make([]*downloader.Datum, 0)
 	aliasID := article.AudioDetail.AliasID
@@ -348,8 +978,12 @@ func extractOdobDownloadData(aid int, article *services.CourseV2) []downloader.D
 		detail, err := getService().AudioDetailAlias(aliasID)
 		if err != nil {
 			fmt.Println(err)
-			return nil
+			return nil, nil
 		}
+		audios = append(audios, OdobAudioMeta{
+			FileTitle: utils.FileName(article.Title, ""),
+			Audio:     *detail,
+		})
 		datum := &downloader.Datum{
 			ID:      aid,
 			Enid:    article.Enid,
@@ -372,11 +1006,11 @@ func extractOdobDownloadData(aid int, article *services.CourseV2) []downloader.D
 		details, err := getService().TopicPkgOdobDetails(article.Enid)
 		if err != nil {
 			fmt.Println(err)
-			return nil
+			return nil, nil
 		}
 
 		if details == nil || len(details.OdobAudioDetailList) == 0 {
-			return nil
+			return nil, nil
 		}
 
 		// 遍历合集中的每个音频
@@ -415,6 +1049,10 @@ func extractOdobDownloadData(aid int, article *services.CourseV2) []downloader.D
 			// 可以根据顺序添加序号
 			orderNum := i + 1
 			title = fmt.Sprintf("%s%03d.%s", audio.PackageTitle, orderNum, title)
+			audios = append(audios, OdobAudioMeta{
+				FileTitle: utils.FileName(title, ""),
+				Audio:     audio,
+			})
 
 			datum := &downloader.Datum{
 				ID:      audioID,
@@ -439,7 +1077,7 @@ func extractOdobDownloadData(aid int, article *services.CourseV2) []downloader.D
 		}
 	}
 
-	return data
+	return
 }
 
 func handleStreams(audioData []*downloader.Datum, audioIds map[int]string) {
@@ -473,143 +1111,8 @@ func handleStreams(audioData []*downloader.Datum, audioIds map[int]string) {
 	wgp.Wait()
 }
 
-func ContentsToMarkdown(contents []services.Content) (res string) {
-	for _, content := range contents {
-		switch content.Type {
-		case "audio":
-			title := strings.TrimRight(content.Title, ".mp3")
-			res += getMdHeader(1) + title + "\r\n\r\n"
-		case "header":
-			content.Text = strings.Trim(content.Text, " ")
-			if len(content.Text) > 0 {
-				res += getMdHeader(content.Level) + content.Text + "\r\n\r\n"
-			}
-		case "blockquote":
-			texts := strings.Split(content.Text, "\n")
-			for _, text := range texts {
-				res += "> " + text + "\r\n"
-				res += "> \r\n"
-			}
-			res = strings.TrimRight(res, "> \r\n")
-			res += "\r\n\r\n"
-		case "paragraph":
-			resP, err := paragraphToMarkDown(content.Contents)
-			if err != nil {
-				return
-			}
-			res += resP
-		case "list":
-			resL, err := listToMarkdown(content.Contents)
-			if err != nil {
-				return
-			}
-			res += resL
-		case "elite": // 划重点
-			res += getMdHeader(2) + "划重点\r\n\r\n" + content.Text + "\r\n\r\n"
-
-		case "image":
-			res += "![" + content.URL + "](" + content.URL
-			if content.Legend != "" {
-				res += " \"" + content.Legend + "\""
-			}
-			res += ")" + "\r\n\r\n"
-		case "label-group":
-			res += getMdHeader(2) + "`" + content.Text + "`" + "\r\n\r\n"
-		}
-	}
-
-	res += "---\r\n"
-	return
-}
-
-func paragraphToMarkDown(content interface{}) (res string, err error) {
-	tmpJson, err := jsoniter.Marshal(content)
-	if err != nil {
-		return
-	}
-	cont := services.Contents{}
-	err = jsoniter.Unmarshal(tmpJson, &cont)
-	if err != nil {
-		return
-	}
-	for _, item := range cont {
-		subContent := strings.Trim(item.Text.Content, " ")
-		switch item.Type {
-		case "text":
-			if item.Text.Bold {
-				res += " **" + subContent + "** "
-			} else if item.Text.Highlight {
-				res += " *" + subContent + "* "
-			} else {
-				res += subContent
-			}
-		}
-	}
-	res = strings.Trim(res, " ")
-	res = strings.Trim(res, "\r\n")
-	res += "\r\n\r\n"
-	return
-}
-
-func listToMarkdown(content interface{}) (res string, err error) {
-	tmpJson, err := jsoniter.Marshal(content)
-	if err != nil {
-		return
-	}
-	var cont []services.Contents
-	err = jsoniter.Unmarshal(tmpJson, &cont)
-	if err != nil {
-		return
-	}
-
-	for _, item := range cont {
-		for _, item := range item {
-			subContent := strings.Trim(item.Text.Content, " ")
-			switch item.Type {
-			case "text":
-				if item.Text.Bold {
-					res += "* **" + subContent + "** "
-				} else if item.Text.Highlight {
-					res += "* *" + subContent + "* "
-				} else {
-					res += "* " + subContent
-				}
-			}
-		}
-		res += "\r\n\r\n"
-	}
-	return
-}
-
-func articleCommentsToMarkdown(contents []services.ArticleComment) (res string) {
-	res = getMdHeader(2) + "热门留言\r\n\r\n"
-	for _, content := range contents {
-		res += content.NotesOwner.Name + "：" + content.Note + "\r\n\r\n"
-		if content.CommentReply != "" {
-			res += "> " + content.CommentReplyUser.Name + "(" + content.CommentReplyUser.Role + ") 回复：" + content.CommentReply + "\r\n\r\n"
-		}
-	}
-	res += "---\r\n"
-	return
-}
-
-func getMdHeader(level int) string {
-	heads := map[int]string{
-		1: "# ",
-		2: "## ",
-		3: "### ",
-		4: "#### ",
-		5: "##### ",
-		6: "###### ",
-	}
-	if s, ok := heads[level]; ok {
-		return s
-	}
-	return ""
-}
-
 func DownloadMarkdownCourse(d *CourseDownload, path string) error {
-	list, err := ArticleList(d.ID, "")
+	list, err := d.articleList()
 	if err != nil {
 		return err
 	}
@@ -619,49 +1122,64 @@ func DownloadMarkdownCourse(d *CourseDownload, path string) error {
 		mName = utils.FileName(d.ClassName+"-合集", "md")
 		mFileName = filepath.Join(path, mName)
 		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】\n", mFileName)
+		if err := d.writeCourseHeader(mFileName, list); err != nil {
+			return err
+		}
 	}
-	for _, v := range list.List {
+	for i, v := range list.List {
 		if d.AID > 0 && v.ID != d.AID {
 			continue
 		}
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		event := d.articleEvent(v, i, len(list.List))
 		detail, enId, err := ArticleDetail(d.ID, v.ID)
 		if err != nil {
 			fmt.Println(err.Error())
+			d.emitItem(event, err)
 			return err
 		}
 		// fmt.Printf("%#v\n", detail)
 
-		var content []services.Content
-		err = jsoniter.UnmarshalFromString(detail.Content, &content)
+		doc, err := services.ParseDocument(detail.Content)
 		if err != nil {
+			d.emitItem(event, err)
 			return err
 		}
+		reportUnknown(v.Title, doc)
 
 		name = utils.FileName(v.Title, "md")
 		if d.IsOrder {
 			name = fmt.Sprintf("%03d.%s", v.OrderNum, name)
 		}
 		fileName = filepath.Join(path, name)
+		event.Path = fileName
 		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
 		_, exist, err := utils.FileSize(fileName)
 
 		if err != nil {
 			fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+			d.emitItem(event, err)
 			return err
 		}
 
 		if exist {
 			fmt.Printf("\033[33;1m%s\033[0m\n", "已存在")
+			event.Type = EventItemSkipped
+			d.emitItem(event, nil)
 			continue
 		}
 
-		res := ContentsToMarkdown(content)
-		if d.IsComment {
-			// 添加留言
-			commentList, err := ArticleCommentList(enId, "like", 1, 20)
-			if err == nil {
-				res += articleCommentsToMarkdown(commentList.List)
-			}
+		d.withPoints(v, doc)
+		if d.assets != nil {
+			d.assets.Localize(v.Title, doc)
+		}
+		res, err := d.templates.Markdown(d.articleData(v, enId, doc))
+		if err != nil {
+			fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+			d.emitItem(event, err)
+			return err
 		}
 
 		f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -678,6 +1196,7 @@ func DownloadMarkdownCourse(d *CourseDownload, path string) error {
 			return err
 		}
 		fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+		d.emitItem(event, nil)
 		if d.IsMerge {
 			f, err := os.OpenFile(mFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 			if err != nil {
@@ -700,106 +1219,99 @@ func DownloadMarkdownCourse(d *CourseDownload, path string) error {
 	return nil
 }
 
+// writeCourseHeader 按课程模板生成合集开头，合集已存在或模板为空时跳过
+func (d *CourseDownload) writeCourseHeader(fileName string, list *services.ArticleList) error {
+	if _, exist, err := utils.FileSize(fileName); err != nil || exist {
+		return err
+	}
+	header, err := d.templates.CourseMarkdown(CourseData{Class: d.class, Articles: list.List})
+	if err != nil || header == "" {
+		return err
+	}
+	return os.WriteFile(fileName, []byte(header), 0644)
+}
+
 func DownloadPdfCourse(d *CourseDownload, path string) error {
-	list, err := ArticleList(d.ID, "")
+	list, err := d.articleList()
 	if err != nil {
 		return err
 	}
 	name, fileName := "", ""
-	// mName, mFileName := "", ""
-	// if d.IsMerge {
-	// 	mName = utils.FileName(d.ClassName+"-合集", "pdf")
-	// 	mFileName = filepath.Join(path, mName)
-	// 	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】\n", mFileName)
-	// }
-	for _, v := range list.List {
+	for i, v := range list.List {
 		if d.AID > 0 && v.ID != d.AID {
 			continue
 		}
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		event := d.articleEvent(v, i, len(list.List))
 		detail, enId, err := ArticleDetail(d.ID, v.ID)
 		if err != nil {
 			fmt.Println(err.Error())
+			d.emitItem(event, err)
 			return err
 		}
 		// fmt.Printf("%#v\n", detail)
 
-		var content []services.Content
-		err = jsoniter.UnmarshalFromString(detail.Content, &content)
+		doc, err := services.ParseDocument(detail.Content)
 		if err != nil {
+			d.emitItem(event, err)
 			return err
 		}
+		reportUnknown(v.Title, doc)
 
 		name = utils.FileName(v.Title, "pdf")
 		if d.IsOrder {
 			name = fmt.Sprintf("%03d.%s", v.OrderNum, name)
 		}
 		fileName = filepath.Join(path, name)
+		event.Path = fileName
 		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
 		_, exist, err := utils.FileSize(fileName)
 
 		if err != nil {
 			fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+			d.emitItem(event, err)
 			return err
 		}
 
 		if exist {
 			fmt.Printf("\033[33;1m%s\033[0m\n", "已存在")
+			event.Type = EventItemSkipped
+			d.emitItem(event, nil)
 			continue
 		}
 
-		res := ContentsToMarkdown(content)
-		if d.IsComment {
-			// 添加留言
-			commentList, err := ArticleCommentList(enId, "like", 1, 20)
-			if err == nil {
-				res += articleCommentsToMarkdown(commentList.List)
-			}
-		}
-		err = utils.Md2Pdf(path, name, []byte(res))
-		if err != nil {
-			return err
-		}
-		// 	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		// 	if err != nil {
-		// 		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
-		// 		return err
-		// 	}
-		// 	_, err = f.WriteString(res)
-		// 	if err != nil {
-		// 		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
-		// 		return err
-		// 	}
-		// 	if err = f.Close(); err != nil {
-		// 		return err
-		// 	}
-		// 	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
-		// 	if d.IsMerge {
-		// 		f, err := os.OpenFile(mFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		// 		if err != nil {
-		// 			fmt.Printf("\033[31;1m%s\033[0m\n", "合集失败"+err.Error())
-		// 			return err
-		// 		}
-		// 		_, err = f.WriteString(res)
-		// 		if err != nil {
-		// 			fmt.Printf("\033[31;1m%s\033[0m\n", "合集失败"+err.Error())
-		// 			return err
-		// 		}
-		// 		if err = f.Close(); err != nil {
-		// 			return err
-		// 		}
-		// 	}
-		// }
-		// if d.IsMerge {
-		// 	fmt.Printf("\033[32;1m%s\033[0m\n", "合集完成")
+		d.withPoints(v, doc)
+		if d.assets != nil {
+			d.assets.Localize(v.Title, doc)
+		}
+		data := d.articleData(v, enId, doc)
+		if d.pdfEngine == PdfEngineNative {
+			err = nativeArticlePdf(fileName, data, d.profile)
+		} else {
+			var res string
+			if res, err = d.templates.HTML(data); err != nil {
+				fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+				d.emitItem(event, err)
+				return err
+			}
+			err = utils.Html2Pdf(path, strings.TrimSuffix(name, ".pdf"), []byte(res), d.profile)
+		}
+		d.emitItem(event, err)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func DownloadMarkdownAudioBook(aliasID, path string, article *services.CourseV2) error {
-	content, err2 := getArticleDetail(aliasID)
+func DownloadMarkdownAudioBook(tmpl *Templates, assets *Assets, aliasID, path string, article *services.CourseV2) error {
+	doc, err2 := getArticleDetail(aliasID)
 	if err2 != nil {
 		return err2
 	}
+	reportUnknown(article.Title, doc)
 
 	name := utils.FileName(article.Title, "md")
 	fileName := filepath.Join(path, name)
@@ -816,7 +1328,14 @@ func DownloadMarkdownAudioBook(aliasID, path string, article *services.CourseV2)
 		return nil
 	}
 
-	res := ContentsToMarkdown(content)
+	if assets != nil {
+		assets.Localize(article.Title, doc)
+	}
+	res, err := tmpl.Markdown(odobArticleData(article, doc))
+	if err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return err
+	}
 
 	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -835,7 +1354,7 @@ func DownloadMarkdownAudioBook(aliasID, path string, article *services.CourseV2)
 	return nil
 }
 
-func getArticleDetail(aliasID string) (content []services.Content, err error) {
+func getArticleDetail(aliasID string) (doc *services.Document, err error) {
 
 	if aliasID == "" {
 		return nil, errors.New("ID 为空")
@@ -847,10 +1366,5 @@ func getArticleDetail(aliasID string) (content []services.Content, err error) {
 		return nil, err
 	}
 
-	err = jsoniter.UnmarshalFromString(detail.Content, &content)
-	if err != nil {
-		return nil, err
-	}
-
-	return content, nil
+	return services.ParseDocument(detail.Content)
 }
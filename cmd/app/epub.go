@@ -0,0 +1,153 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// EpubDirName 课程、听书 EPUB 目录名
+const EpubDirName = "EPUB"
+
+// embedFonts 是否在 EPUB 中嵌入字体，未指定时使用 config.json 的 Fonts.embed_epub
+func embedFonts(embed bool) bool {
+	return embed || config.Instance.Fonts.EmbedEpub
+}
+
+// DownloadEpubCourse 将课程生成一个 EPUB，章节为一级目录，文章为二级目录，没有章节时文章为一级目录
+// 指定文章时只包含该文章
+func DownloadEpubCourse(d *CourseDownload, course *services.CourseInfo, path string) error {
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	info := course.ClassInfo
+	title := info.Name
+	articles := list.List
+	if d.AID > 0 {
+		articles = nil
+		for _, v := range list.List {
+			if v.ID == d.AID {
+				articles = append(articles, v)
+				title = info.Name + "-" + v.Title
+			}
+		}
+	}
+
+	name := utils.FileName(title, "epub")
+	event := Event{
+		Kind:   KindCourse,
+		ID:     d.ID,
+		Enid:   info.Enid,
+		Title:  d.ClassName,
+		Author: d.lecturer,
+		Item:   title,
+		Format: CourseFormatName(d.DownloadType),
+		Path:   filepath.Join(path, name),
+		Done:   1,
+		Total:  1,
+	}
+	_, exist, err := utils.FileSize(event.Path)
+	if err != nil {
+		d.emitItem(event, err)
+		return err
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		event.Type = EventItemSkipped
+		d.emitItem(event, nil)
+		return nil
+	}
+
+	var contents []utils.HtmlContent
+	for i, chapter := range GroupByChapter(course.ChapterList, articles) {
+		level := 1
+		if chapter.Name != "" {
+			contents = append(contents, utils.HtmlContent{
+				Content:   fmt.Sprintf("<h1 class=\"chapter\">%s</h1>\n", html.EscapeString(chapter.Name)),
+				ChapterID: fmt.Sprintf("chapter_%03d.xhtml", i+1),
+				TocLevel:  1,
+				TocText:   chapter.Name,
+			})
+			level = 2
+		}
+		for _, v := range chapter.Articles {
+			if err := d.canceled(); err != nil {
+				return err
+			}
+			doc, enId, err := d.articleDocument(v)
+			if err != nil {
+				d.emitItem(event, err)
+				return err
+			}
+			res, err := d.templates.EPUB(d.articleData(v, enId, doc))
+			if err != nil {
+				d.emitItem(event, err)
+				return err
+			}
+			contents = append(contents, utils.HtmlContent{
+				Content:   fmt.Sprintf("<h1>%s</h1>\n%s", html.EscapeString(v.Title), res),
+				ChapterID: fmt.Sprintf("article_%d.xhtml", v.ID),
+				TocLevel:  level,
+				TocText:   v.Title,
+			})
+		}
+	}
+
+	err = utils.Html2Epub(utils.EpubOptions{
+		Title:       title,
+		Author:      info.LecturerName,
+		Description: info.Intro,
+		Output:      event.Path,
+		ImagesDir:   filepath.Join(path, "images"),
+		EmbedFonts:  embedFonts(d.EmbedFonts),
+		HTML:        contents,
+	}, info.Logo)
+	d.emitItem(event, err)
+	return err
+}
+
+// DownloadEpubAudioBook 将每天听本书文稿生成 EPUB
+func DownloadEpubAudioBook(tmpl *Templates, assets *Assets, aliasID, path string, book *services.CourseV2, embed bool) (fileName string, err error) {
+	name := utils.FileName(book.Title, "epub")
+	fileName = filepath.Join(path, name)
+	_, exist, err := utils.FileSize(fileName)
+	if err != nil {
+		return
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		return
+	}
+
+	doc, err := getArticleDetail(aliasID)
+	if err != nil {
+		return
+	}
+	reportUnknown(book.Title, doc)
+	if assets != nil {
+		assets.Localize(book.Title, doc)
+	}
+	res, err := tmpl.EPUB(odobArticleData(book, doc))
+	if err != nil {
+		return
+	}
+	err = utils.Html2Epub(utils.EpubOptions{
+		Title:       book.Title,
+		Author:      book.Author,
+		Description: book.Intro,
+		Output:      fileName,
+		ImagesDir:   filepath.Join(path, "images"),
+		EmbedFonts:  embedFonts(embed),
+		HTML: []utils.HtmlContent{{
+			Content:   res,
+			ChapterID: "article.xhtml",
+			TocText:   book.Title,
+		}},
+	}, book.Icon)
+	return
+}
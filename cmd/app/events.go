@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 下载事件类型
+const (
+	EventJobStarted   = "job.started"
+	EventJobFinished  = "job.finished"
+	EventJobFailed    = "job.failed"
+	EventJobCanceled  = "job.canceled"
+	EventItemFinished = "item.finished"
+	EventItemFailed   = "item.failed"
+	EventItemSkipped  = "item.skipped"
+	// EventCourseFinished 整门课程下载完成，只下载单篇文章时不发布
+	EventCourseFinished = "course.finished"
+)
+
+// 下载内容分类
+const (
+	KindCourse = "course"
+	KindOdob   = "odob"
+	KindEbook  = "ebook"
+)
+
+// Event 下载过程中的事件，API 进度推送、webhook 等通过 Subscribe 订阅
+type Event struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	JobID    string    `json:"job_id,omitempty"`
+	Kind     string    `json:"kind,omitempty"` // course, odob, ebook
+	ID       int       `json:"id,omitempty"`
+	Enid     string    `json:"enid,omitempty"`
+	Title    string    `json:"title,omitempty"`  // 课程名或书名
+	Author   string    `json:"author,omitempty"` // 讲师或作者
+	Item     string    `json:"item,omitempty"`   // 文章或音频标题
+	ItemID   int       `json:"item_id,omitempty"`
+	ItemEnid string    `json:"item_enid,omitempty"`
+	Order    int       `json:"order,omitempty"` // 文章在课程中的序号
+	Format   string    `json:"format,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Paths    []string  `json:"paths,omitempty"` // 任务生成或已存在的文件，只在任务结束事件中提供
+	Error    string    `json:"error,omitempty"`
+	Done     int       `json:"done,omitempty"`  // 已处理数量
+	Total    int       `json:"total,omitempty"` // 总数量
+}
+
+var (
+	subscribers   = map[int]*subscriber{}
+	subscriberSeq int
+	subscriberMu  sync.RWMutex
+)
+
+// subscriber 订阅者，取消订阅后不再回调
+type subscriber struct {
+	mu     sync.Mutex
+	fn     func(Event)
+	closed bool
+}
+
+// Subscribe 订阅下载事件，返回取消订阅的函数，取消订阅返回后回调不会再被调用
+// 回调在下载协程中同步执行，耗时操作需自行异步处理
+func Subscribe(fn func(Event)) (unsubscribe func()) {
+	s := &subscriber{fn: fn}
+	subscriberMu.Lock()
+	subscriberSeq++
+	id := subscriberSeq
+	subscribers[id] = s
+	subscriberMu.Unlock()
+	return func() {
+		subscriberMu.Lock()
+		delete(subscribers, id)
+		subscriberMu.Unlock()
+		// 等待正在执行的回调结束
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+	}
+}
+
+// Publish 发布下载事件，回调在锁外执行，回调中可以订阅或取消其他订阅
+func Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	subscriberMu.RLock()
+	list := make([]*subscriber, 0, len(subscribers))
+	for _, s := range subscribers {
+		list = append(list, s)
+	}
+	subscriberMu.RUnlock()
+	for _, s := range list {
+		s.call(e)
+	}
+}
+
+func (s *subscriber) call(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.fn(e)
+	}
+}
+
+// Task 下载任务的上下文，零值即命令行下载
+type Task struct {
+	Ctx   context.Context `json:"-"`
+	JobID string          `json:"-"`
+}
+
+// task 供任务队列设置上下文
+func (t *Task) task() *Task {
+	return t
+}
+
+// canceled 任务是否已取消
+func (t *Task) canceled() error {
+	if t.Ctx == nil {
+		return nil
+	}
+	return t.Ctx.Err()
+}
+
+// emit 发布当前任务的事件
+func (t *Task) emit(e Event) {
+	e.JobID = t.JobID
+	Publish(e)
+}
+
+// emitItem 发布单个文件的处理结果
+func (t *Task) emitItem(e Event, err error) {
+	switch {
+	case err != nil:
+		e.Type = EventItemFailed
+		e.Error = err.Error()
+	case e.Type == "":
+		e.Type = EventItemFinished
+	}
+	t.emit(e)
+}
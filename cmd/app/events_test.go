@@ -0,0 +1,22 @@
+package app
+
+import "testing"
+
+func TestPublish(t *testing.T) {
+	var got []string
+	var inner func()
+	unsubscribe := Subscribe(func(e Event) {
+		got = append(got, e.Type)
+		// 回调中订阅不会死锁
+		if inner == nil {
+			inner = Subscribe(func(Event) {})
+		}
+	})
+	Publish(Event{Type: EventJobStarted})
+	unsubscribe()
+	inner()
+	Publish(Event{Type: EventJobFinished})
+	if len(got) != 1 || got[0] != EventJobStarted {
+		t.Errorf("events = %q", got)
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/config"
+)
+
+// 执行时机
+const (
+	HookOnItem   = "item"
+	HookOnCourse = "course"
+)
+
+// HookRunner 在文件生成或课程下载完成后执行 config.json 中配置的命令
+// 命令通过环境变量 DEDAO_* 和标准输入中的事件 JSON 获取路径、格式、课程名等信息
+type HookRunner struct {
+	Hooks []config.HookConfig
+
+	runs     int
+	failures []hookFailure
+}
+
+type hookFailure struct {
+	command string
+	path    string
+	err     error
+}
+
+// Start 订阅 jobID 的下载事件并执行命令，stop 取消订阅并等待队列中的命令执行完成
+// 命令在单独的协程中按事件顺序逐个执行，不阻塞下载，队列满时才会等待
+func (h *HookRunner) Start(jobID string) (stop func()) {
+	if len(h.Hooks) == 0 {
+		return func() {}
+	}
+	queue := make(chan Event, 1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range queue {
+			for _, hook := range h.Hooks {
+				if matchHook(hook, e) {
+					h.run(hook, e)
+				}
+			}
+		}
+	}()
+	unsubscribe := Subscribe(func(e Event) {
+		if e.JobID == jobID {
+			queue <- e
+		}
+	})
+	return func() {
+		// 取消订阅后不会再向队列发送，可以安全关闭
+		unsubscribe()
+		close(queue)
+		<-done
+	}
+}
+
+// PrintSummary 输出执行失败的命令
+func (h *HookRunner) PrintSummary() {
+	if h.runs == 0 {
+		return
+	}
+	if len(h.failures) == 0 {
+		fmt.Printf("已执行 %d 次 hook\n", h.runs)
+		return
+	}
+	fmt.Printf("\033[31;1m已执行 %d 次 hook，失败 %d 次：\033[0m\n", h.runs, len(h.failures))
+	for _, f := range h.failures {
+		fmt.Printf("  %s\n    文件：%s\n    错误：%v\n", f.command, f.path, f.err)
+	}
+}
+
+func (h *HookRunner) run(hook config.HookConfig, e Event) {
+	h.runs++
+	if err := runHook(hook.Command, e); err != nil {
+		h.failures = append(h.failures, hookFailure{command: hook.Command, path: e.Path, err: err})
+	}
+}
+
+func matchHook(hook config.HookConfig, e Event) bool {
+	on := hook.On
+	if on == "" {
+		on = HookOnItem
+	}
+	switch {
+	case on == HookOnItem && e.Type == EventItemFinished:
+	case on == HookOnCourse && e.Type == EventCourseFinished:
+	default:
+		return false
+	}
+	if len(hook.Kinds) > 0 && !slices.Contains(hook.Kinds, e.Kind) {
+		return false
+	}
+	if len(hook.Formats) > 0 && !slices.Contains(hook.Formats, e.Format) {
+		return false
+	}
+	return hook.Command != ""
+}
+
+// runHook 执行命令，退出码非 0 时返回错误，错误信息包含标准错误输出
+func runHook(command string, e Event) error {
+	input, err := jsoniter.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), hookEnv(e)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = fmt.Errorf("退出码 %d", exitErr.ExitCode())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+	}
+	return err
+}
+
+// hookEnv 传递给命令的环境变量
+func hookEnv(e Event) []string {
+	env := map[string]string{
+		"DEDAO_EVENT":     e.Type,
+		"DEDAO_KIND":      e.Kind,
+		"DEDAO_PATH":      e.Path,
+		"DEDAO_FORMAT":    e.Format,
+		"DEDAO_TITLE":     e.Title,
+		"DEDAO_AUTHOR":    e.Author,
+		"DEDAO_ITEM":      e.Item,
+		"DEDAO_ENID":      e.Enid,
+		"DEDAO_ITEM_ENID": e.ItemEnid,
+	}
+	if e.ID > 0 {
+		env["DEDAO_ID"] = strconv.Itoa(e.ID)
+	}
+	if e.ItemID > 0 {
+		env["DEDAO_ITEM_ID"] = strconv.Itoa(e.ItemID)
+	}
+	if e.Order > 0 {
+		env["DEDAO_ORDER"] = strconv.Itoa(e.Order)
+	}
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	return list
+}
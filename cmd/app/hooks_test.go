@@ -0,0 +1,114 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/config"
+)
+
+func TestMatchHook(t *testing.T) {
+	item := Event{Type: EventItemFinished, Kind: KindCourse, Format: "md"}
+	course := Event{Type: EventCourseFinished, Kind: KindCourse, Format: "md"}
+	cases := []struct {
+		name  string
+		hook  config.HookConfig
+		event Event
+		want  bool
+	}{
+		{"默认每个文件执行", config.HookConfig{Command: "true"}, item, true},
+		{"默认不在课程完成时执行", config.HookConfig{Command: "true"}, course, false},
+		{"课程完成", config.HookConfig{Command: "true", On: HookOnCourse}, course, true},
+		{"课程完成时不对文件执行", config.HookConfig{Command: "true", On: HookOnCourse}, item, false},
+		{"跳过的文件不执行", config.HookConfig{Command: "true"}, Event{Type: EventItemSkipped, Kind: KindCourse}, false},
+		{"失败的文件不执行", config.HookConfig{Command: "true"}, Event{Type: EventItemFailed, Kind: KindCourse}, false},
+		{"分类匹配", config.HookConfig{Command: "true", Kinds: []string{KindOdob, KindCourse}}, item, true},
+		{"分类不匹配", config.HookConfig{Command: "true", Kinds: []string{KindEbook}}, item, false},
+		{"格式匹配", config.HookConfig{Command: "true", Formats: []string{"md"}}, item, true},
+		{"格式不匹配", config.HookConfig{Command: "true", Formats: []string{"mp3"}}, item, false},
+		{"未配置命令", config.HookConfig{}, item, false},
+		{"未知时机", config.HookConfig{Command: "true", On: "job"}, item, false},
+	}
+	for _, c := range cases {
+		if got := matchHook(c.hook, c.event); got != c.want {
+			t.Errorf("%s: matchHook = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestHookEnv(t *testing.T) {
+	env := hookEnv(Event{
+		Type:     EventItemFinished,
+		Kind:     KindCourse,
+		ID:       12,
+		Enid:     "course-enid",
+		Title:    "课程",
+		Author:   "讲师",
+		Item:     "第一讲",
+		ItemID:   34,
+		ItemEnid: "article-enid",
+		Order:    1,
+		Format:   "md",
+		Path:     "output/课程/MD/第一讲.md",
+	})
+	slices.Sort(env)
+	want := []string{
+		"DEDAO_AUTHOR=讲师",
+		"DEDAO_ENID=course-enid",
+		"DEDAO_EVENT=item.finished",
+		"DEDAO_FORMAT=md",
+		"DEDAO_ID=12",
+		"DEDAO_ITEM=第一讲",
+		"DEDAO_ITEM_ENID=article-enid",
+		"DEDAO_ITEM_ID=34",
+		"DEDAO_KIND=course",
+		"DEDAO_ORDER=1",
+		"DEDAO_PATH=output/课程/MD/第一讲.md",
+		"DEDAO_TITLE=课程",
+	}
+	if !slices.Equal(env, want) {
+		t.Errorf("hookEnv = %q, want %q", env, want)
+	}
+
+	// ID 为 0 时不设置
+	env = hookEnv(Event{Type: EventCourseFinished, Kind: KindOdob})
+	for _, v := range env {
+		if strings.HasPrefix(v, "DEDAO_ID=") || strings.HasPrefix(v, "DEDAO_ITEM_ID=") || strings.HasPrefix(v, "DEDAO_ORDER=") {
+			t.Errorf("unexpected %s", v)
+		}
+	}
+}
+
+func TestHookRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook 命令使用 sh")
+	}
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	h := &HookRunner{Hooks: []config.HookConfig{
+		{Command: `echo "$DEDAO_ITEM" >> "` + out + `"`},
+		{Command: "exit 3", On: HookOnCourse},
+	}}
+	stop := h.Start("hook-test")
+	Publish(Event{Type: EventItemFinished, JobID: "hook-test", Item: "第一讲"})
+	Publish(Event{Type: EventItemFinished, JobID: "other", Item: "其他"})
+	Publish(Event{Type: EventItemFinished, JobID: "hook-test", Item: "第二讲"})
+	Publish(Event{Type: EventCourseFinished, JobID: "hook-test", Path: "output/课程"})
+	// stop 等待队列中的命令执行完成
+	stop()
+	Publish(Event{Type: EventItemFinished, JobID: "hook-test", Item: "第三讲"})
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "第一讲\n第二讲\n" {
+		t.Errorf("hooks output = %q", got)
+	}
+	if h.runs != 3 || len(h.failures) != 1 || h.failures[0].err.Error() != "退出码 3" || h.failures[0].path != "output/课程" {
+		t.Errorf("runs = %d, failures = %+v", h.runs, h.failures)
+	}
+}
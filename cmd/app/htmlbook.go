@@ -0,0 +1,243 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// HTMLDirName 单文件 HTML 目录名
+const HTMLDirName = "HTML"
+
+// htmlBookCSS 单文件 HTML 的布局和明暗主题，文章排版沿用 PDF 的样式
+const htmlBookCSS = `
+		:root { --bg: #fff; --fg: #333; --muted: #888; --border: #eee; --panel: #f7f7f7; --link: #e05d00; }
+		:root[data-theme="dark"] { --bg: #1e1e1e; --fg: #d4d4d4; --muted: #999; --border: #3a3a3a; --panel: #262626; --link: #ff9a4d; }
+		@media (prefers-color-scheme: dark) {
+			:root:not([data-theme="light"]) { --bg: #1e1e1e; --fg: #d4d4d4; --muted: #999; --border: #3a3a3a; --panel: #262626; --link: #ff9a4d; }
+		}
+		html { scroll-behavior: smooth; }
+		body { margin: 0; background: var(--bg); color: var(--fg); }
+		a { color: var(--link); }
+		.layout { display: flex; align-items: flex-start; }
+		.sidebar { position: sticky; top: 0; width: 300px; height: 100vh; flex-shrink: 0; overflow-y: auto; box-sizing: border-box; padding: 20px; background: var(--panel); border-right: 1px solid var(--border); font-size: 14px; line-height: 1.6; }
+		.sidebar ol { list-style: none; margin: 0; padding: 0; }
+		.sidebar ol ol { padding-left: 1em; }
+		.sidebar li { margin: 4px 0; }
+		.sidebar a { color: var(--fg); text-decoration: none; }
+		.sidebar a:hover { color: var(--link); }
+		.sidebar .chapter > a { font-weight: bold; }
+		main { flex: 1; min-width: 0; max-width: 800px; margin: 0 auto; padding: 20px 40px 80px; }
+		.cover { text-align: center; padding: 40px 0; }
+		.cover img { max-width: 60%; max-height: 360px; }
+		.cover .intro { text-align: left; color: var(--muted); }
+		.chapter-title { margin-top: 60px; }
+		.article { border-top: 1px solid var(--border); margin-top: 40px; padding-top: 20px; }
+		blockquote { margin-left: 0; padding-left: 1em; border-left: 4px solid var(--border); color: var(--muted); }
+		pre { overflow-x: auto; padding: 12px; background: var(--panel); }
+		table { border-collapse: collapse; }
+		td, th { border: 1px solid var(--border); padding: 4px 8px; }
+		hr { border: none; border-top: 1px solid var(--border); }
+		.theme-toggle { position: fixed; top: 12px; right: 12px; padding: 4px 10px; border: 1px solid var(--border); border-radius: 4px; background: var(--panel); color: var(--fg); cursor: pointer; }
+		@media (max-width: 768px) {
+			.layout { display: block; }
+			.sidebar { position: static; width: auto; height: auto; border-right: none; border-bottom: 1px solid var(--border); }
+			main { padding: 20px; }
+		}`
+
+// htmlBookScript 切换明暗主题，并记住选择
+const htmlBookScript = `
+(function () {
+	var root = document.documentElement;
+	try { var saved = localStorage.getItem("dedao-theme"); if (saved) root.setAttribute("data-theme", saved); } catch (e) {}
+	document.getElementById("theme-toggle").addEventListener("click", function () {
+		var theme = root.getAttribute("data-theme");
+		if (!theme) theme = matchMedia("(prefers-color-scheme: dark)").matches ? "dark" : "light";
+		theme = theme === "dark" ? "light" : "dark";
+		root.setAttribute("data-theme", theme);
+		try { localStorage.setItem("dedao-theme", theme); } catch (e) {}
+	});
+})();
+`
+
+// htmlPage 单文件 HTML 页面，toc 为空时不显示目录
+func htmlPage(title, toc, body string) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"zh-CN\">\n<head>\n\t<meta charset=\"UTF-8\">\n")
+	b.WriteString("\t<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
+	fmt.Fprintf(&b, "\t<title>%s</title>\n", html.EscapeString(title))
+	b.WriteString("\t<style>" + utils.ArticleCSS + htmlBookCSS + "\n\t</style>\n</head>\n<body>\n")
+	b.WriteString("<button id=\"theme-toggle\" class=\"theme-toggle\" type=\"button\">明 / 暗</button>\n")
+	b.WriteString("<div class=\"layout\">\n")
+	if toc != "" {
+		b.WriteString("<nav class=\"sidebar\">\n" + toc + "</nav>\n")
+	}
+	b.WriteString("<main>\n" + body + "</main>\n</div>\n")
+	b.WriteString("<script>" + htmlBookScript + "</script>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// htmlCover 页首的课程图片、名称、讲师和简介
+func htmlCover(logo, name, lecturer, intro string) string {
+	var b strings.Builder
+	b.WriteString("<header class=\"cover\" id=\"top\">\n")
+	if logo != "" {
+		fmt.Fprintf(&b, "<img src=\"%s\" alt=\"\">\n", html.EscapeString(logo))
+	}
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(name))
+	if lecturer != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(lecturer))
+	}
+	if intro = strings.TrimSpace(intro); intro != "" {
+		b.WriteString("<div class=\"intro\">\n")
+		for _, line := range strings.Split(intro, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(line))
+			}
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</header>\n")
+	return b.String()
+}
+
+// localizeImage 下载单张图片，返回改写后的地址
+func localizeImage(assets *Assets, title, link string) string {
+	if link == "" {
+		return ""
+	}
+	doc := &services.Document{Blocks: []services.Block{services.ImageBlock{URL: link}}}
+	assets.Localize(title, doc)
+	return doc.Blocks[0].(services.ImageBlock).URL
+}
+
+// DownloadHTMLBook 将课程生成一个不依赖网络的 HTML 文件，样式和图片内嵌，侧边栏为按章节组织的目录
+// 指定文章时只包含该文章
+func DownloadHTMLBook(d *CourseDownload, course *services.CourseInfo, path string) error {
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	info := course.ClassInfo
+	title := info.Name
+	articles := list.List
+	if d.AID > 0 {
+		articles = nil
+		for _, v := range list.List {
+			if v.ID == d.AID {
+				articles = append(articles, v)
+				title = info.Name + "-" + v.Title
+			}
+		}
+	}
+
+	name := utils.FileName(title, "html")
+	event := Event{
+		Kind:   KindCourse,
+		ID:     d.ID,
+		Enid:   info.Enid,
+		Title:  d.ClassName,
+		Author: d.lecturer,
+		Item:   title,
+		Format: CourseFormatName(d.DownloadType),
+		Path:   filepath.Join(path, name),
+		Done:   1,
+		Total:  1,
+	}
+	_, exist, err := utils.FileSize(event.Path)
+	if err != nil {
+		d.emitItem(event, err)
+		return err
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		event.Type = EventItemSkipped
+		d.emitItem(event, nil)
+		return nil
+	}
+
+	var toc, body strings.Builder
+	body.WriteString(htmlCover(localizeImage(d.assets, info.Name, info.Logo), info.Name, classLecturer(info), info.Intro))
+	toc.WriteString("<ol>\n")
+	for i, chapter := range GroupByChapter(course.ChapterList, articles) {
+		if chapter.Name != "" {
+			id := fmt.Sprintf("chapter-%d", i+1)
+			fmt.Fprintf(&toc, "<li class=\"chapter\"><a href=\"#%s\">%s</a>\n<ol>\n", id, html.EscapeString(chapter.Name))
+			fmt.Fprintf(&body, "<h1 class=\"chapter-title\" id=\"%s\">%s</h1>\n", id, html.EscapeString(chapter.Name))
+		}
+		for _, v := range chapter.Articles {
+			if err := d.canceled(); err != nil {
+				return err
+			}
+			doc, enId, err := d.articleDocument(v)
+			if err != nil {
+				d.emitItem(event, err)
+				return err
+			}
+			res, err := d.templates.HTML(d.articleData(v, enId, doc))
+			if err != nil {
+				d.emitItem(event, err)
+				return err
+			}
+			id := fmt.Sprintf("article-%d", v.ID)
+			fmt.Fprintf(&toc, "<li><a href=\"#%s\">%s</a></li>\n", id, html.EscapeString(v.Title))
+			fmt.Fprintf(&body, "<article class=\"article\" id=\"%s\">\n<h2>%s</h2>\n%s</article>\n",
+				id, html.EscapeString(v.Title), shiftHTMLHeadings(res, 2))
+		}
+		if chapter.Name != "" {
+			toc.WriteString("</ol>\n</li>\n")
+		}
+	}
+	toc.WriteString("</ol>\n")
+
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+	page := d.assets.EmbedHTML(htmlPage(title, toc.String(), body.String()))
+	err = os.WriteFile(event.Path, []byte(page), 0644)
+	if err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+	} else {
+		fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	}
+	d.emitItem(event, err)
+	return err
+}
+
+// DownloadHTMLAudioBook 将每天听本书文稿生成不依赖网络的 HTML 文件
+func DownloadHTMLAudioBook(tmpl *Templates, assets *Assets, aliasID, path string, book *services.CourseV2) (fileName string, err error) {
+	name := utils.FileName(book.Title, "html")
+	fileName = filepath.Join(path, name)
+	_, exist, err := utils.FileSize(fileName)
+	if err != nil {
+		return
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		return
+	}
+
+	doc, err := getArticleDetail(aliasID)
+	if err != nil {
+		return
+	}
+	reportUnknown(book.Title, doc)
+	assets.Localize(book.Title, doc)
+	res, err := tmpl.HTML(odobArticleData(book, doc))
+	if err != nil {
+		return
+	}
+	body := htmlCover(localizeImage(assets, book.Title, book.Icon), book.Title, book.Author, book.Intro) +
+		"<article class=\"article\">\n" + res + "</article>\n"
+
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+	if err = os.WriteFile(fileName, []byte(assets.EmbedHTML(htmlPage(book.Title, "", body))), 0644); err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return
+	}
+	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	return
+}
@@ -0,0 +1,366 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yann0917/dedao-dl/config"
+)
+
+// 任务状态
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobFinished = "finished"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// JobRequest 下载任务参数，与命令行参数一致
+type JobRequest struct {
+	Kind      string `json:"kind"`       // course, odob, ebook
+	ID        int    `json:"id"`         // 课程 ID、听书 ID 或电子书 ID
+	Enid      string `json:"enid"`       // 电子书 enid，ID 为 0 时使用
+	ArticleID int    `json:"article_id"` // 只下载课程中的某篇文章
+	Type      int    `json:"type"`       // 下载格式，同 -t 参数
+	Merge     bool   `json:"merge"`
+	Comment   bool   `json:"comment"`
+	Order     bool   `json:"order"`
+	// Comments 每篇文章的留言数量，all 或数字，同 --comments
+	Comments    string `json:"comments"`
+	CommentSort string `json:"comment_sort"` // 同 --comment-sort
+	// MergeChapter 合并 PDF 时每章生成一个文件，同 --merge-chapter
+	MergeChapter bool `json:"merge_chapter"`
+	// OfflineAssets 下载文章图片到 assets 目录，同 --offline-assets
+	OfflineAssets bool   `json:"offline_assets"`
+	ConvertImages string `json:"convert_images"` // 同 --convert-images
+	PdfEngine     string `json:"pdf_engine"`     // 同 --pdf-engine
+	PdfProfile    string `json:"pdf_profile"`    // 同 --pdf-profile
+	EmbedFonts    bool   `json:"embed_fonts"`    // 同 --embed-fonts
+	// Transcript 下载 mp3 时生成 LRC 和 SRT 字幕，同 --transcript
+	Transcript        bool `json:"transcript"`
+	TranscriptSilence bool `json:"transcript_silence"` // 同 --transcript-silence
+	Points            bool `json:"points"`             // 同 --points，只对课程有效
+}
+
+// Job 下载任务
+type Job struct {
+	ID         string     `json:"id"`
+	Request    JobRequest `json:"request"`
+	Title      string     `json:"title,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Finished   int        `json:"finished"`
+	Failed     int        `json:"failed"`
+	Skipped    int        `json:"skipped"`
+	Total      int        `json:"total"`
+	Path       string     `json:"path,omitempty"`  // 整门课程下载完成时为课程输出目录
+	Paths      []string   `json:"paths,omitempty"` // 生成或已存在的文件
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Downloader 根据任务参数生成下载器
+func (r JobRequest) Downloader() (DeDaoDownloader, error) {
+	if r.Type == 0 {
+		r.Type = 1
+	}
+	if CourseFormatName(r.Type) == "" || (r.Kind == KindEbook && EbookFormatName(r.Type) == "") {
+		return nil, fmt.Errorf("下载格式错误: %d", r.Type)
+	}
+	convert, err := ImageFormat(r.ConvertImages)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := ParsePdfEngine(r.PdfEngine)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := config.Instance.PDF.PrintProfile(r.PdfProfile); err != nil {
+		return nil, err
+	}
+	limit, err := ParseCommentLimit(r.Comments)
+	if err != nil {
+		return nil, err
+	}
+	sort, err := ParseCommentSort(r.CommentSort)
+	if err != nil {
+		return nil, err
+	}
+	switch r.Kind {
+	case KindCourse:
+		if r.ID <= 0 {
+			return nil, errors.New("课程ID错误")
+		}
+		return &CourseDownload{
+			DownloadType:      r.Type,
+			ID:                r.ID,
+			AID:               r.ArticleID,
+			IsMerge:           r.Merge,
+			MergeChapter:      r.MergeChapter,
+			IsComment:         r.Comment || limit != 0,
+			CommentLimit:      limit,
+			CommentSort:       sort,
+			IsOrder:           r.Order,
+			OfflineAssets:     r.OfflineAssets,
+			ConvertImages:     convert,
+			PdfEngine:         engine,
+			PdfProfile:        r.PdfProfile,
+			EmbedFonts:        r.EmbedFonts,
+			Transcript:        r.Transcript,
+			TranscriptSilence: r.TranscriptSilence,
+			Points:            r.Points,
+		}, nil
+	case KindOdob:
+		if r.ID <= 0 {
+			return nil, errors.New("听书ID错误")
+		}
+		return &OdobDownload{
+			DownloadType:      r.Type,
+			ID:                r.ID,
+			OfflineAssets:     r.OfflineAssets,
+			ConvertImages:     convert,
+			PdfEngine:         engine,
+			PdfProfile:        r.PdfProfile,
+			EmbedFonts:        r.EmbedFonts,
+			Transcript:        r.Transcript,
+			TranscriptSilence: r.TranscriptSilence,
+		}, nil
+	case KindEbook:
+		if r.ID > 0 {
+			return &EBookDownloadByID{DownloadType: r.Type, ID: r.ID, PdfProfile: r.PdfProfile, EmbedFonts: r.EmbedFonts}, nil
+		}
+		if r.Enid != "" {
+			return &EBookDownloadByEnID{DownloadType: r.Type, EnID: r.Enid, PdfProfile: r.PdfProfile, EmbedFonts: r.EmbedFonts}, nil
+		}
+		return nil, errors.New("电子书ID错误")
+	}
+	return nil, fmt.Errorf("不支持的下载类型: %s", r.Kind)
+}
+
+// JobQueue 下载任务队列
+// 为避免触发反爬限制，任务按提交顺序逐个执行
+type JobQueue struct {
+	mu         sync.Mutex
+	seq        int
+	jobs       map[string]*Job
+	queue      chan *Job
+	downloader func(JobRequest) (DeDaoDownloader, error)
+}
+
+// NewJobQueue 创建任务队列并启动执行协程
+func NewJobQueue() *JobQueue {
+	return newJobQueue(JobRequest.Downloader)
+}
+
+func newJobQueue(downloader func(JobRequest) (DeDaoDownloader, error)) *JobQueue {
+	q := &JobQueue{
+		jobs:       make(map[string]*Job),
+		queue:      make(chan *Job, 1024),
+		downloader: downloader,
+	}
+	Subscribe(q.onEvent)
+	go q.run()
+	return q
+}
+
+// Submit 提交下载任务
+func (q *JobQueue) Submit(req JobRequest) (Job, error) {
+	if _, err := q.downloader(req); err != nil {
+		return Job{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mu.Lock()
+	q.seq++
+	job := &Job{
+		ID:        strconv.Itoa(q.seq),
+		Request:   req,
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	q.jobs[job.ID] = job
+	snapshot := *job
+	q.mu.Unlock()
+
+	select {
+	case q.queue <- job:
+	default:
+		cancel()
+		q.finish(job, errors.New("任务队列已满"))
+		return q.snapshot(job), errors.New("任务队列已满")
+	}
+	return snapshot, nil
+}
+
+// Get 查询任务
+func (q *JobQueue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List 任务列表，按提交顺序排序
+func (q *JobQueue) List() []Job {
+	q.mu.Lock()
+	list := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		list = append(list, *job)
+	}
+	q.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Cancel 取消任务，正在执行的任务在当前文件完成后停止
+func (q *JobQueue) Cancel(id string) (Job, error) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	q.mu.Unlock()
+	if !ok {
+		return Job{}, errors.New("任务不存在")
+	}
+	job.cancel()
+	return q.snapshot(job), nil
+}
+
+func (q *JobQueue) run() {
+	for job := range q.queue {
+		if job.ctx.Err() != nil {
+			q.finish(job, job.ctx.Err())
+			continue
+		}
+		q.mu.Lock()
+		now := time.Now()
+		job.Status = JobRunning
+		job.StartedAt = &now
+		q.mu.Unlock()
+		Publish(q.jobEvent(job, EventJobStarted, nil))
+
+		d, err := q.downloader(job.Request)
+		if err == nil {
+			if t, ok := d.(interface{ task() *Task }); ok {
+				*t.task() = Task{Ctx: job.ctx, JobID: job.ID}
+			}
+			err = q.download(d)
+		}
+		q.finish(job, err)
+	}
+}
+
+// download 执行下载，避免单个任务 panic 导致服务退出
+func (q *JobQueue) download(d DeDaoDownloader) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("下载异常: %v", r)
+		}
+	}()
+	return Download(d)
+}
+
+func (q *JobQueue) finish(job *Job, err error) {
+	q.mu.Lock()
+	now := time.Now()
+	job.FinishedAt = &now
+	eventType := EventJobFinished
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = JobCanceled
+		eventType = EventJobCanceled
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		eventType = EventJobFailed
+	default:
+		job.Status = JobFinished
+	}
+	q.mu.Unlock()
+	job.cancel()
+	Publish(q.jobEvent(job, eventType, err))
+}
+
+func (q *JobQueue) snapshot(job *Job) Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return *job
+}
+
+func (q *JobQueue) jobEvent(job *Job, eventType string, err error) Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e := Event{
+		Type:  eventType,
+		JobID: job.ID,
+		Kind:  job.Request.Kind,
+		ID:    job.Request.ID,
+		Enid:  job.Request.Enid,
+		Title: job.Title,
+		Done:  job.Finished + job.Failed + job.Skipped,
+		Total: job.Total,
+	}
+	if eventType != EventJobStarted {
+		e.Path = job.Path
+		e.Paths = slices.Clone(job.Paths)
+	}
+	if job.Request.Kind == KindEbook {
+		e.Format = EbookFormatName(job.Request.Type)
+	} else {
+		e.Format = CourseFormatName(job.Request.Type)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// onEvent 根据文件事件更新任务进度
+func (q *JobQueue) onEvent(e Event) {
+	if e.JobID == "" {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[e.JobID]
+	if !ok {
+		return
+	}
+	switch e.Type {
+	case EventItemFinished:
+		job.Finished++
+	case EventItemFailed:
+		job.Failed++
+	case EventItemSkipped:
+		job.Skipped++
+	case EventCourseFinished:
+		job.Path = e.Path
+		return
+	default:
+		return
+	}
+	if e.Path != "" && e.Type != EventItemFailed {
+		job.Paths = append(job.Paths, e.Path)
+	}
+	if e.Title != "" {
+		job.Title = e.Title
+	}
+	if e.Total > job.Total {
+		job.Total = e.Total
+	}
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeDownload 按 run 执行的下载器
+type fakeDownload struct {
+	Task
+	run func(t *Task) error
+}
+
+func (f *fakeDownload) Download() error {
+	return f.run(&f.Task)
+}
+
+func TestJobQueue(t *testing.T) {
+	started := make(chan string, 4)
+	release := make(chan struct{})
+	runs := map[int]func(t *Task) error{
+		// 1: 完成一个文件后结束
+		1: func(t *Task) error {
+			started <- t.JobID
+			<-release
+			t.emitItem(Event{Item: "第一讲", Path: "output/课程/MD/第一讲.md"}, nil)
+			t.emitItem(Event{Item: "第二讲", Path: "output/课程/MD/第二讲.md"}, errors.New("超时"))
+			t.emit(Event{Type: EventCourseFinished, Path: "output/课程"})
+			return nil
+		},
+		// 2: 下载失败
+		2: func(t *Task) error { return errors.New("下载失败") },
+		// 3: 排队时被取消，不会执行
+		3: func(t *Task) error { panic("canceled job should not run") },
+		// 4: 执行中被取消
+		4: func(t *Task) error {
+			started <- t.JobID
+			<-t.Ctx.Done()
+			return t.canceled()
+		},
+	}
+	q := newJobQueue(func(r JobRequest) (DeDaoDownloader, error) {
+		run, ok := runs[r.ID]
+		if !ok {
+			return nil, errors.New("课程ID错误")
+		}
+		return &fakeDownload{run: run}, nil
+	})
+
+	done := make(chan Event, 8)
+	unsubscribe := Subscribe(func(e Event) {
+		switch e.Type {
+		case EventJobFinished, EventJobFailed, EventJobCanceled:
+			done <- e
+		}
+	})
+	defer unsubscribe()
+
+	if _, err := q.Submit(JobRequest{Kind: KindCourse, ID: 9}); err == nil {
+		t.Error("invalid request should be rejected")
+	}
+	var ids []string
+	for id := 1; id <= 4; id++ {
+		job, err := q.Submit(JobRequest{Kind: KindCourse, ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != JobQueued {
+			t.Errorf("job %s status = %s, want queued", job.ID, job.Status)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	wait := func(ch <-chan string) string {
+		t.Helper()
+		select {
+		case id := <-ch:
+			return id
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+		return ""
+	}
+	if id := wait(started); id != ids[0] {
+		t.Fatalf("started %s, want %s", id, ids[0])
+	}
+	if job, _ := q.Get(ids[0]); job.Status != JobRunning || job.StartedAt == nil {
+		t.Errorf("job 1 = %+v, want running", job)
+	}
+	if job, _ := q.Get(ids[2]); job.Status != JobQueued {
+		t.Errorf("job 3 status = %s, want queued", job.Status)
+	}
+	if _, err := q.Cancel(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Cancel("404"); err == nil {
+		t.Error("cancel unknown job should fail")
+	}
+	close(release)
+	if id := wait(started); id != ids[3] {
+		t.Fatalf("started %s, want %s", id, ids[3])
+	}
+	if _, err := q.Cancel(ids[3]); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(map[string]Event)
+	for len(events) < 4 {
+		select {
+		case e := <-done:
+			events[e.JobID] = e
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout, events = %+v", events)
+		}
+	}
+
+	want := []struct {
+		status, event string
+	}{
+		{JobFinished, EventJobFinished},
+		{JobFailed, EventJobFailed},
+		{JobCanceled, EventJobCanceled},
+		{JobCanceled, EventJobCanceled},
+	}
+	for i, id := range ids {
+		job, _ := q.Get(id)
+		if job.Status != want[i].status || job.FinishedAt == nil {
+			t.Errorf("job %s = %+v, want %s", id, job, want[i].status)
+		}
+		if events[id].Type != want[i].event {
+			t.Errorf("job %s event = %s, want %s", id, events[id].Type, want[i].event)
+		}
+	}
+	if job, _ := q.Get(ids[0]); job.Finished != 1 || job.Failed != 1 || events[ids[0]].Done != 2 {
+		t.Errorf("job 1 progress = %+v", job)
+	}
+	// 结束事件带有生成的文件，失败的文件不计入
+	if e := events[ids[0]]; e.Path != "output/课程" || len(e.Paths) != 1 || e.Paths[0] != "output/课程/MD/第一讲.md" {
+		t.Errorf("job 1 finished event paths = %q, %q", e.Path, e.Paths)
+	}
+	if job, _ := q.Get(ids[1]); job.Error != "下载失败" || events[ids[1]].Error != "下载失败" {
+		t.Errorf("job 2 error = %q", job.Error)
+	}
+	if job, _ := q.Get(ids[2]); job.StartedAt != nil {
+		t.Error("job canceled in queue should not start")
+	}
+	if list := q.List(); len(list) != 4 {
+		t.Errorf("list = %+v", list)
+	}
+}
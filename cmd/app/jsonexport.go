@@ -0,0 +1,690 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+const (
+	// JSONDirName 课程、听书 JSON 目录名
+	JSONDirName = "JSON"
+	// JSONSchemaVersion JSON 导出格式版本，删除字段或改变字段含义时加一，新增字段不变
+	JSONSchemaVersion = 1
+	// JSONCourseFileName 课程清单文件名
+	JSONCourseFileName = "course.json"
+	// JSONLinesFileName 合并导出时的文章文件名，每行一篇文章
+	JSONLinesFileName = "articles.jsonl"
+
+	JSONSchemaCourse  = "dedao-dl/course"
+	JSONSchemaArticle = "dedao-dl/article"
+	JSONSchemaEbook   = "dedao-dl/ebook"
+	// JSONSchemaComments dedao-dl comments 导出的留言
+	JSONSchemaComments = "dedao-dl/comments"
+)
+
+// JSONCourse 课程清单
+type JSONCourse struct {
+	Schema   string             `json:"schema"`
+	Version  int                `json:"version"`
+	Course   JSONCourseInfo     `json:"course"`
+	Chapters []JSONChapter      `json:"chapters"`
+	Articles []JSONArticleEntry `json:"articles"`
+}
+
+// JSONCourseInfo 课程信息
+type JSONCourseInfo struct {
+	ID            int    `json:"id"`
+	Enid          string `json:"enid"`
+	Name          string `json:"name"`
+	Intro         string `json:"intro"`
+	Highlight     string `json:"highlight,omitempty"`
+	Lecturer      string `json:"lecturer"`
+	LecturerTitle string `json:"lecturer_title,omitempty"`
+	LecturerIntro string `json:"lecturer_intro,omitempty"`
+	Cover         string `json:"cover,omitempty"`
+	URL           string `json:"url,omitempty"`
+	ArticleCount  int    `json:"article_count"`
+	Finished      bool   `json:"finished"`
+	PublishTime   int    `json:"publish_time"` // unix 时间戳，秒
+	UpdateTime    int    `json:"update_time"`
+}
+
+// JSONChapter 课程章节
+type JSONChapter struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Intro      string `json:"intro,omitempty"`
+	OrderNum   int    `json:"order_num"`
+	Finished   bool   `json:"finished"`
+	UpdateTime int    `json:"update_time"`
+}
+
+// JSONArticleEntry 课程清单中的文章
+type JSONArticleEntry struct {
+	ID        int    `json:"id"`
+	Enid      string `json:"enid"`
+	Title     string `json:"title"`
+	ChapterID int    `json:"chapter_id"`
+	OrderNum  int    `json:"order_num"`
+	File      string `json:"file,omitempty"` // 文章 JSON 文件名，合并导出时为空
+}
+
+// JSONArticle 课程文章或每天听本书文稿
+type JSONArticle struct {
+	Schema      string          `json:"schema"`
+	Version     int             `json:"version"`
+	Kind        string          `json:"kind"` // course 或 odob
+	ID          int             `json:"id"`
+	Enid        string          `json:"enid"`
+	Title       string          `json:"title"`
+	Author      string          `json:"author"`
+	Summary     string          `json:"summary,omitempty"`
+	URL         string          `json:"url,omitempty"`
+	Course      *JSONCourseRef  `json:"course,omitempty"`
+	Chapter     *JSONChapterRef `json:"chapter,omitempty"`
+	Book        *JSONBook       `json:"book,omitempty"`
+	OrderNum    int             `json:"order_num,omitempty"`
+	Audio       *JSONAudio      `json:"audio,omitempty"`
+	PublishTime int             `json:"publish_time"` // unix 时间戳，秒
+	UpdateTime  int             `json:"update_time"`
+	Blocks      []JSONBlock     `json:"blocks"`
+	Text        string          `json:"text"`
+	Comments    []JSONComment   `json:"comments,omitempty"`
+}
+
+// JSONCourseRef 文章所属课程
+type JSONCourseRef struct {
+	ID   int    `json:"id"`
+	Enid string `json:"enid"`
+	Name string `json:"name"`
+}
+
+// JSONChapterRef 文章所属章节
+type JSONChapterRef struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	OrderNum int    `json:"order_num"`
+}
+
+// JSONBook 每天听本书的书籍信息
+type JSONBook struct {
+	ClassID  int    `json:"class_id,omitempty"`
+	Cover    string `json:"cover,omitempty"`
+	Finished bool   `json:"finished"` // 是否已听完
+}
+
+// JSONAudio 文章音频
+type JSONAudio struct {
+	AliasID  string `json:"alias_id"`
+	Title    string `json:"title"`
+	Duration int    `json:"duration"` // 秒
+	Size     int    `json:"size"`     // 字节
+}
+
+// JSONBlock 内容块，type 与文章内容中的类型一致：header、paragraph、list、blockquote、image、elite、label-group、table、code
+// 未识别的类型保留原始类型名和文字
+type JSONBlock struct {
+	Type     string         `json:"type"`
+	Level    int            `json:"level,omitempty"`
+	Text     string         `json:"text,omitempty"`
+	Runs     []JSONRun      `json:"runs,omitempty"`
+	Align    string         `json:"align,omitempty"`
+	Ordered  bool           `json:"ordered,omitempty"`
+	Items    []JSONListItem `json:"items,omitempty"`
+	URL      string         `json:"url,omitempty"`
+	Legend   string         `json:"legend,omitempty"`
+	Link     string         `json:"link,omitempty"`
+	Width    int64          `json:"width,omitempty"`
+	Height   int64          `json:"height,omitempty"`
+	Labels   []string       `json:"labels,omitempty"`
+	Rows     [][]JSONCell   `json:"rows,omitempty"`
+	Language string         `json:"language,omitempty"`
+}
+
+// JSONRun 段落中的一段文字
+type JSONRun struct {
+	Type      string `json:"type,omitempty"` // 普通文字为空
+	Text      string `json:"text"`
+	Bold      bool   `json:"bold,omitempty"`
+	Highlight bool   `json:"highlight,omitempty"`
+	Link      string `json:"link,omitempty"`
+}
+
+// JSONListItem 列表项
+type JSONListItem struct {
+	Runs     []JSONRun  `json:"runs"`
+	Children *JSONBlock `json:"children,omitempty"`
+}
+
+// JSONCell 表格单元格
+type JSONCell struct {
+	Runs []JSONRun `json:"runs"`
+}
+
+// JSONComment 留言
+type JSONComment struct {
+	ID         string     `json:"id"`
+	Author     string     `json:"author"`
+	Content    string     `json:"content"`
+	LikeCount  int        `json:"like_count"`
+	CreateTime int        `json:"create_time"`
+	Reply      *JSONReply `json:"reply,omitempty"`
+}
+
+// JSONReply 留言的回复
+type JSONReply struct {
+	Author  string `json:"author"`
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Time    int    `json:"time"`
+}
+
+// JSONEbook 电子书，包含目录和每章的文字
+type JSONEbook struct {
+	Schema   string             `json:"schema"`
+	Version  int                `json:"version"`
+	Book     JSONEbookInfo      `json:"book"`
+	Toc      []JSONTocEntry     `json:"toc"`
+	Chapters []JSONEbookChapter `json:"chapters"`
+}
+
+// JSONEbookInfo 电子书信息
+type JSONEbookInfo struct {
+	ID          int    `json:"id"`
+	Enid        string `json:"enid"`
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	AuthorIntro string `json:"author_intro,omitempty"`
+	Intro       string `json:"intro,omitempty"`
+	Cover       string `json:"cover,omitempty"`
+	Press       string `json:"press,omitempty"`
+	PublishTime string `json:"publish_time,omitempty"` // 出版时间，接口返回的文字
+	Category    string `json:"category,omitempty"`
+}
+
+// JSONTocEntry 电子书目录项
+type JSONTocEntry struct {
+	Level     int    `json:"level"`
+	Text      string `json:"text"`
+	ChapterID string `json:"chapter_id"`
+	Href      string `json:"href"`
+}
+
+// JSONEbookChapter 电子书章节
+type JSONEbookChapter struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Text  string   `json:"text"`
+	Notes []string `json:"notes,omitempty"` // 注释
+}
+
+// DownloadJSONCourse 将课程导出为 JSON，课程目录下生成课程清单 course.json
+// 不合并时每篇文章一个 JSON 文件，合并时所有文章写入 articles.jsonl，每行一篇
+func DownloadJSONCourse(d *CourseDownload, course *services.CourseInfo, path string) error {
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	merge := d.IsMerge && d.AID == 0
+	manifest := jsonCourse(course, list.List, merge, d.IsOrder)
+	if err := writeMeta(filepath.Join(path, JSONCourseFileName), manifest); err != nil {
+		return err
+	}
+
+	chapters := make(map[int]*services.Chapter, len(course.ChapterList))
+	for i, chapter := range course.ChapterList {
+		chapters[chapter.ID] = &course.ChapterList[i]
+	}
+	if merge {
+		return d.jsonLines(course, list, chapters, path)
+	}
+	for i, v := range list.List {
+		if d.AID > 0 && v.ID != d.AID {
+			continue
+		}
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		event := d.articleEvent(v, i, len(list.List))
+		name := ArticleFileTitle(v, d.IsOrder) + ".json"
+		event.Path = filepath.Join(path, name)
+		_, exist, err := utils.FileSize(event.Path)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		if exist {
+			fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+			event.Type = EventItemSkipped
+			d.emitItem(event, nil)
+			continue
+		}
+
+		doc, enId, err := d.articleDocument(v)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+		err = writeJSONFile(event.Path, jsonArticle(d.articleData(v, enId, doc), chapters[v.ChapterID]))
+		d.emitItem(event, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonLines 所有文章写入一个 JSON Lines 文件
+func (d *CourseDownload) jsonLines(course *services.CourseInfo, list *services.ArticleList, chapters map[int]*services.Chapter, path string) error {
+	event := Event{
+		Kind:   KindCourse,
+		ID:     d.ID,
+		Enid:   course.ClassInfo.Enid,
+		Title:  d.ClassName,
+		Author: d.lecturer,
+		Item:   d.ClassName,
+		Format: CourseFormatName(d.DownloadType),
+		Path:   filepath.Join(path, JSONLinesFileName),
+		Done:   1,
+		Total:  1,
+	}
+	_, exist, err := utils.FileSize(event.Path)
+	if err != nil {
+		d.emitItem(event, err)
+		return err
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", JSONLinesFileName, "已存在")
+		event.Type = EventItemSkipped
+		d.emitItem(event, nil)
+		return nil
+	}
+
+	var b strings.Builder
+	for _, v := range list.List {
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		doc, enId, err := d.articleDocument(v)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		line, err := jsoniter.Marshal(jsonArticle(d.articleData(v, enId, doc), chapters[v.ChapterID]))
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", JSONLinesFileName)
+	err = printResult(utils.WriteFileWithTrunc(event.Path, b.String()))
+	d.emitItem(event, err)
+	return err
+}
+
+// DownloadJSONAudioBook 将每天听本书文稿导出为 JSON
+func DownloadJSONAudioBook(assets *Assets, aliasID, path string, book *services.CourseV2) (fileName string, err error) {
+	name := utils.FileName(book.Title, "json")
+	fileName = filepath.Join(path, name)
+	_, exist, err := utils.FileSize(fileName)
+	if err != nil {
+		return
+	}
+	if exist {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+		return
+	}
+
+	doc, err := getArticleDetail(aliasID)
+	if err != nil {
+		return
+	}
+	reportUnknown(book.Title, doc)
+	if assets != nil {
+		assets.Localize(book.Title, doc)
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+	err = writeJSONFile(fileName, jsonArticle(odobArticleData(book, doc), nil))
+	return
+}
+
+// DownloadJSONEbook 将电子书导出为 JSON，包含目录和每章的文字，内容取自生成 EPUB 的章节 HTML
+func DownloadJSONEbook(title string, detail *services.EbookDetail, svgContents []*utils.SvgContent, toc []utils.EbookToc) error {
+	path, err := utils.Mkdir(OutputDir, EbookDirName)
+	if err != nil {
+		return err
+	}
+	fileName, err := utils.FilePath(filepath.Join(path, utils.FileName(title, "")), "json", false)
+	if err != nil {
+		return err
+	}
+	chapters, _, err := utils.EbookChapters(svgContents, toc)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", fileName)
+	return writeJSONFile(fileName, jsonEbook(detail, chapters, toc))
+}
+
+// writeJSONFile 写入 JSON 文件并输出结果
+func writeJSONFile(fileName string, v interface{}) error {
+	return printResult(writeMeta(fileName, v))
+}
+
+func printResult(err error) error {
+	if err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return err
+	}
+	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	return nil
+}
+
+// jsonCourse 课程清单，merge 为 true 时文章写入 articles.jsonl，不记录文章文件名
+func jsonCourse(course *services.CourseInfo, articles []services.ArticleIntro, merge, order bool) JSONCourse {
+	manifest := JSONCourse{
+		Schema:   JSONSchemaCourse,
+		Version:  JSONSchemaVersion,
+		Course:   jsonCourseInfo(course.ClassInfo),
+		Chapters: []JSONChapter{},
+		Articles: []JSONArticleEntry{},
+	}
+	for _, v := range course.ChapterList {
+		manifest.Chapters = append(manifest.Chapters, JSONChapter{
+			ID:         v.ID,
+			Name:       v.Name,
+			Intro:      v.Intro,
+			OrderNum:   v.OrderNum,
+			Finished:   v.IsFinished == 1,
+			UpdateTime: v.UpdateTime,
+		})
+	}
+	for _, v := range articles {
+		entry := JSONArticleEntry{ID: v.ID, Enid: v.Enid, Title: v.Title, ChapterID: v.ChapterID, OrderNum: v.OrderNum}
+		if !merge {
+			entry.File = ArticleFileTitle(v, order) + ".json"
+		}
+		manifest.Articles = append(manifest.Articles, entry)
+	}
+	return manifest
+}
+
+func jsonCourseInfo(info services.ClassInfo) JSONCourseInfo {
+	return JSONCourseInfo{
+		ID:            info.ID,
+		Enid:          info.Enid,
+		Name:          info.Name,
+		Intro:         info.Intro,
+		Highlight:     info.Highlight,
+		Lecturer:      info.LecturerName,
+		LecturerTitle: info.LecturerTitle,
+		LecturerIntro: info.LecturerIntro,
+		Cover:         info.Logo,
+		URL:           info.ShareURL,
+		ArticleCount:  info.CurrentArticleCount,
+		Finished:      info.IsFinished == 1,
+		PublishTime:   info.PublishTime,
+		UpdateTime:    info.UpdateTime,
+	}
+}
+
+func jsonEbookInfo(detail *services.EbookDetail) JSONEbookInfo {
+	return JSONEbookInfo{
+		ID:          detail.ID,
+		Enid:        detail.Enid,
+		Title:       detail.Title,
+		Author:      detail.BookAuthor,
+		AuthorIntro: detail.AuthorInfo,
+		Intro:       detail.BookIntro,
+		Cover:       detail.Cover,
+		Press:       detail.Press.Name,
+		PublishTime: detail.PublishTime,
+		Category:    detail.ClassifyName,
+	}
+}
+
+func jsonEbook(detail *services.EbookDetail, chapters []utils.HtmlContent, toc []utils.EbookToc) JSONEbook {
+	book := JSONEbook{
+		Schema:   JSONSchemaEbook,
+		Version:  JSONSchemaVersion,
+		Book:     jsonEbookInfo(detail),
+		Toc:      []JSONTocEntry{},
+		Chapters: []JSONEbookChapter{},
+	}
+	for _, t := range toc {
+		chapterID, _, _ := strings.Cut(t.Href, "#")
+		book.Toc = append(book.Toc, JSONTocEntry{Level: t.Level, Text: t.Text, ChapterID: chapterID, Href: t.Href})
+	}
+	for _, chapter := range chapters {
+		doc := ebookDocument(chapter.Content)
+		c := JSONEbookChapter{ID: chapter.ChapterID, Text: documentText(doc)}
+		if len(chapter.Toc) > 0 {
+			c.Title = chapter.Toc[0].Text
+		}
+		for _, block := range doc.Blocks {
+			switch b := block.(type) {
+			case services.HeadingBlock:
+				if c.Title == "" {
+					c.Title = b.Text
+				}
+			case services.ParagraphBlock:
+				for _, run := range b.Runs {
+					if run.Type == runFootnote {
+						c.Notes = append(c.Notes, run.Text)
+					}
+				}
+			}
+		}
+		book.Chapters = append(book.Chapters, c)
+	}
+	return book
+}
+
+// jsonArticle 文章的 JSON 数据，chapter 为文章所属章节，没有章节时为 nil
+func jsonArticle(data ArticleData, chapter *services.Chapter) JSONArticle {
+	a := JSONArticle{
+		Schema:   JSONSchemaArticle,
+		Version:  JSONSchemaVersion,
+		Title:    data.Title,
+		Author:   data.Author,
+		Blocks:   jsonBlocks(data.Document.Blocks),
+		Text:     documentText(data.Document),
+		Comments: jsonComments(data.Comments),
+	}
+	switch {
+	case data.Article != nil:
+		v := data.Article
+		a.Kind = KindCourse
+		a.ID, a.Enid, a.OrderNum = v.ID, v.Enid, v.OrderNum
+		a.PublishTime, a.UpdateTime = v.PublishTime, v.UpdateTime
+		a.Summary, a.URL = v.Summary, articleSourceURL(*v)
+		if data.Class != nil {
+			a.Course = &JSONCourseRef{ID: data.Class.ID, Enid: data.Class.Enid, Name: data.Class.Name}
+		}
+		if chapter != nil {
+			a.Chapter = &JSONChapterRef{ID: chapter.ID, Name: chapter.Name, OrderNum: chapter.OrderNum}
+		}
+		if v.Audio != nil && v.Audio.AliasID != "" {
+			a.Audio = jsonAudio(*v.Audio)
+		}
+	case data.Book != nil:
+		v := data.Book
+		a.Kind = KindOdob
+		a.ID, a.Enid = v.ID, v.Enid
+		a.PublishTime = v.CreateTime
+		a.Summary, a.URL = v.Intro, v.DdURL
+		a.Book = &JSONBook{ClassID: v.ClassID, Cover: v.Icon, Finished: v.IsFinished == 1}
+		if v.AudioDetail.AliasID != "" {
+			a.Audio = jsonAudio(v.AudioDetail)
+		}
+	}
+	return a
+}
+
+func jsonAudio(audio services.Audio) *JSONAudio {
+	return &JSONAudio{AliasID: audio.AliasID, Title: audio.Title, Duration: audio.Duration, Size: audio.Size}
+}
+
+func jsonBlocks(blocks []services.Block) []JSONBlock {
+	list := []JSONBlock{}
+	for _, block := range blocks {
+		b := JSONBlock{Type: block.BlockType()}
+		switch v := block.(type) {
+		case services.AudioBlock:
+			// 音频信息在 audio 中
+			continue
+		case services.HeadingBlock:
+			b.Level, b.Text = v.Level, v.Text
+		case services.ParagraphBlock:
+			b.Runs, b.Align = jsonRuns(v.Runs), v.Justify
+		case services.ListBlock:
+			b = jsonList(v)
+		case services.QuoteBlock:
+			b.Text = v.Text
+		case services.ImageBlock:
+			b.URL, b.Legend, b.Link, b.Width, b.Height = v.URL, v.Legend, v.Jump, v.Width, v.Height
+		case services.EliteBlock:
+			b.Text = v.Text
+		case services.LabelGroupBlock:
+			b.Text, b.Labels = v.Text, v.Labels
+		case services.TableBlock:
+			for _, row := range v.Rows {
+				cells := make([]JSONCell, 0, len(row))
+				for _, cell := range row {
+					cells = append(cells, JSONCell{Runs: jsonRuns(cell.Runs)})
+				}
+				b.Rows = append(b.Rows, cells)
+			}
+		case services.CodeBlock:
+			b.Language, b.Text = v.Language, v.Text
+		case services.UnknownBlock:
+			b.Text = v.Text
+		}
+		list = append(list, b)
+	}
+	return list
+}
+
+func jsonList(list services.ListBlock) JSONBlock {
+	b := JSONBlock{Type: services.BlockList, Ordered: list.Ordered}
+	for _, item := range list.Items {
+		i := JSONListItem{Runs: jsonRuns(item.Runs)}
+		if item.Children != nil {
+			children := jsonList(*item.Children)
+			i.Children = &children
+		}
+		b.Items = append(b.Items, i)
+	}
+	return b
+}
+
+func jsonRuns(runs []services.Run) []JSONRun {
+	list := make([]JSONRun, 0, len(runs))
+	for _, run := range runs {
+		r := JSONRun{Text: run.Text, Bold: run.Bold, Highlight: run.Highlight, Link: run.Jump}
+		if run.Type != "text" {
+			r.Type = run.Type
+		}
+		list = append(list, r)
+	}
+	return list
+}
+
+func jsonComments(comments []services.ArticleComment) (list []JSONComment) {
+	for _, c := range comments {
+		comment := JSONComment{
+			ID:         c.NoteIdStr,
+			Author:     c.NotesOwner.Name,
+			Content:    c.Note,
+			LikeCount:  c.NotesCount.LikeCount,
+			CreateTime: c.CreateTime,
+		}
+		if c.CommentReply != "" {
+			comment.Reply = &JSONReply{
+				Author:  c.CommentReplyUser.Name,
+				Role:    c.CommentReplyUser.Role,
+				Content: c.CommentReply,
+				Time:    c.CommentReplyTime,
+			}
+		}
+		list = append(list, comment)
+	}
+	return
+}
+
+// documentText 文章的纯文字，内容块之间空一行，不包含图片和注释
+func documentText(doc *services.Document) string {
+	var paras []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			paras = append(paras, s)
+		}
+	}
+	for _, block := range doc.Blocks {
+		switch b := block.(type) {
+		case services.HeadingBlock:
+			add(b.Text)
+		case services.ParagraphBlock:
+			add(plainRuns(b.Runs))
+		case services.ListBlock:
+			add(listText(b, ""))
+		case services.QuoteBlock:
+			add(b.Text)
+		case services.ImageBlock:
+			add(b.Legend)
+		case services.EliteBlock:
+			add(b.Text)
+		case services.LabelGroupBlock:
+			add(b.Text)
+		case services.TableBlock:
+			var rows []string
+			for _, row := range b.Rows {
+				cells := make([]string, 0, len(row))
+				for _, cell := range row {
+					cells = append(cells, plainRuns(cell.Runs))
+				}
+				rows = append(rows, strings.Join(cells, "\t"))
+			}
+			add(strings.Join(rows, "\n"))
+		case services.CodeBlock:
+			add(b.Text)
+		case services.UnknownBlock:
+			add(b.Text)
+		}
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+func listText(list services.ListBlock, indent string) string {
+	var lines []string
+	for i, item := range list.Items {
+		marker := "- "
+		if list.Ordered {
+			marker = fmt.Sprintf("%d. ", i+1)
+		}
+		lines = append(lines, indent+marker+plainRuns(item.Runs))
+		if item.Children != nil {
+			lines = append(lines, listText(*item.Children, indent+"  "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plainRuns 段落中的文字，不包含注释
+func plainRuns(runs []services.Run) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.Type != runFootnote {
+			b.WriteString(run.Text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+func TestJSONArticle(t *testing.T) {
+	doc := &services.Document{Blocks: []services.Block{
+		services.AudioBlock{Title: "音频.mp3"},
+		services.HeadingBlock{Level: 2, Text: "标题"},
+		services.ParagraphBlock{Runs: []services.Run{{Type: "text", Text: "正文"}, {Type: "text", Text: "粗体", Bold: true, Jump: "https://example.com"}}},
+		services.ListBlock{Ordered: true, Items: []services.ListItem{
+			{Runs: []services.Run{{Text: "一"}}, Children: &services.ListBlock{Items: []services.ListItem{{Runs: []services.Run{{Text: "子项"}}}}}},
+			{Runs: []services.Run{{Text: "二"}}},
+		}},
+		services.ImageBlock{URL: "a.png", Legend: "图"},
+		services.TableBlock{Rows: [][]services.TableCell{{{Runs: []services.Run{{Text: "A"}}}, {Runs: []services.Run{{Text: "B"}}}}}},
+		services.UnknownBlock{Type: "video", Text: "视频"},
+	}}
+	intro := services.ArticleIntro{ArticleBase: services.ArticleBase{ID: 1, Enid: "e1", Title: "文章", Summary: "摘要", ChapterID: 9, PublishTime: 100}}
+	intro.Audio = &services.Audio{AliasID: "a1", Duration: 60, Size: 1024}
+	data := ArticleData{
+		Class:    &services.ClassInfo{ID: 2, Name: "课程"},
+		Article:  &intro,
+		Title:    intro.Title,
+		Author:   "讲师",
+		Document: doc,
+		Comments: []services.ArticleComment{{Note: "留言", CommentReply: "回复"}},
+	}
+	a := jsonArticle(data, &services.Chapter{ID: 9, Name: "第一章"})
+	if a.Kind != KindCourse || a.Course.Name != "课程" || a.Chapter.Name != "第一章" || a.Audio.Duration != 60 || a.PublishTime != 100 {
+		t.Fatalf("metadata = %+v", a)
+	}
+	if a.Summary != "摘要" || a.URL != "https://www.dedao.cn/course/article?id=e1" || a.Book != nil {
+		t.Errorf("summary = %q, url = %q, book = %+v", a.Summary, a.URL, a.Book)
+	}
+	if len(a.Blocks) != 6 || a.Blocks[0].Type != services.BlockHeading || a.Blocks[5].Type != "video" {
+		t.Fatalf("blocks = %+v", a.Blocks)
+	}
+	if r := a.Blocks[1].Runs[1]; r.Type != "" || !r.Bold || r.Link != "https://example.com" {
+		t.Errorf("run = %+v", r)
+	}
+	if a.Blocks[2].Items[0].Children == nil || a.Comments[0].Reply == nil {
+		t.Errorf("list = %+v, comments = %+v", a.Blocks[2], a.Comments)
+	}
+	want := "标题\n\n正文粗体\n\n1. 一\n  - 子项\n2. 二\n\n图\n\nA\tB\n\n视频"
+	if a.Text != want {
+		t.Errorf("text = %q, want %q", a.Text, want)
+	}
+
+	b, err := jsoniter.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if err := jsoniter.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["schema"] != JSONSchemaArticle || v["version"] != float64(JSONSchemaVersion) {
+		t.Errorf("schema = %v, version = %v", v["schema"], v["version"])
+	}
+	for _, key := range []string{"book", "article_intro"} {
+		if _, ok := v[key]; ok {
+			t.Errorf("%s should be omitted for course articles", key)
+		}
+	}
+
+	book := &services.CourseV2{ID: 3, Enid: "b1", Title: "书", Intro: "简介", Icon: "cover.jpg", IsFinished: 1, DdURL: "igetget://book", CreateTime: 200}
+	a = jsonArticle(odobArticleData(book, &services.Document{}), nil)
+	if a.Kind != KindOdob || a.Summary != "简介" || a.URL != "igetget://book" || a.PublishTime != 200 || a.Course != nil {
+		t.Errorf("odob = %+v", a)
+	}
+	if a.Book == nil || a.Book.Cover != "cover.jpg" || !a.Book.Finished {
+		t.Errorf("book = %+v", a.Book)
+	}
+}
+
+func TestJSONCourse(t *testing.T) {
+	course := &services.CourseInfo{
+		ClassInfo:   services.ClassInfo{ID: 2, Enid: "c1", Name: "课程", LecturerName: "讲师", Logo: "logo.png", CurrentArticleCount: 2, IsFinished: 1},
+		ChapterList: []services.Chapter{{ID: 9, Name: "第一章", OrderNum: 1}},
+	}
+	var articles []services.ArticleIntro
+	for i, title := range []string{"发刊词", "第一讲"} {
+		var a services.ArticleIntro
+		a.ID, a.Title, a.ChapterID, a.OrderNum = i+1, title, 9, i+1
+		articles = append(articles, a)
+	}
+
+	c := jsonCourse(course, articles, false, true)
+	if c.Schema != JSONSchemaCourse || c.Course.Name != "课程" || c.Course.Lecturer != "讲师" || c.Course.Cover != "logo.png" || !c.Course.Finished {
+		t.Errorf("course = %+v", c.Course)
+	}
+	if len(c.Chapters) != 1 || c.Chapters[0].Name != "第一章" || c.Chapters[0].OrderNum != 1 {
+		t.Errorf("chapters = %+v", c.Chapters)
+	}
+	if len(c.Articles) != 2 || c.Articles[1].File != "002.第一讲.json" || c.Articles[1].ChapterID != 9 {
+		t.Errorf("articles = %+v", c.Articles)
+	}
+	if c = jsonCourse(course, articles, true, true); c.Articles[0].File != "" {
+		t.Errorf("merged article file = %q", c.Articles[0].File)
+	}
+
+	b, err := jsoniter.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if err := jsoniter.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"class_info", "chapter_list"} {
+		if _, ok := v[key]; ok {
+			t.Errorf("%s should not be exported", key)
+		}
+	}
+}
+
+func TestJSONEbook(t *testing.T) {
+	chapters := []utils.HtmlContent{
+		{ChapterID: "c1", Content: `<h1>第一章</h1><p>正文<img class="epub-footnote" alt="注释"/></p>`},
+		{ChapterID: "c2", Content: `<p>无标题</p>`},
+	}
+	toc := []utils.EbookToc{{Href: "c1#a", Level: 0, Text: "第一章"}}
+	detail := &services.EbookDetail{ID: 1, Enid: "b1", Title: "书", BookAuthor: "作者", Press: services.Press{Name: "出版社"}}
+	book := jsonEbook(detail, chapters, toc)
+	if book.Book.Title != "书" || book.Book.Author != "作者" || book.Book.Press != "出版社" {
+		t.Errorf("book = %+v", book.Book)
+	}
+	if len(book.Toc) != 1 || book.Toc[0].ChapterID != "c1" {
+		t.Errorf("toc = %+v", book.Toc)
+	}
+	if c := book.Chapters[0]; c.Title != "第一章" || c.Text != "第一章\n\n正文" || len(c.Notes) != 1 {
+		t.Errorf("chapter = %+v", c)
+	}
+	if c := book.Chapters[1]; c.Title != "" || c.Text != "无标题" {
+		t.Errorf("chapter = %+v", c)
+	}
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+const (
+	// MetaFileName 课程元数据文件名，保存在课程目录下
+	MetaFileName = "meta.json"
+	// MetaVersion 元数据格式版本
+	MetaVersion = 1
+	// OdobDirName 每天听本书输出目录名
+	OdobDirName = "每天听本书"
+	// EbookDirName 电子书输出目录名
+	EbookDirName = "Ebook"
+)
+
+// CourseMeta 下载时保存的课程元数据
+type CourseMeta struct {
+	Version     int                     `json:"version"`
+	ClassInfo   services.ClassInfo      `json:"class_info"`
+	ChapterList []services.Chapter      `json:"chapter_list"`
+	Articles    []services.ArticleIntro `json:"articles"`
+	IsOrder     bool                    `json:"is_order"`
+	// IntroArticle 发刊词，旧版本的元数据中没有
+	IntroArticle *services.ArticleIntro `json:"intro_article,omitempty"`
+}
+
+// OdobMeta 下载时保存的每天听本书元数据
+type OdobMeta struct {
+	Version int               `json:"version"`
+	Course  services.CourseV2 `json:"course"`
+	Audios  []OdobAudioMeta   `json:"audios"`
+}
+
+// OdobAudioMeta 每天听本书单个音频的元数据
+type OdobAudioMeta struct {
+	FileTitle string         `json:"file_title"` // 音频文件名（不含扩展名）
+	Audio     services.Audio `json:"audio"`
+}
+
+// EbookMeta 下载时保存的电子书元数据
+type EbookMeta struct {
+	Version   int                  `json:"version"`
+	FileTitle string               `json:"file_title"` // 电子书文件名（不含扩展名）
+	Detail    services.EbookDetail `json:"detail"`
+}
+
+// CourseDir 课程输出目录
+func CourseDir(className string) string {
+	return filepath.Join(OutputDir, utils.FileName(className, ""))
+}
+
+// ArticleFileTitle 文章对应的文件名（不含扩展名）
+func ArticleFileTitle(article services.ArticleIntro, isOrder bool) string {
+	name := utils.FileName(article.Title, "")
+	if isOrder {
+		name = fmt.Sprintf("%03d.%s", article.OrderNum, name)
+	}
+	return name
+}
+
+// SaveCourseMeta 保存课程元数据到课程目录
+func SaveCourseMeta(course *services.CourseInfo, articles *services.ArticleList, isOrder bool) error {
+	path, err := utils.Mkdir(CourseDir(course.ClassInfo.Name))
+	if err != nil {
+		return err
+	}
+	return writeMeta(filepath.Join(path, MetaFileName), newCourseMeta(course, articles, isOrder))
+}
+
+func newCourseMeta(course *services.CourseInfo, articles *services.ArticleList, isOrder bool) *CourseMeta {
+	meta := &CourseMeta{
+		Version:     MetaVersion,
+		ClassInfo:   course.ClassInfo,
+		ChapterList: course.ChapterList,
+		IsOrder:     isOrder,
+	}
+	if articles != nil {
+		meta.Articles = articles.List
+	}
+	if course.ArticleIntro.ID > 0 || course.ArticleIntro.Title != "" {
+		intro := course.ArticleIntro
+		meta.IntroArticle = &intro
+	}
+	return meta
+}
+
+// LoadCourseMeta 读取课程目录下的元数据
+func LoadCourseMeta(dir string) (meta *CourseMeta, err error) {
+	err = readMeta(filepath.Join(dir, MetaFileName), &meta)
+	return
+}
+
+// SaveOdobMeta 保存每天听本书元数据
+func SaveOdobMeta(course *services.CourseV2, audios []OdobAudioMeta) error {
+	path, err := utils.Mkdir(OutputDir, OdobDirName, "meta")
+	if err != nil {
+		return err
+	}
+	meta := OdobMeta{
+		Version: MetaVersion,
+		Course:  *course,
+		Audios:  audios,
+	}
+	return writeMeta(filepath.Join(path, utils.FileName(course.Title, "json")), meta)
+}
+
+// LoadOdobMetas 读取所有每天听本书元数据
+func LoadOdobMetas() (metas []*OdobMeta, err error) {
+	files, err := filepath.Glob(filepath.Join(OutputDir, OdobDirName, "meta", "*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		var meta *OdobMeta
+		if err1 := readMeta(file, &meta); err1 != nil {
+			fmt.Printf("读取元数据 %s 失败: %v\n", file, err1)
+			continue
+		}
+		metas = append(metas, meta)
+	}
+	return
+}
+
+// SaveEbookMeta 保存电子书元数据，与电子书文件同名
+func SaveEbookMeta(detail *services.EbookDetail, fileTitle string) error {
+	path, err := utils.Mkdir(OutputDir, EbookDirName)
+	if err != nil {
+		return err
+	}
+	meta := EbookMeta{
+		Version:   MetaVersion,
+		FileTitle: fileTitle,
+		Detail:    *detail,
+	}
+	return writeMeta(filepath.Join(path, utils.FileName(fileTitle, "json")), meta)
+}
+
+// LoadEbookMetas 读取所有电子书元数据
+func LoadEbookMetas() (metas []*EbookMeta, err error) {
+	files, err := filepath.Glob(filepath.Join(OutputDir, EbookDirName, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		var meta *EbookMeta
+		if err1 := readMeta(file, &meta); err1 != nil || meta == nil || meta.FileTitle == "" {
+			continue
+		}
+		metas = append(metas, meta)
+	}
+	return
+}
+
+// CourseDirs 列出输出目录下所有保存了元数据的课程目录
+func CourseDirs() (dirs []string, err error) {
+	entries, err := os.ReadDir(OutputDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == OdobDirName || entry.Name() == EbookDirName {
+			continue
+		}
+		dir := filepath.Join(OutputDir, entry.Name())
+		if utils.CheckFileExist(filepath.Join(dir, MetaFileName)) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return
+}
+
+// SortedArticles 按 OrderNum 排序的文章列表
+func (m *CourseMeta) SortedArticles() []services.ArticleIntro {
+	list := make([]services.ArticleIntro, len(m.Articles))
+	copy(list, m.Articles)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].OrderNum < list[j].OrderNum
+	})
+	return list
+}
+
+// Cover 课程封面
+func (m *CourseMeta) Cover() string {
+	for _, img := range []string{m.ClassInfo.SquareImg, m.ClassInfo.Logo, m.ClassInfo.IndexImg} {
+		if img != "" {
+			return img
+		}
+	}
+	return ""
+}
+
+// Chapter 文章所在章节
+func (m *CourseMeta) Chapter(article services.ArticleIntro) *services.Chapter {
+	for i, chapter := range m.ChapterList {
+		if (article.ChapterID > 0 && chapter.ID == article.ChapterID) ||
+			(article.ChapterIDStr != "" && chapter.IDStr == article.ChapterIDStr) {
+			return &m.ChapterList[i]
+		}
+	}
+	return nil
+}
+
+func writeMeta(fileName string, v interface{}) error {
+	data, err := jsoniter.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileWithTrunc(fileName, string(data))
+}
+
+func readMeta(fileName string, v interface{}) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return utils.UnmarshalReader(f, v)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yann0917/dedao-dl/metrics"
+)
+
+// ServeMetrics 在 addr 上提供 Prometheus 指标 /metrics，并统计下载事件
+func ServeMetrics(addr string) {
+	Subscribe(observeEvent)
+	go func() {
+		if err := metrics.Serve(addr); err != nil {
+			fmt.Printf("监控指标服务启动失败: %v\n", err)
+		}
+	}()
+}
+
+func observeEvent(e Event) {
+	var result string
+	switch e.Type {
+	case EventItemFinished:
+		result = "finished"
+	case EventItemFailed:
+		result = "failed"
+	case EventItemSkipped:
+		result = "skipped"
+	default:
+		return
+	}
+	metrics.Items.WithLabelValues(e.Kind, e.Format, result).Inc()
+}
+
+// observeAPI 统计 API 请求，route 为匹配的路由，如 GET /api/v1/jobs/{id}
+func observeAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "other"
+		}
+		metrics.APIRequests.WithLabelValues(route, strconv.Itoa(rec.status)).Inc()
+		if !strings.HasSuffix(route, "/events") {
+			metrics.APIRequestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+		}
+	})
+}
+
+// statusRecorder 记录响应状态码，保留 Flush 以支持 Server-Sent Events
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/yann0917/dedao-dl/metrics"
+)
+
+func TestObserveAPI(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := observeAPI(mux)
+
+	route := metrics.APIRequests.WithLabelValues("GET /api/v1/jobs/{id}", "404")
+	other := metrics.APIRequests.WithLabelValues("other", "404")
+	before, beforeOther := testutil.ToFloat64(route), testutil.ToFloat64(other)
+	for _, path := range []string{"/api/v1/jobs/1", "/api/v1/jobs/2", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// 按路由模板统计，不同的任务 ID 使用同一个标签
+	if got := testutil.ToFloat64(route) - before; got != 2 {
+		t.Errorf("route requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(other) - beforeOther; got != 1 {
+		t.Errorf("other requests = %v, want 1", got)
+	}
+}
+
+func TestObserveEvent(t *testing.T) {
+	counters := map[string]float64{}
+	for _, result := range []string{"finished", "failed", "skipped"} {
+		counters[result] = testutil.ToFloat64(metrics.Items.WithLabelValues(KindCourse, "md", result))
+	}
+	for _, typ := range []string{EventItemFinished, EventItemFinished, EventItemFailed, EventItemSkipped, EventJobFinished} {
+		observeEvent(Event{Type: typ, Kind: KindCourse, Format: "md"})
+	}
+	for result, want := range map[string]float64{"finished": 2, "failed": 1, "skipped": 1} {
+		if got := testutil.ToFloat64(metrics.Items.WithLabelValues(KindCourse, "md", result)) - counters[result]; got != want {
+			t.Errorf("items %s = %v, want %v", result, got, want)
+		}
+	}
+}
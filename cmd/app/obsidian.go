@@ -0,0 +1,367 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// ObsidianDirName Obsidian 笔记目录名
+const ObsidianDirName = "Obsidian"
+
+// obsidianNameReplacer wikilink 中不能使用的字符
+var obsidianNameReplacer = strings.NewReplacer("[", "【", "]", "】", "#", "＃", "^", "＾", "|", "｜")
+
+// frontMatter YAML front matter，按添加顺序输出，空值不输出
+type frontMatter struct {
+	b strings.Builder
+}
+
+func (f *frontMatter) str(key, value string) {
+	if value != "" {
+		fmt.Fprintf(&f.b, "%s: %s\n", key, strconv.Quote(value))
+	}
+}
+
+func (f *frontMatter) date(key string, ts int) {
+	if ts > 0 {
+		fmt.Fprintf(&f.b, "%s: %s\n", key, time.Unix(int64(ts), 0).Format(time.DateOnly))
+	}
+}
+
+func (f *frontMatter) bool(key string, value bool) {
+	fmt.Fprintf(&f.b, "%s: %t\n", key, value)
+}
+
+func (f *frontMatter) list(key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	f.b.WriteString(key + ":\n")
+	for _, v := range values {
+		fmt.Fprintf(&f.b, "  - %s\n", strconv.Quote(v))
+	}
+}
+
+func (f *frontMatter) String() string {
+	return "---\n" + f.b.String() + "---\n\n"
+}
+
+// obsidianNote 笔记文件名，不含扩展名，同时用于 wikilink
+func obsidianNote(title string, order int) string {
+	name := utils.FileName(obsidianNameReplacer.Replace(title), "")
+	if order > 0 {
+		name = fmt.Sprintf("%03d %s", order, name)
+	}
+	return name
+}
+
+// wikilink 链接到笔记，显示标题
+func wikilink(note, title string) string {
+	title = obsidianNameReplacer.Replace(title)
+	if title == "" || title == note {
+		return "[[" + note + "]]"
+	}
+	return "[[" + note + "|" + title + "]]"
+}
+
+// obsidianTags 内容中的标签，Obsidian 标签不能包含空格
+func obsidianTags(doc *services.Document) (tags []string) {
+	seen := make(map[string]bool)
+	for _, block := range doc.Blocks {
+		group, ok := block.(services.LabelGroupBlock)
+		if !ok {
+			continue
+		}
+		for _, label := range group.Labels {
+			tag := strings.Join(strings.Fields(strings.TrimPrefix(label, "#")), "-")
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return
+}
+
+// audioDuration 文章音频时长，优先使用文章信息，其次使用内容中的音频
+func audioDuration(article services.ArticleIntro, doc *services.Document) string {
+	if article.Audio != nil && article.Audio.Duration > 0 {
+		return formatSeconds(article.Audio.Duration)
+	}
+	for _, block := range doc.Blocks {
+		if audio, ok := block.(services.AudioBlock); ok && audio.Duration != "" {
+			if seconds, err := strconv.Atoi(audio.Duration); err == nil {
+				return formatSeconds(seconds)
+			}
+			return audio.Duration
+		}
+	}
+	return ""
+}
+
+// formatSeconds 将秒数格式化为 mm:ss 或 h:mm:ss
+func formatSeconds(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// articleSourceURL 文章网页地址
+func articleSourceURL(article services.ArticleIntro) string {
+	if article.ShareURL != "" {
+		return article.ShareURL
+	}
+	if article.Enid != "" {
+		return "https://www.dedao.cn/course/article?id=" + article.Enid
+	}
+	return ""
+}
+
+// obsidianCourse 课程笔记，每篇文章一个笔记，课程笔记按章节链接所有文章
+type obsidianCourse struct {
+	course   *services.CourseInfo
+	groups   []CourseChapter
+	articles []services.ArticleIntro // 按章节顺序排列，与课程笔记一致
+	position map[int]int             // 文章 ID -> articles 下标
+	notes    map[int]string          // 文章 ID -> 笔记名
+	chapters map[int]string          // 章节 ID -> 章节名
+	order    bool
+}
+
+func newObsidianCourse(course *services.CourseInfo, articles []services.ArticleIntro, order bool) *obsidianCourse {
+	c := &obsidianCourse{
+		course:   course,
+		groups:   GroupByChapter(course.ChapterList, articles),
+		position: make(map[int]int, len(articles)),
+		notes:    make(map[int]string, len(articles)),
+		chapters: make(map[int]string, len(course.ChapterList)),
+		order:    order,
+	}
+	for _, chapter := range course.ChapterList {
+		c.chapters[chapter.ID] = chapter.Name
+	}
+	for _, article := range articles {
+		order := 0
+		if c.order {
+			order = article.OrderNum
+		}
+		c.notes[article.ID] = obsidianNote(article.Title, order)
+	}
+	for _, group := range c.groups {
+		for _, article := range group.Articles {
+			c.position[article.ID] = len(c.articles)
+			c.articles = append(c.articles, article)
+		}
+	}
+	return c
+}
+
+// mocNote 课程笔记名
+func (c *obsidianCourse) mocNote() string {
+	return obsidianNote(c.course.ClassInfo.Name, 0)
+}
+
+// moc 课程目录笔记，按章节顺序链接文章
+func (c *obsidianCourse) moc(opts config.MarkdownOptions) string {
+	info := c.course.ClassInfo
+	var fm frontMatter
+	fm.str("title", info.Name)
+	fm.str("lecturer", info.LecturerName)
+	fm.str("enid", info.Enid)
+	fm.list("tags", []string{"得到课程"})
+
+	r := mdRenderer{opts: opts}
+	r.para(r.heading(1, escapeInline(info.Name)))
+	r.para(textToMarkdown(info.Intro))
+
+	for _, chapter := range c.groups {
+		if chapter.Name != "" {
+			r.para(r.heading(2, escapeInline(chapter.Name)))
+		}
+		var lines []string
+		for _, article := range chapter.Articles {
+			lines = append(lines, "- "+wikilink(c.notes[article.ID], article.Title))
+		}
+		r.para(strings.Join(lines, "\n"))
+	}
+	return MarkdownNewline(fm.String()+r.b.String(), opts)
+}
+
+// note 文章笔记，上一讲和下一讲按课程笔记中的章节顺序
+func (c *obsidianCourse) note(article services.ArticleIntro, doc *services.Document, comments []services.ArticleComment, opts config.MarkdownOptions) string {
+	info := c.course.ClassInfo
+	var fm frontMatter
+	fm.str("title", article.Title)
+	fm.str("course", info.Name)
+	fm.str("chapter", c.chapters[article.ChapterID])
+	fm.str("lecturer", info.LecturerName)
+	fm.date("published", article.PublishTime)
+	fm.date("updated", article.UpdateTime)
+	fm.str("enid", article.Enid)
+	fm.str("duration", audioDuration(article, doc))
+	fm.list("tags", obsidianTags(doc))
+	fm.bool("read", article.IsRead)
+	fm.str("source", articleSourceURL(article))
+
+	r := mdRenderer{opts: opts}
+	for _, block := range doc.Blocks {
+		r.block(block)
+	}
+	if len(comments) > 0 {
+		r.comments(comments)
+	}
+
+	nav := []string{"课程：" + wikilink(c.mocNote(), info.Name)}
+	index, ok := c.position[article.ID]
+	if ok && index > 0 {
+		prev := c.articles[index-1]
+		nav = append(nav, "上一讲："+wikilink(c.notes[prev.ID], prev.Title))
+	}
+	if ok && index < len(c.articles)-1 {
+		next := c.articles[index+1]
+		nav = append(nav, "下一讲："+wikilink(c.notes[next.ID], next.Title))
+	}
+	r.b.WriteString("---\n\n")
+	r.para(strings.Join(nav, " · "))
+	return MarkdownNewline(fm.String()+r.b.String(), opts)
+}
+
+// DownloadObsidianCourse 将课程生成 Obsidian 笔记
+func DownloadObsidianCourse(d *CourseDownload, course *services.CourseInfo, path string) error {
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	opts := config.Instance.Markdown
+	vault := newObsidianCourse(course, list.List, d.IsOrder)
+
+	mocFile := filepath.Join(path, utils.FileName(vault.mocNote(), "md"))
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】\n", mocFile)
+	// 课程笔记每次重新生成，包含新更新的文章
+	if err := os.WriteFile(mocFile, []byte(vault.moc(opts)), 0644); err != nil {
+		return err
+	}
+
+	for i, v := range list.List {
+		if d.AID > 0 && v.ID != d.AID {
+			continue
+		}
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		event := d.articleEvent(v, i, len(list.List))
+		name := utils.FileName(vault.notes[v.ID], "md")
+		fileName := filepath.Join(path, name)
+		event.Path = fileName
+		_, exist, err := utils.FileSize(fileName)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		if exist {
+			fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+			event.Type = EventItemSkipped
+			d.emitItem(event, nil)
+			continue
+		}
+
+		detail, enId, err := ArticleDetail(d.ID, v.ID)
+		if err != nil {
+			fmt.Println(err.Error())
+			d.emitItem(event, err)
+			return err
+		}
+		doc, err := services.ParseDocument(detail.Content)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		reportUnknown(v.Title, doc)
+		d.withPoints(v, doc)
+		if d.assets != nil {
+			d.assets.Localize(v.Title, doc)
+		}
+		var comments []services.ArticleComment
+		if d.IsComment {
+			comments = fetchComments(enId, d.CommentSort, d.CommentLimit)
+		}
+
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+		err = os.WriteFile(fileName, []byte(vault.note(v, doc, comments, opts)), 0644)
+		if err != nil {
+			fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		} else {
+			fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+		}
+		d.emitItem(event, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// odobLiteratureNote 每天听本书的文献笔记
+func odobLiteratureNote(book *services.CourseV2, doc *services.Document, opts config.MarkdownOptions) string {
+	var fm frontMatter
+	fm.str("title", book.Title)
+	fm.str("author", book.Author)
+	fm.str("enid", book.Enid)
+	if book.Duration > 0 {
+		fm.str("duration", formatSeconds(book.Duration))
+	}
+	fm.date("created", book.CreateTime)
+	fm.list("tags", append([]string{"每天听本书", "literature"}, obsidianTags(doc)...))
+	fm.bool("read", book.IsFinished == 1)
+	fm.str("source", book.DdURL)
+
+	r := mdRenderer{opts: opts}
+	if book.Intro != "" {
+		r.para("> " + escapeInline(strings.Join(strings.Fields(book.Intro), " ")))
+	}
+	for _, block := range doc.Blocks {
+		r.block(block)
+	}
+	return MarkdownNewline(fm.String()+r.b.String(), opts)
+}
+
+// DownloadObsidianAudioBook 将每天听本书文稿生成 Obsidian 文献笔记
+func DownloadObsidianAudioBook(assets *Assets, aliasID, path string, book *services.CourseV2) (fileName string, err error) {
+	name := utils.FileName(obsidianNote(book.Title, 0), "md")
+	fileName = filepath.Join(path, name)
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+	_, exist, err := utils.FileSize(fileName)
+	if err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return
+	}
+	if exist {
+		fmt.Printf("\033[33;1m%s\033[0m\n", "已存在")
+		return
+	}
+
+	doc, err := getArticleDetail(aliasID)
+	if err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return
+	}
+	reportUnknown(book.Title, doc)
+	if assets != nil {
+		assets.Localize(book.Title, doc)
+	}
+	if err = os.WriteFile(fileName, []byte(odobLiteratureNote(book, doc, config.Instance.Markdown)), 0644); err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return
+	}
+	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	return
+}
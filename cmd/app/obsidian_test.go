@@ -0,0 +1,107 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestObsidianNote(t *testing.T) {
+	if got := obsidianNote("a[b]#c^d|e", 0); got != "a【b】＃c＾d｜e" {
+		t.Errorf("obsidianNote = %q", got)
+	}
+	if got := obsidianNote("发刊词", 1); got != "001 发刊词" {
+		t.Errorf("obsidianNote with order = %q", got)
+	}
+	tests := []struct{ note, title, want string }{
+		{"发刊词", "发刊词", "[[发刊词]]"},
+		{"001 发刊词", "", "[[001 发刊词]]"},
+		{"001 发刊词", "发刊词", "[[001 发刊词|发刊词]]"},
+		{"001 a｜b", "a|b", "[[001 a｜b|a｜b]]"},
+	}
+	for _, tt := range tests {
+		if got := wikilink(tt.note, tt.title); got != tt.want {
+			t.Errorf("wikilink(%q, %q) = %q, want %q", tt.note, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestObsidianCourse(t *testing.T) {
+	course := &services.CourseInfo{
+		ClassInfo:   services.ClassInfo{Name: "经济学课", LecturerName: "讲师", Enid: "c1"},
+		ChapterList: []services.Chapter{{ID: 9, Name: "第一章"}},
+	}
+	var articles []services.ArticleIntro
+	for i, title := range []string{"发刊词", "第一讲", "第二讲"} {
+		var a services.ArticleIntro
+		a.ID, a.Title, a.ChapterID, a.OrderNum = i+1, title, 9, i+1
+		articles = append(articles, a)
+	}
+	articles[1].Enid = "e2"
+	articles[1].PublishTime = 1577880000
+	c := newObsidianCourse(course, articles, true)
+	doc := &services.Document{Blocks: []services.Block{
+		services.ParagraphBlock{Runs: []services.Run{{Text: "正文"}}},
+		services.LabelGroupBlock{Labels: []string{"#经济 学", "经济-学", "入门"}},
+	}}
+	opts := config.MarkdownOptions{}
+
+	note := c.note(articles[1], doc, nil, opts)
+	if !strings.HasPrefix(note, "---\ntitle: \"第一讲\"\ncourse: \"经济学课\"\nchapter: \"第一章\"\nlecturer: \"讲师\"\npublished: ") {
+		t.Errorf("front matter = %q", note)
+	}
+	for _, want := range []string{
+		"enid: \"e2\"\n",
+		"tags:\n  - \"经济-学\"\n  - \"入门\"\n",
+		"read: false\n",
+		"source: \"https://www.dedao.cn/course/article?id=e2\"\n---\n\n",
+		"课程：[[经济学课]] · 上一讲：[[001 发刊词|发刊词]] · 下一讲：[[003 第二讲|第二讲]]",
+	} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note missing %q:\n%s", want, note)
+		}
+	}
+	if first := c.note(articles[0], doc, nil, opts); strings.Contains(first, "上一讲") || !strings.Contains(first, "下一讲：[[002 第一讲|第一讲]]") {
+		t.Errorf("first note nav = %q", first)
+	}
+	if last := c.note(articles[2], doc, nil, opts); strings.Contains(last, "下一讲") || !strings.Contains(last, "上一讲：[[002 第一讲|第一讲]]") {
+		t.Errorf("last note nav = %q", last)
+	}
+
+	moc := c.moc(opts)
+	if !strings.Contains(moc, "## 第一章\n\n- [[001 发刊词|发刊词]]\n- [[002 第一讲|第一讲]]\n- [[003 第二讲|第二讲]]") {
+		t.Errorf("moc = %q", moc)
+	}
+}
+
+func TestObsidianCourseChapterOrder(t *testing.T) {
+	course := &services.CourseInfo{
+		ClassInfo:   services.ClassInfo{Name: "经济学课"},
+		ChapterList: []services.Chapter{{ID: 1, Name: "第一章"}, {ID: 2, Name: "第二章"}},
+	}
+	// 接口返回的文章顺序与章节顺序不一致
+	var articles []services.ArticleIntro
+	for i, v := range []struct {
+		title   string
+		chapter int
+	}{{"第二章第一讲", 2}, {"第一章第一讲", 1}, {"第一章第二讲", 1}} {
+		var a services.ArticleIntro
+		a.ID, a.Title, a.ChapterID = i+1, v.title, v.chapter
+		articles = append(articles, a)
+	}
+	c := newObsidianCourse(course, articles, false)
+	doc := &services.Document{}
+	opts := config.MarkdownOptions{}
+
+	if note := c.note(articles[2], doc, nil, opts); !strings.Contains(note, "上一讲：[[第一章第一讲]] · 下一讲：[[第二章第一讲]]") {
+		t.Errorf("note nav = %q", note)
+	}
+	if note := c.note(articles[0], doc, nil, opts); strings.Contains(note, "下一讲") || !strings.Contains(note, "上一讲：[[第一章第二讲]]") {
+		t.Errorf("last note nav = %q", note)
+	}
+	if note := c.note(articles[1], doc, nil, opts); strings.Contains(note, "上一讲") {
+		t.Errorf("first note nav = %q", note)
+	}
+}
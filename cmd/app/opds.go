@@ -0,0 +1,367 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// ebookFormats 电子书支持下载的格式，按优先级排序
+var ebookFormats = []struct {
+	Ext, MimeType, Name string
+}{
+	{"epub", "application/epub+zip", "EPUB"},
+	{"pdf", "application/pdf", "PDF"},
+	{"html", "text/html", "HTML"},
+}
+
+// OpdsServer 本地 OPDS 书库服务，为已下载的电子书生成 OPDS 1.2 目录
+type OpdsServer struct {
+	Listen   string
+	BaseURL  string // 目录中链接的前缀，为空时使用请求的 Host
+	Username string // basic auth 用户名，为空时不校验
+	Password string
+}
+
+// opdsBook 已下载的电子书
+type opdsBook struct {
+	Meta    *EbookMeta
+	Files   []string // 存在的文件格式
+	Updated time.Time
+}
+
+// Run 启动 OPDS 服务
+func (s *OpdsServer) Run() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/opds", http.StatusFound)
+	})
+	mux.HandleFunc("GET /opds", s.handleRoot)
+	mux.HandleFunc("GET /opds/all", s.handleAll)
+	mux.HandleFunc("GET /opds/categories", s.handleCategories)
+	mux.HandleFunc("GET /opds/categories/{name}", s.handleCategory)
+	mux.HandleFunc("GET /opds/authors", s.handleAuthors)
+	mux.HandleFunc("GET /opds/authors/{name}", s.handleAuthor)
+	mux.HandleFunc("GET /opds/search", s.handleSearch)
+	mux.HandleFunc("GET /opds/opensearch.xml", s.handleOpenSearch)
+	mux.HandleFunc("GET /books/{file}", s.handleBook)
+
+	fmt.Printf("OPDS 服务已启动：http://%s/opds\n", s.Listen)
+	return http.ListenAndServe(s.Listen, BasicAuth(s.Username, s.Password, mux))
+}
+
+func (s *OpdsServer) handleRoot(w http.ResponseWriter, r *http.Request) {
+	base := requestBaseURL(s.BaseURL, r)
+	feed := s.newFeed(base, "opds", "得到电子书", false)
+	nav := []struct {
+		ID, Title, Href, Summary string
+		Acquisition              bool
+	}{
+		{"all", "全部电子书", "/opds/all", "按下载时间排序的全部电子书", true},
+		{"categories", "按分类浏览", "/opds/categories", "按电子书分类浏览", false},
+		{"authors", "按作者浏览", "/opds/authors", "按电子书作者浏览", false},
+	}
+	for _, n := range nav {
+		feed.Entries = append(feed.Entries, utils.OpdsEntry{
+			ID:      "urn:dedao-dl:opds:" + n.ID,
+			Title:   n.Title,
+			Content: n.Summary,
+			Links:   []utils.OpdsLink{navLink(base+n.Href, n.Acquisition)},
+		})
+	}
+	writeOpds(w, feed)
+}
+
+func (s *OpdsServer) handleAll(w http.ResponseWriter, r *http.Request) {
+	books, err := loadOpdsBooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeBooks(w, r, "all", "全部电子书", books)
+}
+
+func (s *OpdsServer) handleCategories(w http.ResponseWriter, r *http.Request) {
+	s.writeGroups(w, r, "categories", "按分类浏览", bookCategories)
+}
+
+func (s *OpdsServer) handleCategory(w http.ResponseWriter, r *http.Request) {
+	s.writeGroup(w, r, "categories", r.PathValue("name"), bookCategories)
+}
+
+func (s *OpdsServer) handleAuthors(w http.ResponseWriter, r *http.Request) {
+	s.writeGroups(w, r, "authors", "按作者浏览", bookAuthors)
+}
+
+func (s *OpdsServer) handleAuthor(w http.ResponseWriter, r *http.Request) {
+	s.writeGroup(w, r, "authors", r.PathValue("name"), bookAuthors)
+}
+
+func (s *OpdsServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	books, err := loadOpdsBooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var result []*opdsBook
+	for _, book := range books {
+		if query == "" || book.match(query) {
+			result = append(result, book)
+		}
+	}
+	s.writeBooks(w, r, "search", fmt.Sprintf("搜索：%s", query), result)
+}
+
+func (s *OpdsServer) handleOpenSearch(w http.ResponseWriter, r *http.Request) {
+	base := requestBaseURL(s.BaseURL, r)
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml; charset=utf-8")
+	if err := utils.WriteOpenSearch(w, "dedao-dl", base+"/opds/search?q={searchTerms}"); err != nil {
+		fmt.Printf("输出 OpenSearch 失败: %v\n", err)
+	}
+}
+
+// handleBook 输出 output/Ebook 下的电子书文件
+func (s *OpdsServer) handleBook(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	mimeType := ""
+	for _, f := range ebookFormats {
+		if f.Ext == ext {
+			mimeType = f.MimeType
+		}
+	}
+	if mimeType == "" || name != filepath.Base(name) {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := http.Dir(filepath.Join(OutputDir, EbookDirName)).Open("/" + name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(name))
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+func (s *OpdsServer) newFeed(base, id, title string, acquisition bool) *utils.OpdsFeed {
+	return &utils.OpdsFeed{
+		ID:          "urn:dedao-dl:" + id,
+		Title:       title,
+		Updated:     time.Now(),
+		Acquisition: acquisition,
+		Start:       base + "/opds",
+		Search:      base + "/opds/opensearch.xml",
+	}
+}
+
+// writeGroups 输出分类或作者的导航目录
+func (s *OpdsServer) writeGroups(w http.ResponseWriter, r *http.Request, kind, title string, keys func(*opdsBook) []string) {
+	books, err := loadOpdsBooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	base := requestBaseURL(s.BaseURL, r)
+	feed := s.newFeed(base, "opds:"+kind, title, false)
+	feed.Self = base + "/opds/" + kind
+
+	groups := groupBooks(books, keys)
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		feed.Entries = append(feed.Entries, utils.OpdsEntry{
+			ID:      "urn:dedao-dl:opds:" + kind + ":" + name,
+			Title:   name,
+			Content: fmt.Sprintf("%d 本", len(groups[name])),
+			Links:   []utils.OpdsLink{navLink(base+"/opds/"+kind+"/"+url.PathEscape(name), true)},
+		})
+	}
+	writeOpds(w, feed)
+}
+
+// writeGroup 输出某个分类或作者下的电子书
+func (s *OpdsServer) writeGroup(w http.ResponseWriter, r *http.Request, kind, name string, keys func(*opdsBook) []string) {
+	books, err := loadOpdsBooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	list, ok := groupBooks(books, keys)[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeBooks(w, r, "opds:"+kind+":"+name, name, list)
+}
+
+func (s *OpdsServer) writeBooks(w http.ResponseWriter, r *http.Request, id, title string, books []*opdsBook) {
+	base := requestBaseURL(s.BaseURL, r)
+	feed := s.newFeed(base, id, title, true)
+	feed.Self = base + r.URL.RequestURI()
+	for _, book := range books {
+		feed.Entries = append(feed.Entries, book.entry(base))
+	}
+	writeOpds(w, feed)
+}
+
+func writeOpds(w http.ResponseWriter, feed *utils.OpdsFeed) {
+	contentType := utils.OpdsNavigationType
+	if feed.Acquisition {
+		contentType = utils.OpdsAcquisitionType
+	}
+	w.Header().Set("Content-Type", contentType+";charset=utf-8")
+	if err := feed.WriteOPDS(w); err != nil {
+		fmt.Printf("输出 OPDS 目录失败: %v\n", err)
+	}
+}
+
+func navLink(href string, acquisition bool) utils.OpdsLink {
+	contentType := utils.OpdsNavigationType
+	if acquisition {
+		contentType = utils.OpdsAcquisitionType
+	}
+	return utils.OpdsLink{Rel: utils.OpdsRelSubsection, Href: href, Type: contentType}
+}
+
+// loadOpdsBooks 读取已下载的电子书，按下载时间倒序
+func loadOpdsBooks() (books []*opdsBook, err error) {
+	metas, err := LoadEbookMetas()
+	if err != nil {
+		return
+	}
+	dir := filepath.Join(OutputDir, EbookDirName)
+	for _, meta := range metas {
+		book := &opdsBook{Meta: meta}
+		for _, f := range ebookFormats {
+			info, err1 := os.Stat(filepath.Join(dir, utils.FileName(meta.FileTitle, f.Ext)))
+			if err1 != nil {
+				continue
+			}
+			book.Files = append(book.Files, f.Ext)
+			if info.ModTime().After(book.Updated) {
+				book.Updated = info.ModTime()
+			}
+		}
+		if len(book.Files) > 0 {
+			books = append(books, book)
+		}
+	}
+	sort.SliceStable(books, func(i, j int) bool {
+		return books[i].Updated.After(books[j].Updated)
+	})
+	return
+}
+
+func groupBooks(books []*opdsBook, keys func(*opdsBook) []string) map[string][]*opdsBook {
+	groups := make(map[string][]*opdsBook)
+	for _, book := range books {
+		for _, key := range keys(book) {
+			groups[key] = append(groups[key], book)
+		}
+	}
+	return groups
+}
+
+func bookCategories(book *opdsBook) []string {
+	name := strings.TrimSpace(book.Meta.Detail.ClassifyName)
+	if name == "" {
+		name = "未分类"
+	}
+	return []string{name}
+}
+
+func bookAuthors(book *opdsBook) []string {
+	authors := bookAuthorList(&book.Meta.Detail)
+	if len(authors) == 0 {
+		return []string{"佚名"}
+	}
+	return authors
+}
+
+// bookAuthorList 电子书作者列表，优先使用 AuthorList
+func bookAuthorList(detail *services.EbookDetail) (authors []string) {
+	for _, author := range detail.AuthorList {
+		if author = strings.TrimSpace(author); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	if len(authors) == 0 && strings.TrimSpace(detail.BookAuthor) != "" {
+		authors = append(authors, strings.TrimSpace(detail.BookAuthor))
+	}
+	return
+}
+
+// match 标题、作者、分类、出版社、简介中包含关键词
+func (b *opdsBook) match(query string) bool {
+	d := b.Meta.Detail
+	query = strings.ToLower(query)
+	for _, field := range []string{d.Title, d.OperatingTitle, d.BookAuthor, strings.Join(d.AuthorList, " "), d.ClassifyName, d.Press.Name, d.BookIntro} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *opdsBook) entry(base string) utils.OpdsEntry {
+	d := b.Meta.Detail
+	title := d.Title
+	if title == "" {
+		title = d.OperatingTitle
+	}
+	entry := utils.OpdsEntry{
+		ID:        "urn:dedao-dl:ebook:" + strconv.Itoa(d.ID),
+		Title:     title,
+		Updated:   b.Updated,
+		Authors:   bookAuthorList(&d),
+		Summary:   d.OtherShareSummary,
+		Content:   d.BookIntro,
+		Publisher: d.Press.Name,
+		Issued:    d.PublishTime,
+		Language:  "zh",
+	}
+	if d.ClassifyName != "" {
+		entry.Categories = []string{d.ClassifyName}
+	}
+	if d.Cover != "" {
+		entry.Links = append(entry.Links,
+			utils.OpdsLink{Rel: utils.OpdsRelImage, Href: d.Cover, Type: "image/jpeg"},
+			utils.OpdsLink{Rel: utils.OpdsRelThumbnail, Href: d.Cover, Type: "image/jpeg"},
+		)
+	}
+	for _, ext := range b.Files {
+		for _, f := range ebookFormats {
+			if f.Ext != ext {
+				continue
+			}
+			entry.Links = append(entry.Links, utils.OpdsLink{
+				Rel:   utils.OpdsRelAcquisition,
+				Href:  base + "/books/" + url.PathEscape(utils.FileName(b.Meta.FileTitle, ext)),
+				Type:  f.MimeType,
+				Title: f.Name,
+			})
+		}
+	}
+	return entry
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// pdfBook 合并生成的一个 PDF 文件
+type pdfBook struct {
+	title    string
+	chapters []CourseChapter
+}
+
+// DownloadPdfBook 将课程合并生成一个 PDF，或每章一个 PDF
+// 包含封面、按章节组织的目录和每篇文章的书签，章节为一级书签，文章为二级书签
+func DownloadPdfBook(d *CourseDownload, course *services.CourseInfo, path string) error {
+	list, err := d.articleList()
+	if err != nil {
+		return err
+	}
+	books := pdfBooks(d.ClassName, GroupByChapter(course.ChapterList, list.List), d.MergeChapter, d.IsOrder)
+
+	var cover []byte
+	if d.pdfEngine != PdfEngineNative {
+		cover = d.pdfCover(course.ClassInfo)
+	}
+	for i, book := range books {
+		if err := d.canceled(); err != nil {
+			return err
+		}
+		name := utils.FileName(book.title, "pdf")
+		event := Event{
+			Kind:   KindCourse,
+			ID:     d.ID,
+			Title:  d.ClassName,
+			Author: d.lecturer,
+			Item:   book.title,
+			Format: CourseFormatName(d.DownloadType),
+			Path:   filepath.Join(path, name),
+			Done:   i + 1,
+			Total:  len(books),
+		}
+		_, exist, err := utils.FileSize(event.Path)
+		if err != nil {
+			d.emitItem(event, err)
+			return err
+		}
+		if exist {
+			fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "已存在")
+			event.Type = EventItemSkipped
+			d.emitItem(event, nil)
+			continue
+		}
+
+		if d.pdfEngine == PdfEngineNative {
+			err = d.nativePdfBook(book, event.Path, course.ClassInfo)
+		} else {
+			var body []byte
+			if body, err = d.pdfBookBody(book); err == nil {
+				err = utils.HtmlBook2Pdf(path, book.title, cover, body, d.profile)
+			}
+		}
+		d.emitItem(event, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pdfBooks 合并为一个文件，或每章一个文件，没有章节的课程仍为一个合集
+func pdfBooks(className string, chapters []CourseChapter, mergeChapter, order bool) []pdfBook {
+	if !mergeChapter {
+		return []pdfBook{{title: className + "-合集", chapters: chapters}}
+	}
+	books := make([]pdfBook, 0, len(chapters))
+	for i, chapter := range chapters {
+		name := chapter.Name
+		if name == "" {
+			name = "合集"
+		}
+		title := className + "-" + name
+		if order {
+			title = fmt.Sprintf("%02d.%s", i+1, title)
+		}
+		books = append(books, pdfBook{title: title, chapters: []CourseChapter{chapter}})
+	}
+	return books
+}
+
+// pdfBookBody 合并章节和文章的 HTML，章节标题为 h1，文章标题为 h2，文章中的标题依次降两级
+func (d *CourseDownload) pdfBookBody(book pdfBook) ([]byte, error) {
+	var b strings.Builder
+	for _, chapter := range book.chapters {
+		if chapter.Name != "" {
+			fmt.Fprintf(&b, "<h1 class=\"chapter\" style=\"page-break-before: always;\">%s</h1>\n", html.EscapeString(chapter.Name))
+		}
+		for _, v := range chapter.Articles {
+			if err := d.canceled(); err != nil {
+				return nil, err
+			}
+			doc, enId, err := d.articleDocument(v)
+			if err != nil {
+				return nil, err
+			}
+			res, err := d.templates.HTML(d.articleData(v, enId, doc))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, "<div class=\"article\" style=\"page-break-before: always;\">\n<h2>%s</h2>\n%s</div>\n",
+				html.EscapeString(v.Title), shiftHTMLHeadings(res, 2))
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// nativePdfBook 使用内置引擎生成合并的 PDF，章节为一级书签，文章为二级书签
+func (d *CourseDownload) nativePdfBook(book pdfBook, fileName string, info services.ClassInfo) error {
+	w, err := newPdfWriter(book.title, d.lecturer, d.profile)
+	if err != nil {
+		return err
+	}
+	w.cover(localizeImage(d.assets, info.Name, info.Logo), info.Name, classLecturer(info), info.Intro)
+	var titles []pdfTocEntry
+	for _, chapter := range book.chapters {
+		level := 0
+		if chapter.Name != "" {
+			titles = append(titles, pdfTocEntry{level: 0, title: chapter.Name})
+			level = 1
+		}
+		for _, v := range chapter.Articles {
+			titles = append(titles, pdfTocEntry{level: level, title: v.Title})
+		}
+	}
+	entries := w.toc(titles)
+
+	i := 0
+	for _, chapter := range book.chapters {
+		if chapter.Name != "" {
+			w.section(entries[i], 1)
+			i++
+		}
+		for _, v := range chapter.Articles {
+			if err := d.canceled(); err != nil {
+				return err
+			}
+			doc, enId, err := d.articleDocument(v)
+			if err != nil {
+				return err
+			}
+			data := d.articleData(v, enId, doc)
+			w.section(entries[i], 2)
+			i++
+			w.document(doc, 2)
+			w.comments(data.Comments, 2)
+		}
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", filepath.Base(fileName))
+	return w.save(fileName)
+}
+
+// classLecturer 讲师名称和头衔
+func classLecturer(info services.ClassInfo) string {
+	if info.LecturerNameAndTitle != "" {
+		return info.LecturerNameAndTitle
+	}
+	return info.LecturerName
+}
+
+// articleDocument 获取文章内容，去掉与文章标题重复的音频标题，用于合并生成 PDF、EPUB
+func (d *CourseDownload) articleDocument(article services.ArticleIntro) (*services.Document, string, error) {
+	fmt.Printf("正在获取文章：【\033[37;1m%s\033[0m】\n", article.Title)
+	detail, enId, err := ArticleDetail(d.ID, article.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := services.ParseDocument(detail.Content)
+	if err != nil {
+		return nil, "", err
+	}
+	reportUnknown(article.Title, doc)
+	d.withPoints(article, doc)
+	if d.assets != nil {
+		d.assets.Localize(article.Title, doc)
+	}
+	blocks := doc.Blocks[:0:0]
+	for _, block := range doc.Blocks {
+		if _, ok := block.(services.AudioBlock); !ok {
+			blocks = append(blocks, block)
+		}
+	}
+	doc.Blocks = blocks
+	return doc, enId, nil
+}
+
+// pdfCover 封面：课程图片、名称、讲师和简介
+func (d *CourseDownload) pdfCover(info services.ClassInfo) []byte {
+	logo := info.Logo
+	if logo != "" && d.assets != nil {
+		doc := &services.Document{Blocks: []services.Block{services.ImageBlock{URL: logo}}}
+		d.assets.Localize(info.Name, doc)
+		logo = doc.Blocks[0].(services.ImageBlock).URL
+	}
+	var b strings.Builder
+	b.WriteString("<div class=\"cover\" style=\"text-align: center; padding-top: 15%;\">\n")
+	if logo != "" {
+		fmt.Fprintf(&b, "<img src=\"%s\" style=\"max-width: 60%%; max-height: 400px;\">\n", html.EscapeString(logo))
+	}
+	fmt.Fprintf(&b, "<div style=\"font-size: 32px; font-weight: bold; margin-top: 40px;\">%s</div>\n", html.EscapeString(info.Name))
+	if lecturer := classLecturer(info); lecturer != "" {
+		fmt.Fprintf(&b, "<div style=\"font-size: 20px; margin-top: 16px;\">%s</div>\n", html.EscapeString(lecturer))
+	}
+	if intro := strings.TrimSpace(info.Intro); intro != "" {
+		b.WriteString("<div style=\"text-align: left; margin: 40px 10%; color: #666;\">\n")
+		for _, line := range strings.Split(intro, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(line))
+			}
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</div>\n")
+	return []byte(b.String())
+}
+
+var htmlHeadingRegexp = regexp.MustCompile(`(?i)<(/?)h([1-6])\b`)
+
+// shiftHTMLHeadings 标题降级，最低为 h6
+func shiftHTMLHeadings(s string, offset int) string {
+	return htmlHeadingRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		sub := htmlHeadingRegexp.FindStringSubmatch(m)
+		level, _ := strconv.Atoi(sub[2])
+		return "<" + sub[1] + "h" + strconv.Itoa(min(level+offset, 6))
+	})
+}
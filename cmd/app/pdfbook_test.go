@@ -0,0 +1,51 @@
+package app
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestShiftHTMLHeadings(t *testing.T) {
+	in := `<h1>一</h1><H2 class="a">二</H2><h5>五</h5><header>不变</header>`
+	want := `<h3>一</h3><h4 class="a">二</h4><h6>五</h6><header>不变</header>`
+	if got := shiftHTMLHeadings(in, 2); got != want {
+		t.Errorf("shiftHTMLHeadings = %q, want %q", got, want)
+	}
+}
+
+func TestPdfBooks(t *testing.T) {
+	article := func(id, chapter int) services.ArticleIntro {
+		var a services.ArticleIntro
+		a.ID, a.ChapterID = id, chapter
+		return a
+	}
+	chapters := GroupByChapter(
+		[]services.Chapter{{ID: 1, Name: "第一章"}, {ID: 2, Name: "第二章"}},
+		[]services.ArticleIntro{article(1, 2), article(2, 1), article(3, 0), article(4, 1)},
+	)
+
+	books := pdfBooks("课程", chapters, false, true)
+	if len(books) != 1 || books[0].title != "课程-合集" || len(books[0].chapters) != 3 {
+		t.Fatalf("merged books = %+v", books)
+	}
+
+	books = pdfBooks("课程", chapters, true, true)
+	var titles []string
+	for _, book := range books {
+		titles = append(titles, book.title)
+	}
+	if want := []string{"01.课程-第一章", "02.课程-第二章", "03.课程-其他"}; !slices.Equal(titles, want) {
+		t.Errorf("titles = %q, want %q", titles, want)
+	}
+	if a := books[0].chapters[0].Articles; len(a) != 2 || a[0].ID != 2 || a[1].ID != 4 {
+		t.Errorf("first chapter articles = %+v", a)
+	}
+
+	// 没有章节的课程
+	books = pdfBooks("课程", GroupByChapter(nil, []services.ArticleIntro{article(1, 0)}), true, false)
+	if len(books) != 1 || books[0].title != "课程-合集" {
+		t.Errorf("chapterless books = %+v", books)
+	}
+}
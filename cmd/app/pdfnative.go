@@ -0,0 +1,755 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // 注册 gif 解码
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+	_ "golang.org/x/image/webp" // 注册 webp 解码
+)
+
+// PDF 生成方式
+const (
+	PdfEngineAuto        = "auto"        // 有可用的中文字体时使用内置引擎，否则使用 wkhtmltopdf
+	PdfEngineNative      = "native"      // 内置引擎，直接排版文章内容块
+	PdfEngineWkhtmltopdf = "wkhtmltopdf" // 将 HTML 交给 wkhtmltopdf 生成
+)
+
+// ParsePdfEngine 校验 PDF 生成方式，为空时使用配置
+func ParsePdfEngine(engine string) (string, error) {
+	switch engine = strings.ToLower(engine); engine {
+	case "", PdfEngineAuto, PdfEngineNative, PdfEngineWkhtmltopdf:
+		return engine, nil
+	}
+	return "", fmt.Errorf("不支持的 PDF 生成方式: %s，可选 auto、native、wkhtmltopdf", engine)
+}
+
+// pdfEngine 确定 PDF 生成方式，为空时使用 config.json 的 PDF.engine
+func pdfEngine(engine string) string {
+	if engine == "" {
+		engine = strings.ToLower(config.Instance.PDF.Engine)
+	}
+	if engine == "" || engine == PdfEngineAuto {
+		if pdfFontFile() != "" {
+			return PdfEngineNative
+		}
+		return PdfEngineWkhtmltopdf
+	}
+	return engine
+}
+
+// pdfFontFile 内置引擎使用的字体，优先使用配置的字体
+func pdfFontFile() string {
+	if file := config.Instance.PDF.Font; file != "" {
+		return file
+	}
+	return utils.FindCJKFont()
+}
+
+const pdfFontFamily = "cjk"
+
+type pdfColor [3]int
+
+var (
+	pdfTextColor      = pdfColor{51, 51, 51}
+	pdfHighlightColor = pdfColor{255, 96, 2}
+	pdfLinkColor      = pdfColor{0, 102, 204}
+	pdfMutedColor     = pdfColor{136, 136, 136}
+	pdfBorderColor    = pdfColor{221, 221, 221}
+	pdfPanelColor     = pdfColor{246, 246, 246}
+)
+
+// pdfHeadingScales 一到六级标题相对正文的字号
+var pdfHeadingScales = [...]float64{1.8, 1.55, 1.35, 1.2, 1.1, 1}
+
+// pdfWriter 内置的 PDF 生成，直接排版文章内容块，不依赖 wkhtmltopdf
+// 使用嵌入子集的 TrueType 中文字体，支持标题、段落、列表、图片、表格、页眉页脚、目录和书签
+type pdfWriter struct {
+	pdf      *gofpdf.Fpdf
+	profile  utils.PrintProfile
+	title    string
+	bold     bool                 // 有粗体字体，否则重复绘制模拟粗体
+	first    int                  // 显示页眉页脚的第一页，封面不显示
+	marks    []pdfMark            // 各章节开始的页码，用于页眉页脚的 {section}
+	outline  int                  // 文章中的标题生成书签的层级，-1 时不生成
+	images   map[string]*pdfImage // 本地图片路径 -> 已添加的图片
+	size     float64              // 正文字号，单位 pt
+	top      float64              // 页边距，单位 mm
+	right    float64
+	bottom   float64
+	left     float64
+	contentW float64
+}
+
+// pdfMark 从 page 页开始的章节标题
+type pdfMark struct {
+	page  int
+	title string
+}
+
+type pdfImage struct {
+	name string
+	typ  string
+	w, h float64 // 按 96 dpi 换算的尺寸，单位 mm
+}
+
+// newPdfWriter 按打印配置创建 PDF，找不到中文字体时返回错误
+func newPdfWriter(title, author string, profile utils.PrintProfile) (*pdfWriter, error) {
+	file := pdfFontFile()
+	if file == "" {
+		return nil, errors.New("未找到中文字体，请在 config.json 的 PDF.font 中指定 TrueType 字体，或使用 --pdf-engine wkhtmltopdf")
+	}
+	regular, err := utils.LoadTrueTypeFont(file)
+	if err != nil {
+		return nil, err
+	}
+	w := &pdfWriter{profile: profile, title: title, first: 1, outline: -1, images: make(map[string]*pdfImage), size: profile.FontSize}
+	w.top, w.right, w.bottom, w.left = profile.Margins()
+	w.contentW = profile.Width - w.left - w.right
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           gofpdf.SizeType{Wd: profile.Width, Ht: profile.Height},
+	})
+	pdf.SetMargins(w.left, w.top, w.right)
+	pdf.SetAutoPageBreak(false, w.bottom)
+	pdf.SetCellMargin(0)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", regular)
+	w.pdf = pdf
+	if file := config.Instance.PDF.BoldFont; file != "" {
+		bold, err := utils.LoadTrueTypeFont(file)
+		if err != nil {
+			return nil, err
+		}
+		pdf.AddUTF8FontFromBytes(pdfFontFamily, "B", bold)
+		w.bold = true
+	}
+	if w.size <= 0 || w.contentW <= 0 {
+		return nil, errors.New("打印配置的字号或页边距无效")
+	}
+
+	pdf.SetTitle(title, true)
+	pdf.SetAuthor(author, true)
+	pdf.SetCreator("dedao-dl", true)
+	return w, pdf.Error()
+}
+
+// decorate 排版完成后在每页添加页眉页脚，此时才能确定总页数
+func (w *pdfWriter) decorate() {
+	pages := w.pdf.PageCount()
+	if pages == 0 {
+		return
+	}
+	_, pageH := w.pdf.GetPageSize()
+	size := w.size * 0.75
+	h := size * 1.2 * 25.4 / 72
+	section, next := "", 0
+	for page := 1; page <= pages; page++ {
+		for ; next < len(w.marks) && w.marks[next].page <= page; next++ {
+			section = w.marks[next].title
+		}
+		if page < w.first {
+			continue
+		}
+		vars := map[string]string{
+			"{book}":    w.title,
+			"{section}": section,
+			"{page}":    strconv.Itoa(page),
+			"{pages}":   strconv.Itoa(pages),
+		}
+		w.pdf.SetPage(page)
+		w.setFont(false, size)
+		w.setColor(pdfMutedColor)
+		w.band(utils.PrintTemplate(w.profile.Header, vars), (w.top-h)/2, h)
+		w.band(utils.PrintTemplate(w.profile.Footer, vars), pageH-(w.bottom+h)/2, h)
+	}
+	w.pdf.SetPage(pages)
+}
+
+// band 页眉或页脚的左、中、右三部分
+func (w *pdfWriter) band(parts [3]string, y, h float64) {
+	for i, align := range []string{"LM", "CM", "RM"} {
+		if parts[i] != "" {
+			w.pdf.SetXY(w.left, y)
+			w.pdf.CellFormat(w.contentW, h, parts[i], "", 0, align, false, 0, "")
+		}
+	}
+}
+
+// save 添加页眉页脚，写入文件并输出结果
+func (w *pdfWriter) save(fileName string) error {
+	w.decorate()
+	if err := w.pdf.OutputFileAndClose(fileName); err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return err
+	}
+	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	return nil
+}
+
+// ensure 剩余空间不足 h 时换页
+func (w *pdfWriter) ensure(h float64) {
+	_, pageH := w.pdf.GetPageSize()
+	if w.pdf.GetY()+h > pageH-w.bottom {
+		w.pdf.AddPage()
+	}
+}
+
+func (w *pdfWriter) space(h float64) {
+	w.pdf.SetY(w.pdf.GetY() + h)
+}
+
+func (w *pdfWriter) setFont(bold bool, size float64) {
+	style := ""
+	if bold && w.bold {
+		style = "B"
+	}
+	w.pdf.SetFont(pdfFontFamily, style, size)
+}
+
+func (w *pdfWriter) setColor(c pdfColor) {
+	w.pdf.SetTextColor(c[0], c[1], c[2])
+}
+
+// lineHeight 行高，单位 mm
+func (w *pdfWriter) lineHeight(size float64) float64 {
+	return size * w.profile.LineHeight * 25.4 / 72
+}
+
+// pdfSpan 一段相同样式的文字
+type pdfSpan struct {
+	text      string
+	bold      bool
+	highlight bool
+	link      string
+}
+
+func runsToSpans(runs []services.Run) []pdfSpan {
+	spans := make([]pdfSpan, 0, len(runs))
+	for _, run := range runs {
+		spans = append(spans, pdfSpan{text: run.Text, bold: run.Bold, highlight: run.Highlight, link: run.Jump})
+	}
+	return spans
+}
+
+// pdfToken 换行的最小单位：一个汉字、一个单词或一个空格
+type pdfToken struct {
+	text  string
+	span  int
+	width float64
+	space bool
+	br    bool
+}
+
+type pdfLine struct {
+	tokens []pdfToken
+	width  float64
+}
+
+// pdfTextStyle 段落样式
+type pdfTextStyle struct {
+	size   float64
+	indent float64 // 左缩进，单位 mm
+	width  float64 // 可用宽度，为 0 时为缩进后的正文宽度
+	color  pdfColor
+	center bool
+	pre    bool   // 保留行首空格，用于代码
+	marker string // 首行前的列表符号
+	bar    bool   // 左侧竖线，用于引用
+	fill   bool   // 背景色，用于代码
+}
+
+// isWideRune 汉字、假名、全角符号等可以在任意字符间换行
+func isWideRune(r rune) bool {
+	return r >= 0x2E80
+}
+
+// noBreakBefore 避免出现在行首的标点，允许超出行宽
+func noBreakBefore(s string) bool {
+	for _, r := range s {
+		return strings.ContainsRune("，。、；：？！）」』”’》〉】…,.;:?!)", r)
+	}
+	return false
+}
+
+// tokens 按当前字体测量宽度并拆分，超过行宽的单词按字符拆分
+func (w *pdfWriter) tokens(spans []pdfSpan, size, maxW float64, pre bool) []pdfToken {
+	var tokens []pdfToken
+	for i, span := range spans {
+		w.setFont(span.bold, size)
+		var word []rune
+		flush := func() {
+			if len(word) == 0 {
+				return
+			}
+			s := string(word)
+			if width := w.pdf.GetStringWidth(s); width <= maxW {
+				tokens = append(tokens, pdfToken{text: s, span: i, width: width})
+			} else {
+				for _, r := range word {
+					tokens = append(tokens, pdfToken{text: string(r), span: i, width: w.pdf.GetStringWidth(string(r))})
+				}
+			}
+			word = word[:0]
+		}
+		text := span.text
+		if pre {
+			text = strings.ReplaceAll(text, "\t", "    ")
+		}
+		for _, r := range text {
+			if r > 0xFFFF {
+				// 字体子集只支持基本多文种平面
+				r = '□'
+			}
+			switch {
+			case r == '\n':
+				flush()
+				tokens = append(tokens, pdfToken{span: i, br: true})
+			case unicode.IsSpace(r):
+				flush()
+				tokens = append(tokens, pdfToken{text: " ", span: i, width: w.pdf.GetStringWidth(" "), space: true})
+			case isWideRune(r):
+				flush()
+				tokens = append(tokens, pdfToken{text: string(r), span: i, width: w.pdf.GetStringWidth(string(r))})
+			default:
+				word = append(word, r)
+			}
+		}
+		flush()
+	}
+	return tokens
+}
+
+// breakLines 按行宽换行，行首行尾的空格不占宽度
+func breakLines(tokens []pdfToken, maxW float64, pre bool) []pdfLine {
+	var lines []pdfLine
+	var cur pdfLine
+	push := func() {
+		for len(cur.tokens) > 0 && cur.tokens[len(cur.tokens)-1].space {
+			cur.width -= cur.tokens[len(cur.tokens)-1].width
+			cur.tokens = cur.tokens[:len(cur.tokens)-1]
+		}
+		lines = append(lines, cur)
+		cur = pdfLine{}
+	}
+	for _, t := range tokens {
+		if t.br {
+			push()
+			continue
+		}
+		if t.space && len(cur.tokens) == 0 && !pre {
+			continue
+		}
+		if cur.width+t.width > maxW && len(cur.tokens) > 0 && !t.space && !noBreakBefore(t.text) {
+			push()
+		}
+		cur.tokens = append(cur.tokens, t)
+		cur.width += t.width
+	}
+	if len(cur.tokens) > 0 {
+		push()
+	}
+	return lines
+}
+
+// text 排版一个段落，返回首行的页码和位置
+func (w *pdfWriter) text(spans []pdfSpan, style pdfTextStyle) (page int, y float64) {
+	maxW := style.width
+	if maxW == 0 {
+		maxW = w.contentW - style.indent
+	}
+	lineH := w.lineHeight(style.size)
+	x := w.left + style.indent
+	for i, line := range breakLines(w.tokens(spans, style.size, maxW, style.pre), maxW, style.pre) {
+		w.ensure(lineH)
+		if i == 0 {
+			page, y = w.pdf.PageNo(), w.pdf.GetY()
+		}
+		w.drawLine(line, spans, style, x, w.pdf.GetY(), maxW, lineH, i == 0)
+		w.pdf.SetY(w.pdf.GetY() + lineH)
+	}
+	return
+}
+
+func (w *pdfWriter) drawLine(line pdfLine, spans []pdfSpan, style pdfTextStyle, x, y, maxW, lineH float64, first bool) {
+	if style.fill {
+		w.pdf.SetFillColor(pdfPanelColor[0], pdfPanelColor[1], pdfPanelColor[2])
+		w.pdf.Rect(x-2, y, maxW+4, lineH, "F")
+	}
+	if style.bar {
+		w.pdf.SetFillColor(pdfBorderColor[0], pdfBorderColor[1], pdfBorderColor[2])
+		w.pdf.Rect(x-4, y, 1, lineH, "F")
+	}
+	color := style.color
+	if color == (pdfColor{}) {
+		color = pdfTextColor
+	}
+	if first && style.marker != "" {
+		w.setFont(false, style.size)
+		w.setColor(color)
+		markerW := w.pdf.GetStringWidth(style.marker)
+		w.pdf.SetXY(x-markerW-1.5, y)
+		w.pdf.CellFormat(markerW, lineH, style.marker, "", 0, "LM", false, 0, "")
+	}
+	if style.center {
+		x += (maxW - line.width) / 2
+	}
+	for i := 0; i < len(line.tokens); {
+		j, text, width := i, "", 0.0
+		for ; j < len(line.tokens) && line.tokens[j].span == line.tokens[i].span; j++ {
+			text += line.tokens[j].text
+			width += line.tokens[j].width
+		}
+		span := spans[line.tokens[i].span]
+		w.setFont(span.bold, style.size)
+		switch {
+		case span.link != "":
+			w.setColor(pdfLinkColor)
+		case span.highlight:
+			w.setColor(pdfHighlightColor)
+		default:
+			w.setColor(color)
+		}
+		w.pdf.SetXY(x, y)
+		w.pdf.CellFormat(width, lineH, text, "", 0, "LM", false, 0, "")
+		if span.bold && !w.bold {
+			w.pdf.SetXY(x+0.12, y)
+			w.pdf.CellFormat(width, lineH, text, "", 0, "LM", false, 0, "")
+		}
+		if span.link != "" {
+			w.pdf.LinkString(x, y, width, lineH, span.link)
+		}
+		x += width
+		i = j
+	}
+}
+
+func (w *pdfWriter) bodyStyle() pdfTextStyle {
+	return pdfTextStyle{size: w.size}
+}
+
+// paragraph 段落，段后留白
+func (w *pdfWriter) paragraph(spans []pdfSpan, style pdfTextStyle) {
+	w.text(spans, style)
+	w.space(style.size * 0.6 * 25.4 / 72)
+}
+
+// heading 标题，与下文至少两行保持在同一页，返回标题所在的页码和位置
+func (w *pdfWriter) heading(level int, text string) (page int, y float64) {
+	level = min(max(level, 1), 6)
+	size := w.size * pdfHeadingScales[level-1]
+	w.space(size * 0.5 * 25.4 / 72)
+	w.ensure(w.lineHeight(size) + 2*w.lineHeight(w.size))
+	page, y = w.text([]pdfSpan{{text: text, bold: true}}, pdfTextStyle{size: size})
+	if w.outline >= 0 {
+		w.setFont(false, size)
+		w.pdf.Bookmark(text, w.outline, y)
+	}
+	w.space(size * 0.3 * 25.4 / 72)
+	return
+}
+
+// document 排版文章内容，offset 为标题降级的级数
+func (w *pdfWriter) document(doc *services.Document, offset int) {
+	for _, block := range doc.Blocks {
+		w.block(block, offset)
+	}
+}
+
+func (w *pdfWriter) block(block services.Block, offset int) {
+	switch v := block.(type) {
+	case services.AudioBlock:
+		if title := strings.TrimSpace(strings.TrimSuffix(v.Title, ".mp3")); title != "" {
+			w.heading(1+offset, title)
+		}
+	case services.HeadingBlock:
+		if v.Text != "" {
+			w.heading(v.Level+offset, v.Text)
+		}
+	case services.ParagraphBlock:
+		w.paragraph(runsToSpans(v.Runs), w.bodyStyle())
+	case services.ListBlock:
+		w.list(v, 0)
+		w.space(w.size * 0.6 * 25.4 / 72)
+	case services.QuoteBlock:
+		style := pdfTextStyle{size: w.size, indent: 6, color: pdfMutedColor, bar: true}
+		for _, line := range strings.Split(strings.TrimSpace(v.Text), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				w.text([]pdfSpan{{text: line}}, style)
+			}
+		}
+		w.space(w.size * 0.6 * 25.4 / 72)
+	case services.EliteBlock:
+		w.heading(2+offset, "划重点")
+		w.paragraph([]pdfSpan{{text: v.Text}}, w.bodyStyle())
+	case services.ImageBlock:
+		w.image(v)
+	case services.LabelGroupBlock:
+		w.paragraph([]pdfSpan{{text: v.Text, bold: true, highlight: true}}, pdfTextStyle{size: w.size * pdfHeadingScales[3]})
+	case services.TableBlock:
+		w.table(v)
+	case services.CodeBlock:
+		w.text([]pdfSpan{{text: strings.TrimRight(v.Text, "\n")}}, pdfTextStyle{size: w.size * 0.85, indent: 2, width: w.contentW - 4, pre: true, fill: true})
+		w.space(w.size * 0.6 * 25.4 / 72)
+	case services.UnknownBlock:
+		if text := strings.TrimSpace(v.Text); text != "" {
+			w.paragraph([]pdfSpan{{text: text}}, w.bodyStyle())
+		}
+	}
+}
+
+func (w *pdfWriter) list(list services.ListBlock, depth int) {
+	for i, item := range list.Items {
+		marker := "•"
+		if list.Ordered {
+			marker = strconv.Itoa(i+1) + "."
+		}
+		w.text(runsToSpans(item.Runs), pdfTextStyle{size: w.size, indent: float64(depth+1) * 7, marker: marker})
+		if item.Children != nil {
+			w.list(*item.Children, depth+1)
+		}
+	}
+}
+
+// table 表格，各列等宽，第一行为表头
+func (w *pdfWriter) table(table services.TableBlock) {
+	cols := 0
+	for _, row := range table.Rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	const pad = 1.5
+	colW := w.contentW / float64(cols)
+	size := w.size * 0.9
+	lineH := w.lineHeight(size)
+	w.pdf.SetDrawColor(pdfBorderColor[0], pdfBorderColor[1], pdfBorderColor[2])
+	for i, row := range table.Rows {
+		cells := make([][]pdfLine, cols)
+		spans := make([][]pdfSpan, cols)
+		rows := 1
+		for j, cell := range row {
+			spans[j] = runsToSpans(cell.Runs)
+			if i == 0 {
+				for k := range spans[j] {
+					spans[j][k].bold = true
+				}
+			}
+			cells[j] = breakLines(w.tokens(spans[j], size, colW-2*pad, false), colW-2*pad, false)
+			rows = max(rows, len(cells[j]))
+		}
+		rowH := float64(rows)*lineH + 2*pad
+		w.ensure(rowH)
+		y := w.pdf.GetY()
+		for j := 0; j < cols; j++ {
+			x := w.left + float64(j)*colW
+			style := "D"
+			if i == 0 {
+				w.pdf.SetFillColor(pdfPanelColor[0], pdfPanelColor[1], pdfPanelColor[2])
+				style = "FD"
+			}
+			w.pdf.Rect(x, y, colW, rowH, style)
+			for k, line := range cells[j] {
+				w.drawLine(line, spans[j], pdfTextStyle{size: size}, x+pad, y+pad+float64(k)*lineH, colW-2*pad, lineH, k == 0)
+			}
+		}
+		w.pdf.SetY(y + rowH)
+	}
+	w.space(w.size * 0.6 * 25.4 / 72)
+}
+
+// image 图片居中显示，宽度不超过正文，无法嵌入时显示图片说明
+func (w *pdfWriter) image(block services.ImageBlock) {
+	caption := pdfTextStyle{size: w.size * 0.8, color: pdfMutedColor, center: true}
+	img, err := w.loadImage(block.URL)
+	if err != nil {
+		text := block.Legend
+		if text == "" {
+			text = block.URL
+		}
+		w.paragraph([]pdfSpan{{text: "[图片] " + text}}, caption)
+		return
+	}
+	_, pageH := w.pdf.GetPageSize()
+	scale := min(1, w.contentW/img.w, (pageH-w.top-w.bottom-w.lineHeight(caption.size))/img.h)
+	width, height := img.w*scale, img.h*scale
+	w.ensure(height)
+	y := w.pdf.GetY()
+	w.pdf.ImageOptions(img.name, w.left+(w.contentW-width)/2, y, width, height, false,
+		gofpdf.ImageOptions{ImageType: img.typ}, 0, block.Jump)
+	w.pdf.SetY(y + height + 2)
+	if block.Legend != "" {
+		w.text([]pdfSpan{{text: block.Legend}}, caption)
+	}
+	w.space(w.size * 0.6 * 25.4 / 72)
+}
+
+// loadImage 添加本地图片，jpeg 直接嵌入，png、gif、webp 转换为 png
+func (w *pdfWriter) loadImage(file string) (*pdfImage, error) {
+	if img, ok := w.images[file]; ok {
+		return img, nil
+	}
+	data, typ, width, height, err := readImage(file)
+	if err != nil {
+		return nil, err
+	}
+	img := &pdfImage{name: utils.MD5str(file), typ: typ}
+	w.pdf.RegisterImageOptionsReader(img.name, gofpdf.ImageOptions{ImageType: img.typ}, bytes.NewReader(data))
+	if err := w.pdf.Error(); err != nil {
+		return nil, err
+	}
+	img.w, img.h = float64(width)*25.4/96, float64(height)*25.4/96
+	w.images[file] = img
+	return img, nil
+}
+
+// readImage 读取本地图片，jpeg 原样返回，png、gif、webp 转换为 png，typ 为 jpg 或 png，尺寸单位为像素
+func readImage(file string) (data []byte, typ string, width, height int, err error) {
+	if data, err = os.ReadFile(file); err != nil {
+		return
+	}
+	if http.DetectContentType(data) == "image/jpeg" {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", 0, 0, err
+		}
+		typ, width, height = "jpg", cfg.Width, cfg.Height
+	} else {
+		// 统一转换为 8 位 png，避免 gofpdf 不支持的隔行扫描、16 位等格式
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", 0, 0, err
+		}
+		dst := image.NewNRGBA(src.Bounds())
+		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+		var buf bytes.Buffer
+		if err = png.Encode(&buf, dst); err != nil {
+			return nil, "", 0, 0, err
+		}
+		data, typ, width, height = buf.Bytes(), "png", dst.Bounds().Dx(), dst.Bounds().Dy()
+	}
+	if width == 0 || height == 0 {
+		return nil, "", 0, 0, errors.New("图片尺寸为 0")
+	}
+	return
+}
+
+// comments 留言
+func (w *pdfWriter) comments(comments []services.ArticleComment, offset int) {
+	if len(comments) == 0 {
+		return
+	}
+	w.heading(2+offset, "留言")
+	for _, c := range comments {
+		w.paragraph([]pdfSpan{{text: c.NotesOwner.Name + "：", bold: true}, {text: c.Note + commentLikes(c)}}, w.bodyStyle())
+		if c.CommentReply != "" {
+			w.paragraph([]pdfSpan{{text: c.CommentReplyUser.Name + "(" + c.CommentReplyUser.Role + ") 回复：" + c.CommentReply}},
+				pdfTextStyle{size: w.size, indent: 6, color: pdfMutedColor, bar: true})
+		}
+	}
+}
+
+// cover 封面：课程图片、名称、讲师和简介，不显示页眉页脚
+func (w *pdfWriter) cover(logo, name, lecturer, intro string) {
+	w.pdf.AddPage()
+	w.first = w.pdf.PageNo() + 1
+	_, pageH := w.pdf.GetPageSize()
+	w.pdf.SetY(pageH * 0.17)
+	if img, err := w.loadImage(logo); err == nil {
+		scale := min(1, w.contentW*0.6/img.w, pageH/3/img.h)
+		w.pdf.ImageOptions(img.name, w.left+(w.contentW-img.w*scale)/2, w.pdf.GetY(), img.w*scale, img.h*scale, false,
+			gofpdf.ImageOptions{ImageType: img.typ}, 0, "")
+		w.space(img.h*scale + 12)
+	}
+	w.paragraph([]pdfSpan{{text: name, bold: true}}, pdfTextStyle{size: w.size * 2, center: true})
+	if lecturer != "" {
+		w.paragraph([]pdfSpan{{text: lecturer}}, pdfTextStyle{size: w.size * 1.2, center: true})
+	}
+	w.space(8)
+	for _, line := range strings.Split(strings.TrimSpace(intro), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.paragraph([]pdfSpan{{text: line}}, pdfTextStyle{size: w.size, indent: w.contentW * 0.08, width: w.contentW * 0.84, color: pdfMutedColor})
+		}
+	}
+}
+
+// pdfTocEntry 目录项，level 0 为章节，1 为文章
+type pdfTocEntry struct {
+	level int
+	title string
+	link  int
+	alias string
+}
+
+// toc 目录页，页码在对应的标题排版后通过别名替换
+func (w *pdfWriter) toc(titles []pdfTocEntry) []pdfTocEntry {
+	w.pdf.AddPage()
+	w.paragraph([]pdfSpan{{text: "目 录", bold: true}}, pdfTextStyle{size: w.size * 1.5, center: true})
+	numW := w.size * 1.5
+	entries := make([]pdfTocEntry, len(titles))
+	for i, e := range titles {
+		e.link = w.pdf.AddLink()
+		e.alias = fmt.Sprintf("{%04d}", i)
+		entries[i] = e
+		size := w.size
+		if e.level == 0 {
+			size = w.size * 1.1
+		}
+		indent := float64(e.level) * 8
+		page, y := w.text([]pdfSpan{{text: e.title, bold: e.level == 0}}, pdfTextStyle{size: size, indent: indent, width: w.contentW - indent - numW})
+		cur, curY := w.pdf.PageNo(), w.pdf.GetY()
+		w.pdf.SetPage(page)
+		w.pdf.Link(w.left+indent, y, w.contentW-indent, w.lineHeight(size), e.link)
+		// 等宽字体的别名和替换后的页码宽度相同，右对齐不受影响
+		w.pdf.SetFont("Courier", "", w.size*0.9)
+		w.setColor(pdfTextColor)
+		w.pdf.SetXY(w.left+w.contentW-numW, y)
+		w.pdf.CellFormat(numW, w.lineHeight(size), e.alias, "", 0, "RM", false, 0, "")
+		w.pdf.SetPage(cur)
+		w.pdf.SetY(curY)
+	}
+	return entries
+}
+
+// section 合并 PDF 中的章节或文章，另起一页，添加书签并回填目录页码
+func (w *pdfWriter) section(entry pdfTocEntry, headingLevel int) {
+	w.pdf.AddPage()
+	page, y := w.heading(headingLevel, entry.title)
+	w.setFont(false, w.size)
+	w.pdf.Bookmark(entry.title, entry.level, y)
+	w.marks = append(w.marks, pdfMark{page: page, title: entry.title})
+	if entry.link > 0 {
+		w.pdf.SetLink(entry.link, y, page)
+		w.pdf.RegisterAlias(entry.alias, fmt.Sprintf("%6d", page))
+	}
+}
+
+// nativeArticlePdf 使用内置引擎将一篇文章生成 PDF，文中标题生成书签
+func nativeArticlePdf(fileName string, data ArticleData, profile utils.PrintProfile) error {
+	w, err := newPdfWriter(data.Title, data.Author, profile)
+	if err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return err
+	}
+	w.outline = 0
+	w.marks = []pdfMark{{page: 1, title: data.Title}}
+	w.pdf.AddPage()
+	w.document(data.Document, 0)
+	w.comments(data.Comments, 0)
+	return w.save(fileName)
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBreakLines(t *testing.T) {
+	// 每个字符宽 1，汉字可在任意位置换行，单词整体换行，行首不出现句号
+	tokenize := func(s string) (tokens []pdfToken) {
+		var word []rune
+		flush := func() {
+			if len(word) > 0 {
+				tokens = append(tokens, pdfToken{text: string(word), width: float64(len(word))})
+				word = nil
+			}
+		}
+		for _, r := range s {
+			switch {
+			case r == ' ':
+				flush()
+				tokens = append(tokens, pdfToken{text: " ", width: 1, space: true})
+			case isWideRune(r):
+				flush()
+				tokens = append(tokens, pdfToken{text: string(r), width: 1})
+			default:
+				word = append(word, r)
+			}
+		}
+		flush()
+		return
+	}
+	text := func(lines []pdfLine) string {
+		var out []string
+		for _, line := range lines {
+			var b strings.Builder
+			for _, t := range line.tokens {
+				b.WriteString(t.text)
+			}
+			out = append(out, b.String())
+		}
+		return strings.Join(out, "|")
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"经济学是一门研究选择的学问", "经济学是一|门研究选择|的学问"},
+		{"hello world foo", "hello|world|foo"},
+		{"学习经济学。很有用", "学习经济学。|很有用"},
+		{"用 Go 写代码", "用 Go|写代码"},
+	}
+	for _, tt := range tests {
+		if got := text(breakLines(tokenize(tt.in), 5, false)); got != tt.want {
+			t.Errorf("breakLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package app
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// PodcastServer 本地播客订阅服务，为已下载的课程和听书音频生成订阅源
+type PodcastServer struct {
+	Listen   string
+	BaseURL  string // 订阅中音频地址的前缀，为空时使用请求的 Host
+	Username string // basic auth 用户名，为空时不校验
+	Password string
+}
+
+// Run 启动播客服务
+func (s *PodcastServer) Run() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /feeds/course/{name}", s.handleCourseFeed)
+	mux.HandleFunc("GET /feeds/odob.xml", s.handleOdobFeed)
+	mux.HandleFunc("GET /media/{file...}", s.handleMedia)
+
+	fmt.Printf("播客服务已启动：http://%s\n", s.Listen)
+	return http.ListenAndServe(s.Listen, BasicAuth(s.Username, s.Password, mux))
+}
+
+// BasicAuth 为 handler 增加 basic auth 校验，用户名为空时不校验
+func BasicAuth(username, password string, next http.Handler) http.Handler {
+	if username == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(u), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dedao-dl"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestBaseURL 对外访问地址，未配置时使用请求的 Host
+func requestBaseURL(base string, r *http.Request) string {
+	if base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func (s *PodcastServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	dirs, err := CourseDirs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	base := requestBaseURL(s.BaseURL, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, "<!DOCTYPE html>\n<html><head><meta charset=\"UTF-8\"><title>dedao-dl 播客订阅</title></head><body>\n<h1>播客订阅</h1>\n<ul>\n")
+	for _, dir := range dirs {
+		if !utils.CheckFileExist(filepath.Join(dir, "MP3")) {
+			continue
+		}
+		name := filepath.Base(dir)
+		feed := base + "/feeds/course/" + url.PathEscape(name+".xml")
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(feed), html.EscapeString(name))
+	}
+	if utils.CheckFileExist(filepath.Join(OutputDir, OdobDirName, "MP3")) {
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(base+"/feeds/odob.xml"), OdobDirName)
+	}
+	fmt.Fprint(w, "</ul>\n</body></html>\n")
+}
+
+func (s *PodcastServer) handleCourseFeed(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(r.PathValue("name"), ".xml")
+	if name == "" || name != filepath.Base(name) {
+		http.NotFound(w, r)
+		return
+	}
+	feed, err := CoursePodcast(filepath.Join(OutputDir, name), requestBaseURL(s.BaseURL, r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeFeed(w, feed)
+}
+
+func (s *PodcastServer) handleOdobFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := OdobPodcast(requestBaseURL(s.BaseURL, r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, feed)
+}
+
+// handleMedia 输出 MP3 文件，http.ServeContent 支持 Range 请求
+func (s *PodcastServer) handleMedia(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.PathValue("file"))
+	if !strings.EqualFold(path.Ext(name), ".mp3") {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := http.Dir(OutputDir).Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/mpeg")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+func writeFeed(w http.ResponseWriter, feed *utils.PodcastFeed) {
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	if err := feed.WriteRSS(w); err != nil {
+		fmt.Printf("输出订阅失败: %v\n", err)
+	}
+}
+
+// mediaURL 输出目录下文件的访问地址
+func mediaURL(baseURL string, elem ...string) string {
+	escaped := make([]string, 0, len(elem))
+	for _, e := range elem {
+		escaped = append(escaped, url.PathEscape(e))
+	}
+	return baseURL + "/media/" + strings.Join(escaped, "/")
+}
+
+// CoursePodcast 根据课程目录下的元数据和 MP3 文件生成订阅，按 OrderNum 排序，
+// 没有元数据时按 MP3 文件名生成
+func CoursePodcast(dir, baseURL string) (*utils.PodcastFeed, error) {
+	meta, err := LoadCourseMeta(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return mp3Podcast(dir, baseURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("课程元数据不存在，请重新下载课程: %w", err)
+	}
+	if meta == nil {
+		return nil, errors.New("课程元数据为空")
+	}
+	info := meta.ClassInfo
+	feed := &utils.PodcastFeed{
+		Title:       info.Name,
+		Link:        info.ShareURL,
+		Description: info.Intro,
+		Author:      info.LecturerNameAndTitle,
+		Image:       meta.Cover(),
+		Language:    "zh-cn",
+	}
+	if feed.Description == "" {
+		feed.Description = info.Highlight
+	}
+	if feed.Link == "" {
+		feed.Link = baseURL
+	}
+
+	dirName := filepath.Base(dir)
+	for _, article := range meta.SortedArticles() {
+		// 兼容下载时是否使用了 -o 序号前缀
+		var file string
+		var stat os.FileInfo
+		for _, isOrder := range []bool{meta.IsOrder, !meta.IsOrder} {
+			name := ArticleFileTitle(article, isOrder) + ".mp3"
+			if fi, err := os.Stat(filepath.Join(dir, "MP3", name)); err == nil {
+				file, stat = name, fi
+				break
+			}
+		}
+		if stat == nil {
+			continue
+		}
+		episode := utils.PodcastEpisode{
+			GUID:        article.Enid,
+			Title:       article.Title,
+			Description: article.Summary,
+			URL:         mediaURL(baseURL, dirName, "MP3", file),
+			Size:        stat.Size(),
+			Episode:     article.OrderNum,
+			Image:       article.Logo,
+		}
+		if article.Audio != nil {
+			episode.Duration = article.Audio.Duration
+		}
+		if article.PublishTime > 0 {
+			episode.PubDate = time.Unix(int64(article.PublishTime), 0)
+		}
+		feed.Episodes = append(feed.Episodes, episode)
+	}
+	return feed, nil
+}
+
+// mp3Podcast 根据目录下的 MP3 文件生成订阅，按文件名排序，用于旧版本下载的没有元数据的课程
+func mp3Podcast(dir, baseURL string) (*utils.PodcastFeed, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, "MP3"))
+	if err != nil {
+		return nil, err
+	}
+	dirName := filepath.Base(dir)
+	feed := &utils.PodcastFeed{
+		Title:       dirName,
+		Link:        baseURL,
+		Description: dirName,
+		Language:    "zh-cn",
+	}
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(file), ".mp3") {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		feed.Episodes = append(feed.Episodes, utils.PodcastEpisode{
+			GUID:    dirName + "/" + file,
+			Title:   strings.TrimSuffix(file, filepath.Ext(file)),
+			URL:     mediaURL(baseURL, dirName, "MP3", file),
+			Size:    stat.Size(),
+			Episode: len(feed.Episodes) + 1,
+			PubDate: stat.ModTime(),
+		})
+	}
+	return feed, nil
+}
+
+// OdobPodcast 根据听书书架的元数据生成订阅
+func OdobPodcast(baseURL string) (*utils.PodcastFeed, error) {
+	metas, err := LoadOdobMetas()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		return metas[i].Course.CreateTime < metas[j].Course.CreateTime
+	})
+
+	feed := &utils.PodcastFeed{
+		Title:       OdobDirName,
+		Link:        baseURL,
+		Description: "得到 App 每天听本书书架",
+		Author:      "得到",
+		Language:    "zh-cn",
+	}
+	index := 0
+	for _, meta := range metas {
+		for _, audio := range meta.Audios {
+			file := audio.FileTitle + ".mp3"
+			stat, err := os.Stat(filepath.Join(OutputDir, OdobDirName, "MP3", file))
+			if err != nil {
+				continue
+			}
+			index++
+			episode := utils.PodcastEpisode{
+				GUID:        meta.Course.Enid + ":" + audio.Audio.AliasID,
+				Title:       strings.TrimSpace(audio.FileTitle),
+				Description: audio.Audio.Summary,
+				URL:         mediaURL(baseURL, OdobDirName, "MP3", file),
+				Size:        stat.Size(),
+				Duration:    audio.Audio.Duration,
+				Episode:     index,
+				Image:       audio.Audio.Icon,
+			}
+			if episode.Description == "" {
+				episode.Description = meta.Course.Intro
+			}
+			if episode.Image == "" {
+				episode.Image = meta.Course.Icon
+			}
+			if meta.Course.CreateTime > 0 {
+				episode.PubDate = time.Unix(int64(meta.Course.CreateTime), 0)
+			}
+			if feed.Image == "" {
+				feed.Image = episode.Image
+			}
+			feed.Episodes = append(feed.Episodes, episode)
+		}
+	}
+	return feed, nil
+}
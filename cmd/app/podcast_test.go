@@ -0,0 +1,89 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestCoursePodcast(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "课程")
+	if err := os.MkdirAll(filepath.Join(dir, "MP3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 下载时使用了 -o，元数据中未记录
+	if err := os.WriteFile(filepath.Join(dir, "MP3", "002.第二讲.mp3"), []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "MP3", "第一讲.mp3"), []byte("mp3 data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second := services.ArticleIntro{ArticleBase: services.ArticleBase{Enid: "e2", Title: "第二讲", OrderNum: 2, PublishTime: 100}}
+	second.Audio = &services.Audio{Duration: 90}
+	meta := CourseMeta{
+		ClassInfo: services.ClassInfo{Name: "课程", Highlight: "亮点", SquareImg: "cover.png"},
+		Articles: []services.ArticleIntro{
+			second,
+			{ArticleBase: services.ArticleBase{Enid: "e3", Title: "未下载", OrderNum: 3}},
+			{ArticleBase: services.ArticleBase{Enid: "e1", Title: "第一讲", OrderNum: 1}},
+		},
+	}
+	if err := writeMeta(filepath.Join(dir, MetaFileName), meta); err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := CoursePodcast(dir, "http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Description != "亮点" || feed.Link != "http://localhost:8080" || feed.Image != "cover.png" {
+		t.Errorf("feed = %+v", feed)
+	}
+	if len(feed.Episodes) != 2 {
+		t.Fatalf("episodes = %+v", feed.Episodes)
+	}
+	first, last := feed.Episodes[0], feed.Episodes[1]
+	if first.GUID != "e1" || first.Size != 8 || first.URL != "http://localhost:8080/media/%E8%AF%BE%E7%A8%8B/MP3/%E7%AC%AC%E4%B8%80%E8%AE%B2.mp3" {
+		t.Errorf("first = %+v", first)
+	}
+	if last.GUID != "e2" || last.Duration != 90 || last.PubDate.Unix() != 100 || filepath.Base(last.URL) != "002.%E7%AC%AC%E4%BA%8C%E8%AE%B2.mp3" {
+		t.Errorf("last = %+v", last)
+	}
+}
+
+func TestCoursePodcastWithoutMeta(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "旧课程")
+	if err := os.MkdirAll(filepath.Join(dir, "MP3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"002.第二讲.mp3", "001.第一讲.mp3", "封面.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, "MP3", name), []byte("mp3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	feed, err := CoursePodcast(dir, "http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "旧课程" || len(feed.Episodes) != 2 {
+		t.Fatalf("feed = %+v", feed)
+	}
+	first, last := feed.Episodes[0], feed.Episodes[1]
+	if first.Title != "001.第一讲" || first.Episode != 1 || first.Size != 3 || first.GUID != "旧课程/001.第一讲.mp3" {
+		t.Errorf("first = %+v", first)
+	}
+	if last.Title != "002.第二讲" || last.Episode != 2 {
+		t.Errorf("last = %+v", last)
+	}
+
+	// 元数据损坏时仍然报错
+	if err := os.WriteFile(filepath.Join(dir, MetaFileName), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CoursePodcast(dir, "http://localhost:8080"); err == nil {
+		t.Error("invalid meta should fail")
+	}
+}
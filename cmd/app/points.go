@@ -0,0 +1,316 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+const (
+	// PointsTitle 重点摘要文件名和文章开头摘要的标题
+	PointsTitle = "重点摘要"
+)
+
+// ArticlePoints 获取文章重点，没有重点时返回空
+func ArticlePoints(article services.ArticleIntro) ([]services.Block, error) {
+	point, err := getService().ArticlePoint(article.Enid, strconv.Itoa(article.ProductType))
+	if err != nil {
+		return nil, err
+	}
+	if point == nil || point.HasArticlePoint == 0 {
+		return nil, nil
+	}
+	return pointBlocks(point.Content), nil
+}
+
+// pointBlocks 解析重点内容，格式与文章内容相同，解析失败时每行作为一段
+func pointBlocks(content string) (blocks []services.Block) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil
+	}
+	if doc, err := services.ParseDocument(content); err == nil {
+		for _, block := range doc.Blocks {
+			if _, ok := block.(services.AudioBlock); !ok {
+				blocks = append(blocks, block)
+			}
+		}
+		return
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			blocks = append(blocks, services.ParagraphBlock{Runs: []services.Run{{Type: "text", Text: line}}})
+		}
+	}
+	return
+}
+
+// elitePoints 文中的划重点
+func elitePoints(doc *services.Document) (blocks []services.Block) {
+	for _, block := range doc.Blocks {
+		if b, ok := block.(services.EliteBlock); ok {
+			blocks = append(blocks, b)
+		}
+	}
+	return
+}
+
+// fetchPoints 获取文章重点，接口没有重点时使用文中的划重点，获取失败时提示
+// doc 为 nil 时需要重新获取文章内容
+func fetchPoints(courseID int, article services.ArticleIntro, doc *services.Document) []services.Block {
+	blocks, err := ArticlePoints(article)
+	if err != nil {
+		fmt.Printf("\033[33;1m获取重点失败：%v\033[0m\n", err)
+	}
+	if len(blocks) > 0 {
+		return blocks
+	}
+	if doc == nil {
+		detail, _, err := ArticleDetail(courseID, article.ID)
+		if err != nil {
+			return nil
+		}
+		if doc, err = services.ParseDocument(detail.Content); err != nil {
+			return nil
+		}
+	}
+	return elitePoints(doc)
+}
+
+// withPoints 开启 Points 时在文章开头加入重点摘要，音频字幕按朗读的文稿生成，不加入
+func (d *CourseDownload) withPoints(article services.ArticleIntro, doc *services.Document) {
+	if !d.Points || d.DownloadType == 1 {
+		return
+	}
+	blocks := fetchPoints(d.ID, article, doc)
+	if len(blocks) == 0 {
+		return
+	}
+	head := []services.Block{services.HeadingBlock{Level: 2, Text: PointsTitle}}
+	head = append(head, blocks...)
+	doc.Blocks = append(head, doc.Blocks...)
+}
+
+// PointsArticle 一篇文章的重点
+type PointsArticle struct {
+	Title  string
+	Blocks []services.Block
+}
+
+// PointsChapter 一章中有重点的文章
+type PointsChapter struct {
+	Name     string // 没有章节信息的课程为空
+	Articles []PointsArticle
+}
+
+// PointsDocument 将各章重点合成一个文档，level 为章节标题的级别，文章标题和重点中的标题依次降级
+func PointsDocument(chapters []PointsChapter, level int) *services.Document {
+	doc := &services.Document{}
+	for _, chapter := range chapters {
+		articleLevel := level
+		if chapter.Name != "" {
+			doc.Blocks = append(doc.Blocks, services.HeadingBlock{Level: level, Text: chapter.Name})
+			articleLevel++
+		}
+		for _, article := range chapter.Articles {
+			doc.Blocks = append(doc.Blocks, services.HeadingBlock{Level: articleLevel, Text: article.Title})
+			doc.Blocks = append(doc.Blocks, pointsBody(article.Blocks, articleLevel+1)...)
+		}
+	}
+	return doc
+}
+
+// pointsBody 重点中的标题不高于 level
+func pointsBody(blocks []services.Block, level int) []services.Block {
+	body := make([]services.Block, 0, len(blocks))
+	for _, block := range blocks {
+		if h, ok := block.(services.HeadingBlock); ok && h.Level < level {
+			h.Level = level
+			block = h
+		}
+		body = append(body, block)
+	}
+	return body
+}
+
+// PointsExport 按章节和文章顺序将课程各篇文章的重点导出为一个重点摘要文档
+type PointsExport struct {
+	Task
+	ID         int
+	Format     string // md、pdf 或 epub
+	PdfEngine  string // PDF 生成方式，为空时使用配置
+	PdfProfile string // PDF 打印配置名称，为空时使用配置
+	EmbedFonts bool
+}
+
+// ParsePointsFormat 校验重点摘要的导出格式，为空时为 md
+func ParsePointsFormat(format string) (string, error) {
+	switch format = strings.ToLower(format); format {
+	case "":
+		return "md", nil
+	case "md", "pdf", "epub":
+		return format, nil
+	}
+	return "", fmt.Errorf("导出格式错误: %s，可选 md、pdf、epub", format)
+}
+
+// Download 重点摘要随课程更新，已存在的文件也重新生成
+func (e *PointsExport) Download() error {
+	format, err := ParsePointsFormat(e.Format)
+	if err != nil {
+		return err
+	}
+	course, err := CourseInfo(e.ID)
+	if err != nil {
+		return err
+	}
+	list, err := ArticleList(e.ID, "")
+	if err != nil {
+		return err
+	}
+	info := course.ClassInfo
+	path, err := utils.Mkdir(OutputDir, utils.FileName(info.Name, ""))
+	if err != nil {
+		return err
+	}
+	name := utils.FileName(PointsTitle, format)
+	event := Event{
+		Kind:   KindCourse,
+		ID:     e.ID,
+		Enid:   info.Enid,
+		Title:  info.Name,
+		Author: info.LecturerName,
+		Item:   PointsTitle,
+		Format: "points-" + format,
+		Path:   filepath.Join(path, name),
+		Done:   1,
+		Total:  1,
+	}
+
+	var chapters []PointsChapter
+	for _, group := range GroupByChapter(course.ChapterList, list.List) {
+		chapter := PointsChapter{Name: group.Name}
+		for _, v := range group.Articles {
+			if err := e.canceled(); err != nil {
+				return err
+			}
+			fmt.Printf("正在获取重点：【\033[37;1m%s\033[0m】\n", v.Title)
+			if blocks := fetchPoints(e.ID, v, nil); len(blocks) > 0 {
+				chapter.Articles = append(chapter.Articles, PointsArticle{Title: v.Title, Blocks: blocks})
+			}
+		}
+		if len(chapter.Articles) > 0 {
+			chapters = append(chapters, chapter)
+		}
+	}
+	if len(chapters) == 0 {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", name, "无重点")
+		event.Type = EventItemSkipped
+		e.emitItem(event, nil)
+		return nil
+	}
+
+	title := info.Name + " " + PointsTitle
+	switch format {
+	case "pdf":
+		err = e.pdf(&info, title, path, event.Path, PointsDocument(chapters, 1))
+	case "epub":
+		err = e.epub(&info, title, path, event.Path, chapters)
+	default:
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", name)
+		content := "# " + escapeInline(title) + "\n\n" + RenderMarkdown(PointsDocument(chapters, 2), config.Instance.Markdown)
+		err = printResult(utils.WriteFileWithTrunc(event.Path, content))
+	}
+	e.emitItem(event, err)
+	if err == nil {
+		if err1 := SaveCourseIndex(course, list, false); err1 != nil {
+			fmt.Printf("生成课程首页失败: %v\n", err1)
+		}
+	}
+	return err
+}
+
+// pdf 章节和文章标题生成书签
+func (e *PointsExport) pdf(info *services.ClassInfo, title, path, fileName string, doc *services.Document) error {
+	profile, err := config.Instance.PDF.PrintProfile(e.PdfProfile)
+	if err != nil {
+		return err
+	}
+	data := ArticleData{Class: info, Title: title, Author: info.LecturerName, Document: doc}
+	if pdfEngine(e.PdfEngine) == PdfEngineNative {
+		// 内置引擎从本地读取图片
+		assets, err := newLocalAssets(path, "")
+		if err != nil {
+			return err
+		}
+		assets.Localize(title, doc)
+		defer assets.PrintSummary()
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", filepath.Base(fileName))
+		return nativeArticlePdf(fileName, data, profile)
+	}
+	tmpl, err := currentTemplates()
+	if err != nil {
+		return err
+	}
+	res, err := tmpl.HTML(data)
+	if err != nil {
+		return err
+	}
+	// 章节为 h1、文章为 h2，生成目录和书签
+	return utils.HtmlBook2Pdf(path, PointsTitle, nil, []byte(res), profile)
+}
+
+// epub 章节为一级目录，文章为二级目录，没有章节时文章为一级目录
+func (e *PointsExport) epub(info *services.ClassInfo, title, path, fileName string, chapters []PointsChapter) error {
+	tmpl, err := currentTemplates()
+	if err != nil {
+		return err
+	}
+	var contents []utils.HtmlContent
+	n := 0
+	for i, chapter := range chapters {
+		level := 1
+		if chapter.Name != "" {
+			contents = append(contents, utils.HtmlContent{
+				Content:   fmt.Sprintf("<h1 class=\"chapter\">%s</h1>\n", html.EscapeString(chapter.Name)),
+				ChapterID: fmt.Sprintf("chapter_%03d.xhtml", i+1),
+				TocLevel:  1,
+				TocText:   chapter.Name,
+			})
+			level = 2
+		}
+		for _, article := range chapter.Articles {
+			n++
+			res, err := tmpl.EPUB(ArticleData{
+				Class:    info,
+				Title:    article.Title,
+				Author:   info.LecturerName,
+				Document: &services.Document{Blocks: pointsBody(article.Blocks, 2)},
+			})
+			if err != nil {
+				return err
+			}
+			contents = append(contents, utils.HtmlContent{
+				Content:   fmt.Sprintf("<h1>%s</h1>\n%s", html.EscapeString(article.Title), res),
+				ChapterID: fmt.Sprintf("points_%03d.xhtml", n),
+				TocLevel:  level,
+				TocText:   article.Title,
+			})
+		}
+	}
+	return utils.Html2Epub(utils.EpubOptions{
+		Title:       title,
+		Author:      info.LecturerName,
+		Description: info.Intro,
+		Output:      fileName,
+		ImagesDir:   filepath.Join(path, "images"),
+		EmbedFonts:  embedFonts(e.EmbedFonts),
+		HTML:        contents,
+	}, info.Logo)
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestPointBlocks(t *testing.T) {
+	blocks := pointBlocks(`[{"type":"paragraph","contents":[{"type":"text","text":{"content":"重点"}}]},{"type":"audio","title":"音频"}]`)
+	if len(blocks) != 1 || blocks[0].BlockType() != services.BlockParagraph {
+		t.Errorf("json blocks = %+v", blocks)
+	}
+	blocks = pointBlocks("第一点\n\n 第二点 \n")
+	if len(blocks) != 2 || blocks[1].(services.ParagraphBlock).Runs[0].Text != "第二点" {
+		t.Errorf("text blocks = %+v", blocks)
+	}
+	if blocks := pointBlocks(" "); blocks != nil {
+		t.Errorf("empty blocks = %+v", blocks)
+	}
+	doc := &services.Document{Blocks: []services.Block{
+		services.ParagraphBlock{},
+		services.EliteBlock{Text: "划重点"},
+	}}
+	if blocks := elitePoints(doc); len(blocks) != 1 {
+		t.Errorf("elite blocks = %+v", blocks)
+	}
+}
+
+func TestPointsDocument(t *testing.T) {
+	chapters := []PointsChapter{
+		{Name: "第一章", Articles: []PointsArticle{{Title: "01", Blocks: []services.Block{
+			services.HeadingBlock{Level: 1, Text: "小标题"},
+			services.EliteBlock{Text: "重点"},
+		}}}},
+		{Articles: []PointsArticle{{Title: "02", Blocks: []services.Block{services.EliteBlock{Text: "重点"}}}}},
+	}
+	doc := PointsDocument(chapters, 2)
+	want := []services.Block{
+		services.HeadingBlock{Level: 2, Text: "第一章"},
+		services.HeadingBlock{Level: 3, Text: "01"},
+		services.HeadingBlock{Level: 4, Text: "小标题"},
+		services.EliteBlock{Text: "重点"},
+		services.HeadingBlock{Level: 2, Text: "02"},
+		services.EliteBlock{Text: "重点"},
+	}
+	if len(doc.Blocks) != len(want) {
+		t.Fatalf("blocks = %+v", doc.Blocks)
+	}
+	for i, b := range want {
+		if doc.Blocks[i] != b {
+			t.Errorf("block %d = %+v, want %+v", i, doc.Blocks[i], b)
+		}
+	}
+}
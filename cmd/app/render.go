@@ -0,0 +1,458 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+)
+
+// DocumentToMarkdown 按 config.json 中的 Markdown 选项将文章内容渲染为 markdown
+func DocumentToMarkdown(doc *services.Document) string {
+	return RenderMarkdown(doc, config.Instance.Markdown)
+}
+
+// RenderMarkdown 将文章内容渲染为 CommonMark，表格使用 GFM 语法
+func RenderMarkdown(doc *services.Document, opts config.MarkdownOptions) string {
+	r := mdRenderer{opts: opts}
+	for _, block := range doc.Blocks {
+		r.block(block)
+	}
+	r.b.WriteString("---\n")
+	return MarkdownNewline(r.b.String(), opts)
+}
+
+// CommentsToMarkdown 将留言渲染为 markdown
+func CommentsToMarkdown(comments []services.ArticleComment, opts config.MarkdownOptions) string {
+	r := mdRenderer{opts: opts}
+	r.comments(comments)
+	r.b.WriteString("---\n")
+	return MarkdownNewline(r.b.String(), opts)
+}
+
+// MarkdownNewline 按选项转换换行符，渲染时统一使用 \n
+func MarkdownNewline(md string, opts config.MarkdownOptions) string {
+	if opts.CRLF {
+		return strings.ReplaceAll(md, "\n", "\r\n")
+	}
+	return md
+}
+
+type mdRenderer struct {
+	opts config.MarkdownOptions
+	b    strings.Builder
+}
+
+// para 输出一个块，块之间空一行
+func (r *mdRenderer) para(s string) {
+	if s = strings.TrimRight(s, "\n"); s != "" {
+		r.b.WriteString(s + "\n\n")
+	}
+}
+
+func (r *mdRenderer) heading(level int, text string) string {
+	level = min(max(level+r.opts.HeadingOffset, 1), 6)
+	return strings.Repeat("#", level) + " " + text
+}
+
+func (r *mdRenderer) comments(comments []services.ArticleComment) {
+	r.para(r.heading(2, "留言"))
+	for _, c := range comments {
+		r.para(escapeText(c.NotesOwner.Name + "：" + c.Note + commentLikes(c)))
+		if c.CommentReply != "" {
+			r.para("> " + escapeInline(c.CommentReplyUser.Name+"("+c.CommentReplyUser.Role+") 回复："+c.CommentReply))
+		}
+	}
+}
+
+func (r *mdRenderer) block(block services.Block) {
+	switch b := block.(type) {
+	case services.AudioBlock:
+		if title := strings.TrimSpace(strings.TrimSuffix(b.Title, ".mp3")); title != "" {
+			r.para(r.heading(1, escapeInline(title)))
+		}
+	case services.HeadingBlock:
+		if b.Text != "" {
+			r.para(r.heading(b.Level, escapeInline(b.Text)))
+		}
+	case services.ParagraphBlock:
+		r.para(runsToMarkdown(b.Runs))
+	case services.ListBlock:
+		r.para(listToMarkdown(b))
+	case services.QuoteBlock:
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(b.Text), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, "> "+escapeText(line))
+			}
+		}
+		r.para(strings.Join(lines, "\n>\n"))
+	case services.EliteBlock:
+		r.para(r.heading(2, "划重点"))
+		r.para(textToMarkdown(b.Text))
+	case services.ImageBlock:
+		r.para(imageToMarkdown(b))
+	case services.LabelGroupBlock:
+		r.para(r.heading(2, codeSpan(b.Text)))
+	case services.TableBlock:
+		r.para(tableToMarkdown(b))
+	case services.CodeBlock:
+		r.para(codeToMarkdown(b))
+	case services.UnknownBlock:
+		// 未识别的类型保留文字内容
+		r.para(textToMarkdown(b.Text))
+	}
+}
+
+// textToMarkdown 多行文字，每行一个段落
+func textToMarkdown(text string) string {
+	var paras []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paras = append(paras, escapeText(line))
+		}
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+// runsToMarkdown 段落中的文字，相邻的同样式文字先合并，避免出现 ****
+func runsToMarkdown(runs []services.Run) string {
+	var b strings.Builder
+	merged := mergeRuns(runs)
+	for i, run := range merged {
+		var prev, next rune
+		if i > 0 {
+			prev, _ = utf8.DecodeLastRuneInString(merged[i-1].Text)
+		}
+		if i < len(merged)-1 {
+			next, _ = utf8.DecodeRuneInString(merged[i+1].Text)
+		}
+		b.WriteString(runToMarkdown(run, prev, next))
+	}
+	// 段落内的换行使用硬换行
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return escapeLineStart(strings.Join(lines, "\\\n"))
+}
+
+func mergeRuns(runs []services.Run) []services.Run {
+	var merged []services.Run
+	for _, run := range runs {
+		if run.Text == "" {
+			continue
+		}
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.Bold == run.Bold && last.Highlight == run.Highlight && last.Jump == run.Jump {
+				last.Text += run.Text
+				continue
+			}
+		}
+		merged = append(merged, run)
+	}
+	return merged
+}
+
+// runToMarkdown prev、next 为前后相邻的字符，用于处理强调文字首尾的空白
+func runToMarkdown(run services.Run, prev, next rune) string {
+	// 首尾空白放在强调符号外，相邻的是中文或标点时去掉
+	text := strings.TrimSpace(run.Text)
+	if text == "" {
+		return run.Text
+	}
+	lead := run.Text[:strings.Index(run.Text, text)]
+	trail := run.Text[len(lead)+len(text):]
+	if run.Bold || run.Highlight {
+		if isCJKOrPunct(prev) {
+			lead = ""
+		}
+		if isCJKOrPunct(next) {
+			trail = ""
+		}
+	}
+
+	s := escapeInline(text)
+	if run.Jump != "" {
+		s = "[" + s + "](" + linkDestination(run.Jump) + ")"
+	}
+	switch {
+	case run.Bold && run.Highlight:
+		s = emphasis(s, text, "***", "<strong><em>", "</em></strong>")
+	case run.Bold:
+		s = emphasis(s, text, "**", "<strong>", "</strong>")
+	case run.Highlight:
+		s = emphasis(s, text, "*", "<em>", "</em>")
+	}
+	return lead + s + trail
+}
+
+// isCJKOrPunct 中日韩文字和标点前后不需要空格
+func isCJKOrPunct(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || unicode.IsPunct(r)
+}
+
+// emphasis 文字以标点开头或结尾时，* 可能不满足 CommonMark 的左右侧规则，改用 HTML 标签
+func emphasis(s, text, delim, open, close string) string {
+	first, _ := utf8.DecodeRuneInString(text)
+	last, _ := utf8.DecodeLastRuneInString(text)
+	if unicode.IsPunct(first) || unicode.IsSymbol(first) || unicode.IsPunct(last) || unicode.IsSymbol(last) {
+		return open + s + close
+	}
+	return delim + s + delim
+}
+
+func listToMarkdown(list services.ListBlock) string {
+	var lines []string
+	for i, item := range list.Items {
+		marker := "- "
+		if list.Ordered {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		// 续行和子列表缩进到列表项内容的位置
+		indent := strings.Repeat(" ", len(marker))
+		text := runsToMarkdown(item.Runs)
+		if text == "" && item.Children == nil {
+			continue
+		}
+		for j, line := range strings.Split(text, "\n") {
+			if j == 0 {
+				lines = append(lines, marker+line)
+			} else {
+				lines = append(lines, indent+line)
+			}
+		}
+		if item.Children != nil {
+			for _, line := range strings.Split(listToMarkdown(*item.Children), "\n") {
+				if line != "" {
+					lines = append(lines, indent+line)
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func imageToMarkdown(img services.ImageBlock) string {
+	if img.URL == "" {
+		return ""
+	}
+	alt := escapeInline(img.Legend)
+	s := "![" + alt + "](" + linkDestination(img.URL) + ")"
+	if img.Jump != "" {
+		s = "[" + s + "](" + linkDestination(img.Jump) + ")"
+	}
+	if img.Legend != "" {
+		// 图片说明
+		s += "\\\n" + emphasis(alt, img.Legend, "*", "<em>", "</em>")
+	}
+	return s
+}
+
+func tableToMarkdown(table services.TableBlock) string {
+	if len(table.Rows) == 0 {
+		return ""
+	}
+	cols := 0
+	for _, row := range table.Rows {
+		cols = max(cols, len(row))
+	}
+	var lines []string
+	for i, row := range table.Rows {
+		cells := make([]string, cols)
+		for j, cell := range row {
+			text := strings.ReplaceAll(runsToMarkdown(cell.Runs), "\\\n", "<br>")
+			cells[j] = strings.ReplaceAll(text, "|", "\\|")
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func codeToMarkdown(code services.CodeBlock) string {
+	fence := "```"
+	for strings.Contains(code.Text, fence) {
+		fence += "`"
+	}
+	return fence + code.Language + "\n" + strings.TrimRight(code.Text, "\n") + "\n" + fence
+}
+
+// codeSpan 行内代码，内容包含反引号时使用更长的分隔符
+func codeSpan(text string) string {
+	delim := "`"
+	for strings.Contains(text, delim) {
+		delim += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return delim + text + delim
+}
+
+// linkDestination 链接地址包含空格或括号时使用 <> 包裹
+func linkDestination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+var inlineEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`,
+	`[`, `\[`, `]`, `\]`, `<`, `\<`,
+)
+
+// escapeInline 转义行内的 markdown 符号
+func escapeInline(s string) string {
+	return inlineEscaper.Replace(s)
+}
+
+// escapeText 转义整行文字
+func escapeText(s string) string {
+	return escapeLineStart(escapeInline(s))
+}
+
+var lineStartRe = regexp.MustCompile(`(?m)^(\s*)([#=+>-]|\d+[.)])`)
+
+// escapeLineStart 转义行首会被识别为标题、列表等的符号
+func escapeLineStart(s string) string {
+	return lineStartRe.ReplaceAllStringFunc(s, func(m string) string {
+		trimmed := strings.TrimLeft(m, " \t")
+		lead := m[:len(m)-len(trimmed)]
+		last := len(trimmed) - 1
+		return lead + trimmed[:last] + `\` + trimmed[last:]
+	})
+}
+
+// DocumentToHTML 将文章内容渲染为 HTML 片段，用于生成 PDF
+func DocumentToHTML(doc *services.Document) string {
+	var b strings.Builder
+	for _, block := range doc.Blocks {
+		blockToHTML(&b, block)
+	}
+	b.WriteString("<hr>\n")
+	return b.String()
+}
+
+// CommentsToHTML 将留言渲染为 HTML 片段
+func CommentsToHTML(comments []services.ArticleComment) string {
+	var b strings.Builder
+	b.WriteString("<h2>留言</h2>\n")
+	for _, c := range comments {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(c.NotesOwner.Name+"："+c.Note+commentLikes(c)))
+		if c.CommentReply != "" {
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>\n",
+				html.EscapeString(c.CommentReplyUser.Name+"("+c.CommentReplyUser.Role+") 回复："+c.CommentReply))
+		}
+	}
+	b.WriteString("<hr>\n")
+	return b.String()
+}
+
+func blockToHTML(b *strings.Builder, block services.Block) {
+	switch v := block.(type) {
+	case services.AudioBlock:
+		fmt.Fprintf(b, "<h1>%s</h1>\n", html.EscapeString(strings.TrimSuffix(v.Title, ".mp3")))
+	case services.HeadingBlock:
+		if v.Text != "" {
+			level := min(max(v.Level, 1), 6)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, html.EscapeString(v.Text), level)
+		}
+	case services.QuoteBlock:
+		b.WriteString("<blockquote>\n")
+		for _, text := range strings.Split(v.Text, "\n") {
+			fmt.Fprintf(b, "<p>%s</p>\n", html.EscapeString(text))
+		}
+		b.WriteString("</blockquote>\n")
+	case services.ParagraphBlock:
+		fmt.Fprintf(b, "<p>%s</p>\n", runsToHTML(v.Runs))
+	case services.ListBlock:
+		listToHTML(b, v)
+	case services.EliteBlock:
+		fmt.Fprintf(b, "<h2>划重点</h2>\n<p>%s</p>\n", html.EscapeString(v.Text))
+	case services.ImageBlock:
+		img := fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(v.URL), html.EscapeString(v.Legend))
+		if v.Jump != "" {
+			img = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(v.Jump), img)
+		}
+		b.WriteString("<figure>" + img)
+		if v.Legend != "" {
+			fmt.Fprintf(b, "<figcaption>%s</figcaption>", html.EscapeString(v.Legend))
+		}
+		b.WriteString("</figure>\n")
+	case services.LabelGroupBlock:
+		fmt.Fprintf(b, "<h2><code>%s</code></h2>\n", html.EscapeString(v.Text))
+	case services.TableBlock:
+		b.WriteString("<table>\n")
+		for i, row := range v.Rows {
+			tag := "td"
+			if i == 0 {
+				tag = "th"
+			}
+			b.WriteString("<tr>")
+			for _, cell := range row {
+				fmt.Fprintf(b, "<%s>%s</%s>", tag, runsToHTML(cell.Runs), tag)
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</table>\n")
+	case services.CodeBlock:
+		fmt.Fprintf(b, "<pre><code>%s</code></pre>\n", html.EscapeString(v.Text))
+	case services.UnknownBlock:
+		if text := strings.TrimSpace(v.Text); text != "" {
+			fmt.Fprintf(b, "<p>%s</p>\n", html.EscapeString(text))
+		}
+	}
+}
+
+func runsToHTML(runs []services.Run) string {
+	var b strings.Builder
+	for _, run := range runs {
+		text := html.EscapeString(run.Text)
+		if run.Jump != "" {
+			text = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(run.Jump), text)
+		}
+		if run.Highlight {
+			text = "<em>" + text + "</em>"
+		}
+		if run.Bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+func listToHTML(b *strings.Builder, list services.ListBlock) {
+	tag := "ul"
+	if list.Ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag + ">\n")
+	for _, item := range list.Items {
+		b.WriteString("<li>" + runsToHTML(item.Runs))
+		if item.Children != nil {
+			b.WriteString("\n")
+			listToHTML(b, *item.Children)
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+}
+
+// reportUnknown 提示未识别的内容类型，渲染时只保留其中的文字
+func reportUnknown(title string, doc *services.Document) {
+	if types := doc.UnknownTypes(); len(types) > 0 {
+		fmt.Printf("\033[33;1m【%s】包含未识别的内容类型：%s\033[0m\n", title, strings.Join(types, ", "))
+	}
+}
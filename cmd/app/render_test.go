@@ -0,0 +1,90 @@
+package app
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+var update = flag.Bool("update", false, "更新 testdata 中的 golden 文件")
+
+// loadArticle 读取 testdata 中的文章详情响应，
+// 格式与 /pc/ddarticle/v1/article/get/v2 接口一致，id 等字段已替换
+func loadArticle(t *testing.T) *services.Document {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "markdown", "article.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		C services.ArticleDetail `json:"c"`
+	}
+	if err := utils.UnmarshalJSON(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := services.ParseDocument(resp.C.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	doc := loadArticle(t)
+
+	tests := []struct {
+		golden string
+		opts   config.MarkdownOptions
+	}{
+		{"article.md", config.MarkdownOptions{}},
+		{"article.crlf.md", config.MarkdownOptions{CRLF: true}},
+		{"article.offset.md", config.MarkdownOptions{HeadingOffset: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got := RenderMarkdown(doc, tt.opts)
+			path := filepath.Join("testdata", "markdown", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("RenderMarkdown() mismatch, run go test -update to regenerate\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRunsToMarkdown(t *testing.T) {
+	tests := []struct {
+		runs []services.Run
+		want string
+	}{
+		{[]services.Run{{Text: "你好，"}, {Text: " 重点 ", Bold: true}, {Text: "。"}}, "你好，**重点**。"},
+		{[]services.Run{{Text: "read"}, {Text: " the docs ", Bold: true}, {Text: "now"}}, "read **the docs** now"},
+		{[]services.Run{{Text: "中文"}, {Text: " word", Highlight: true}}, "中文*word*"},
+	}
+	for _, tt := range tests {
+		if got := runsToMarkdown(tt.runs); got != tt.want {
+			t.Errorf("runsToMarkdown() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRunsToHTML(t *testing.T) {
+	runs := []services.Run{{Text: "a<b", Bold: true, Highlight: true}, {Text: "c", Highlight: true}}
+	want := "<strong><em>a&lt;b</em></strong><em>c</em>"
+	if got := runsToHTML(runs); got != want {
+		t.Errorf("runsToHTML() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,532 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// sitePage 静态站点页面
+type sitePage struct {
+	Title       string
+	Root        string // 页面到站点根目录的相对路径，如 ../../
+	Breadcrumbs []siteLink
+	Prev, Next  *siteLink
+	Intro       string
+	Sections    []siteSection
+	Body        template.HTML
+}
+
+type siteSection struct {
+	Title string
+	Links []siteLink
+}
+
+type siteLink struct {
+	Title string
+	URL   string // 相对于站点根目录，为空时不生成链接
+	Note  string
+}
+
+// siteBuilder 静态站点生成器
+type siteBuilder struct {
+	dir   string
+	index *utils.SearchIndex
+	tpl   *template.Template
+}
+
+// ExportSite 将已下载的课程、听书和电子书导出为静态网站，所有链接均为相对路径
+func ExportSite(dir string) error {
+	tpl, err := template.New("page").Parse(sitePageTemplate)
+	if err != nil {
+		return err
+	}
+	b := &siteBuilder{dir: dir, index: utils.NewSearchIndex(), tpl: tpl}
+	home := sitePage{Title: "得到书库"}
+
+	courses, err := b.courses()
+	if err != nil {
+		return err
+	}
+	if len(courses.Links) > 0 {
+		home.Sections = append(home.Sections, courses)
+	}
+	odob, err := b.odob()
+	if err != nil {
+		return err
+	}
+	if len(odob.Links) > 0 {
+		home.Sections = append(home.Sections, odob)
+	}
+	ebooks, err := b.ebooks()
+	if err != nil {
+		return err
+	}
+	if len(ebooks.Links) > 0 {
+		home.Sections = append(home.Sections, ebooks)
+	}
+
+	if err = b.writePage("index.html", home); err != nil {
+		return err
+	}
+	if err = b.writePage("search.html", sitePage{
+		Title:       "搜索",
+		Breadcrumbs: []siteLink{{Title: "首页", URL: "index.html"}},
+		Body:        template.HTML(`<div id="search-results"></div>`),
+	}); err != nil {
+		return err
+	}
+	if err = b.writeAssets(); err != nil {
+		return err
+	}
+	fmt.Printf("静态网站已生成：%s，共 %d 个页面\n", filepath.Join(dir, "index.html"), len(b.index.Docs))
+	return nil
+}
+
+// courses 生成课程目录页和文章页
+func (b *siteBuilder) courses() (section siteSection, err error) {
+	section.Title = "课程"
+	dirs, err := CourseDirs()
+	if err != nil {
+		return
+	}
+	for _, dir := range dirs {
+		meta, err1 := LoadCourseMeta(dir)
+		if err1 != nil || meta == nil {
+			fmt.Printf("读取课程元数据 %s 失败: %v\n", dir, err1)
+			continue
+		}
+		// 按课程 ID 生成路径，新下载的课程不会改变已有页面的地址
+		base := fmt.Sprintf("course/%d/", meta.ClassInfo.ID)
+		home := []siteLink{{Title: "首页", URL: "index.html"}}
+		course := siteLink{Title: meta.ClassInfo.Name, URL: base + "index.html"}
+
+		// 已下载文稿的文章，按 OrderNum 排序
+		type page struct {
+			link siteLink
+			md   string
+		}
+		var pages []page
+		pageURL := make(map[int]string)
+		for _, article := range meta.SortedArticles() {
+			for _, isOrder := range []bool{meta.IsOrder, !meta.IsOrder} {
+				md := filepath.Join(dir, "MD", ArticleFileTitle(article, isOrder)+".md")
+				if utils.CheckFileExist(md) {
+					url := fmt.Sprintf("%s%d.html", base, len(pages)+1)
+					pages = append(pages, page{link: siteLink{Title: article.Title, URL: url}, md: md})
+					pageURL[article.ID] = url
+					break
+				}
+			}
+		}
+
+		for j, p := range pages {
+			md, err1 := os.ReadFile(p.md)
+			if err1 != nil {
+				return section, err1
+			}
+			sp := sitePage{
+				Title:       p.link.Title,
+				Breadcrumbs: append(home, course),
+				Body:        template.HTML(utils.MdToHTML(md)),
+			}
+			if j > 0 {
+				sp.Prev = &pages[j-1].link
+			}
+			if j+1 < len(pages) {
+				sp.Next = &pages[j+1].link
+			}
+			if err = b.writePage(p.link.URL, sp); err != nil {
+				return
+			}
+			b.index.Add(utils.SearchDoc{Title: p.link.Title, URL: p.link.URL, Path: meta.ClassInfo.Name}, htmlText(string(sp.Body)))
+		}
+		if len(pages) > 0 {
+			if err = b.copyAssets(filepath.Join(dir, "MD", AssetsDir), base+AssetsDir); err != nil {
+				return
+			}
+		}
+
+		// 课程目录页，按章节分组
+		cp := sitePage{
+			Title:       meta.ClassInfo.Name,
+			Breadcrumbs: home,
+			Intro:       meta.ClassInfo.Intro,
+		}
+		sections := make(map[string]*siteSection)
+		var order []string
+		for _, article := range meta.SortedArticles() {
+			name := ""
+			if chapter := meta.Chapter(article); chapter != nil {
+				name = chapter.Name
+			}
+			if _, ok := sections[name]; !ok {
+				sections[name] = &siteSection{Title: name}
+				order = append(order, name)
+			}
+			link := siteLink{Title: article.Title, URL: pageURL[article.ID]}
+			if link.URL == "" {
+				link.Note = "未下载文稿"
+			}
+			sections[name].Links = append(sections[name].Links, link)
+		}
+		sort.SliceStable(order, func(x, y int) bool {
+			return meta.chapterIndex(order[x]) < meta.chapterIndex(order[y])
+		})
+		for _, name := range order {
+			cp.Sections = append(cp.Sections, *sections[name])
+		}
+		if err = b.writePage(course.URL, cp); err != nil {
+			return
+		}
+		b.index.Add(utils.SearchDoc{Title: course.Title, URL: course.URL, Path: "课程"}, meta.ClassInfo.Intro)
+
+		course.Note = fmt.Sprintf("%s · %d 篇", meta.ClassInfo.LecturerNameAndTitle, len(pages))
+		section.Links = append(section.Links, course)
+	}
+	return
+}
+
+// chapterIndex 章节在 ChapterList 中的位置，未分章节的排在最前
+func (m *CourseMeta) chapterIndex(name string) int {
+	for i, chapter := range m.ChapterList {
+		if chapter.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// odob 生成听书文稿页
+func (b *siteBuilder) odob() (section siteSection, err error) {
+	section.Title = OdobDirName
+	files, err := filepath.Glob(filepath.Join(OutputDir, OdobDirName, "MD", "*.md"))
+	if err != nil {
+		return
+	}
+	sort.Strings(files)
+	metas, _ := LoadOdobMetas()
+	intro := make(map[string]string, len(metas))
+	for _, meta := range metas {
+		intro[utils.FileName(meta.Course.Title, "")] = meta.Course.Intro
+	}
+
+	home := []siteLink{{Title: "首页", URL: "index.html"}}
+	shelf := siteLink{Title: OdobDirName, URL: "odob/index.html"}
+	links := make([]siteLink, 0, len(files))
+	for i, file := range files {
+		title := strings.TrimSuffix(filepath.Base(file), ".md")
+		links = append(links, siteLink{Title: title, URL: fmt.Sprintf("odob/%d.html", i+1), Note: intro[title]})
+	}
+	for i, file := range files {
+		md, err1 := os.ReadFile(file)
+		if err1 != nil {
+			return section, err1
+		}
+		sp := sitePage{
+			Title:       links[i].Title,
+			Breadcrumbs: append(home, shelf),
+			Body:        template.HTML(utils.MdToHTML(md)),
+		}
+		if i > 0 {
+			sp.Prev = &links[i-1]
+		}
+		if i+1 < len(links) {
+			sp.Next = &links[i+1]
+		}
+		if err = b.writePage(links[i].URL, sp); err != nil {
+			return
+		}
+		b.index.Add(utils.SearchDoc{Title: links[i].Title, URL: links[i].URL, Path: OdobDirName}, htmlText(string(sp.Body)))
+	}
+	if len(links) == 0 {
+		return
+	}
+	if err = b.copyAssets(filepath.Join(OutputDir, OdobDirName, "MD", AssetsDir), "odob/"+AssetsDir); err != nil {
+		return
+	}
+	err = b.writePage(shelf.URL, sitePage{
+		Title:       OdobDirName,
+		Breadcrumbs: home,
+		Sections:    []siteSection{{Links: links}},
+	})
+	shelf.Note = fmt.Sprintf("%d 本", len(links))
+	section.Links = append(section.Links, shelf)
+	return
+}
+
+// ebooks 复制电子书 html 并插入导航
+func (b *siteBuilder) ebooks() (section siteSection, err error) {
+	section.Title = "电子书"
+	metas, err := LoadEbookMetas()
+	if err != nil {
+		return
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		return metas[i].FileTitle < metas[j].FileTitle
+	})
+	type book struct {
+		meta *EbookMeta
+		file string
+		link siteLink
+	}
+	var books []book
+	for _, meta := range metas {
+		file := filepath.Join(OutputDir, EbookDirName, utils.FileName(meta.FileTitle, "html"))
+		if !utils.CheckFileExist(file) {
+			continue
+		}
+		title := meta.Detail.Title
+		if title == "" {
+			title = meta.Detail.OperatingTitle
+		}
+		books = append(books, book{meta: meta, file: file, link: siteLink{
+			Title: title,
+			URL:   fmt.Sprintf("ebook/%d.html", len(books)+1),
+			Note:  meta.Detail.BookAuthor,
+		}})
+	}
+
+	home := []siteLink{{Title: "首页", URL: "index.html"}}
+	shelf := siteLink{Title: "电子书", URL: "ebook/index.html"}
+	for i, bk := range books {
+		data, err1 := os.ReadFile(bk.file)
+		if err1 != nil {
+			return section, err1
+		}
+		nav := sitePage{Title: bk.link.Title, Root: "../", Breadcrumbs: append(home, shelf)}
+		if i > 0 {
+			nav.Prev = &books[i-1].link
+		}
+		if i+1 < len(books) {
+			nav.Next = &books[i+1].link
+		}
+		buf := new(bytes.Buffer)
+		if err = b.tpl.ExecuteTemplate(buf, "nav", nav); err != nil {
+			return
+		}
+		content := insertAfterBody(string(data), `<link rel="stylesheet" href="../assets/site.css">`+buf.String())
+		if err = b.writeFile(bk.link.URL, content); err != nil {
+			return
+		}
+		text := bk.meta.Detail.BookIntro + "\n" + htmlText(string(data))
+		b.index.Add(utils.SearchDoc{Title: bk.link.Title, URL: bk.link.URL, Path: "电子书", Snippet: utils.Snippet(bk.meta.Detail.BookIntro, 120)}, text)
+	}
+	if len(books) == 0 {
+		return
+	}
+	links := make([]siteLink, 0, len(books))
+	for _, bk := range books {
+		links = append(links, bk.link)
+	}
+	err = b.writePage(shelf.URL, sitePage{
+		Title:       "电子书",
+		Breadcrumbs: home,
+		Sections:    []siteSection{{Links: links}},
+	})
+	shelf.Note = fmt.Sprintf("%d 本", len(books))
+	section.Links = append(section.Links, shelf)
+	return
+}
+
+// writePage 渲染页面，name 为相对于站点根目录的路径
+func (b *siteBuilder) writePage(name string, page sitePage) error {
+	page.Root = strings.Repeat("../", strings.Count(name, "/"))
+	buf := new(bytes.Buffer)
+	if err := b.tpl.Execute(buf, page); err != nil {
+		return err
+	}
+	return b.writeFile(name, buf.String())
+}
+
+func (b *siteBuilder) writeFile(name, content string) error {
+	fileName := filepath.Join(b.dir, filepath.FromSlash(name))
+	if _, err := utils.Mkdir(filepath.Dir(fileName)); err != nil {
+		return err
+	}
+	return utils.WriteFileWithTrunc(fileName, content)
+}
+
+// copyAssets 复制 markdown 引用的本地图片，markdown 中的图片地址为相对路径 assets/
+func (b *siteBuilder) copyAssets(src, name string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err = b.writeFile(name+"/"+entry.Name(), string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *siteBuilder) writeAssets() error {
+	if err := b.writeFile("assets/site.css", siteCSS); err != nil {
+		return err
+	}
+	if err := b.writeFile("assets/search.js", siteSearchJS); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := b.index.WriteJS(buf, "SEARCH_INDEX"); err != nil {
+		return err
+	}
+	return b.writeFile("assets/search-index.js", buf.String())
+}
+
+// htmlText 提取 html 中的纯文本
+func htmlText(content string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	doc.Find("script,style").Remove()
+	return doc.Text()
+}
+
+// insertAfterBody 在 <body> 标签后插入内容，没有 body 标签时插入到开头
+func insertAfterBody(content, insert string) string {
+	lower := strings.ToLower(content)
+	i := strings.Index(lower, "<body")
+	if i < 0 {
+		return insert + content
+	}
+	j := strings.Index(content[i:], ">")
+	if j < 0 {
+		return insert + content
+	}
+	pos := i + j + 1
+	return content[:pos] + insert + content[pos:]
+}
+
+const sitePageTemplate = `{{define "nav"}}<nav class="site-nav">
+<div class="site-crumbs">{{range .Breadcrumbs}}<a href="{{$.Root}}{{.URL}}">{{.Title}}</a> / {{end}}<span>{{.Title}}</span></div>
+<form class="site-search" action="{{.Root}}search.html"><input type="search" name="q" placeholder="搜索"></form>
+</nav>{{end}}{{define "pager"}}{{if or .Prev .Next}}<div class="site-pager">
+{{if .Prev}}<a class="prev" href="{{.Root}}{{.Prev.URL}}">← {{.Prev.Title}}</a>{{else}}<span></span>{{end}}
+{{if .Next}}<a class="next" href="{{.Root}}{{.Next.URL}}">{{.Next.Title}} →</a>{{end}}
+</div>{{end}}{{end}}<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}assets/site.css">
+</head>
+<body class="site">
+{{template "nav" .}}
+<main>
+{{template "pager" .}}
+<h1>{{.Title}}</h1>
+{{if .Intro}}<p class="site-intro">{{.Intro}}</p>{{end}}
+{{range .Sections}}<section>
+{{if .Title}}<h2>{{.Title}}</h2>{{end}}
+<ul>
+{{range .Links}}<li>{{if .URL}}<a href="{{$.Root}}{{.URL}}">{{.Title}}</a>{{else}}<span class="site-missing">{{.Title}}</span>{{end}}{{if .Note}} <small>{{.Note}}</small>{{end}}</li>
+{{end}}</ul>
+</section>
+{{end}}<article>
+{{.Body}}
+</article>
+{{template "pager" .}}
+</main>
+<script>var SITE_ROOT = "{{.Root}}";</script>
+<script src="{{.Root}}assets/search-index.js"></script>
+<script src="{{.Root}}assets/search.js"></script>
+</body>
+</html>
+`
+
+const siteCSS = `body.site { max-width: 860px; margin: 0 auto; padding: 0 16px 48px; font-family: "PingFang SC", "Microsoft YaHei", Arial, sans-serif; color: #333; line-height: 1.8; }
+body.site img { max-width: 100%; }
+body.site h1 { font-size: 1.6em; }
+body.site li small, body.site .site-intro { color: #888; }
+body.site .site-missing { color: #aaa; }
+body.site em { font-style: normal; color: rgb(255, 96, 2); }
+.site-nav { display: flex; justify-content: space-between; align-items: center; flex-wrap: wrap; gap: 8px; padding: 12px 0; border-bottom: 1px solid #eee; font-size: 14px; font-family: "PingFang SC", "Microsoft YaHei", Arial, sans-serif; }
+.site-nav a { color: rgb(255, 96, 2); text-decoration: none; }
+.site-search input { padding: 4px 8px; border: 1px solid #ddd; border-radius: 4px; }
+.site-pager { display: flex; justify-content: space-between; margin: 16px 0; font-size: 14px; }
+.site-pager a { color: rgb(255, 96, 2); text-decoration: none; }
+#search-results .result { margin: 16px 0; }
+#search-results .result p { margin: 4px 0; color: #666; font-size: 14px; }
+`
+
+// siteSearchJS 与 utils.SearchTokens 保持一致的切分规则
+// 索引中没有单字，搜索单个中日韩文字时合并包含该字的所有词
+const siteSearchJS = `(function () {
+  var CJK = /[\p{Script=Han}\p{Script=Hiragana}\p{Script=Katakana}\p{Script=Hangul}]/u;
+  var WORD = /[\p{L}\p{N}]/u;
+  function tokens(text) {
+    var out = [], word = "", cjk = [];
+    function flushWord() { if (word) { out.push(word); word = ""; } }
+    function flushCJK() {
+      if (cjk.length === 1) out.push(cjk[0]);
+      for (var i = 0; i + 1 < cjk.length; i++) out.push(cjk[i] + cjk[i + 1]);
+      cjk = [];
+    }
+    for (var ch of text) {
+      if (CJK.test(ch)) { flushWord(); cjk.push(ch); }
+      else if (WORD.test(ch)) { flushCJK(); word += ch.toLowerCase(); }
+      else { flushWord(); flushCJK(); }
+    }
+    flushWord(); flushCJK();
+    return out;
+  }
+  function lookup(term) {
+    if (Array.from(term).length !== 1 || !CJK.test(term)) return SEARCH_INDEX.index[term] || [];
+    var seen = {}, ids = [];
+    Object.keys(SEARCH_INDEX.index).forEach(function (key) {
+      if (key.indexOf(term) < 0) return;
+      SEARCH_INDEX.index[key].forEach(function (id) {
+        if (!seen[id]) { seen[id] = true; ids.push(id); }
+      });
+    });
+    return ids.sort(function (a, b) { return a - b; });
+  }
+  function search(query) {
+    if (typeof SEARCH_INDEX === "undefined") return [];
+    var terms = tokens(query), hits = null;
+    for (var i = 0; i < terms.length; i++) {
+      var ids = lookup(terms[i]);
+      var set = {};
+      ids.forEach(function (id) { set[id] = true; });
+      hits = hits === null ? ids.slice() : hits.filter(function (id) { return set[id]; });
+    }
+    return (hits || []).map(function (id) { return SEARCH_INDEX.docs[id]; });
+  }
+  function escape(s) {
+    return String(s).replace(/[&<>"]/g, function (c) { return {"&": "&amp;", "<": "&lt;", ">": "&gt;", "\"": "&quot;"}[c]; });
+  }
+  var box = document.getElementById("search-results");
+  var q = new URLSearchParams(location.search).get("q") || "";
+  document.querySelectorAll(".site-search input").forEach(function (input) { input.value = q; });
+  if (!box) return;
+  if (!q.trim()) { box.innerHTML = "<p>请输入关键词</p>"; return; }
+  var docs = search(q);
+  var html = "<p>共找到 " + docs.length + " 个结果</p>";
+  docs.slice(0, 200).forEach(function (d) {
+    html += '<div class="result"><a href="' + SITE_ROOT + escape(d.u) + '">' + escape(d.t) + "</a>" +
+      "<p><small>" + escape(d.p) + "</small></p><p>" + escape(d.s) + "</p></div>";
+  });
+  box.innerHTML = html;
+})();
+`
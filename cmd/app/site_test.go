@@ -0,0 +1,83 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestExportSite(t *testing.T) {
+	output := OutputDir
+	OutputDir = t.TempDir()
+	defer func() { OutputDir = output }()
+
+	dir := filepath.Join(OutputDir, "课程")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := CourseMeta{
+		ClassInfo:   services.ClassInfo{ID: 42, Name: "课程", Intro: "课程简介"},
+		ChapterList: []services.Chapter{{ID: 1, Name: "第一章"}},
+		Articles: []services.ArticleIntro{
+			{ArticleBase: services.ArticleBase{ID: 2, Title: "第二讲", OrderNum: 2, ChapterID: 1}},
+			{ArticleBase: services.ArticleBase{ID: 1, Title: "第一讲", OrderNum: 1, ChapterID: 1}},
+		},
+		IsOrder: true,
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMeta(filepath.Join(dir, MetaFileName), meta); err != nil {
+		t.Fatal(err)
+	}
+	write(filepath.Join(dir, "MD", "001.第一讲.md"), "经济学是一门研究选择的学问\n\n![](assets/a.png)\n")
+	write(filepath.Join(dir, "MD", AssetsDir, "a.png"), "png")
+	write(filepath.Join(OutputDir, OdobDirName, "MD", "一本书.md"), "听书文稿\n")
+
+	site := t.TempDir()
+	if err := ExportSite(site); err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(site, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	home := read("index.html")
+	if !strings.Contains(home, `href="course/42/index.html"`) || !strings.Contains(home, `href="odob/index.html"`) {
+		t.Errorf("home = %s", home)
+	}
+	course := read("course/42/index.html")
+	for _, want := range []string{`<h2>第一章</h2>`, `href="../../course/42/1.html">第一讲</a>`, `<span class="site-missing">第二讲</span> <small>未下载文稿</small>`} {
+		if !strings.Contains(course, want) {
+			t.Errorf("course page missing %s", want)
+		}
+	}
+	article := read("course/42/1.html")
+	if !strings.Contains(article, `src="assets/a.png"`) || !strings.Contains(article, `href="../../index.html">首页</a>`) {
+		t.Errorf("article = %s", article)
+	}
+	if read("course/42/assets/a.png") != "png" {
+		t.Error("assets not copied")
+	}
+	if !strings.Contains(read("odob/1.html"), "听书文稿") {
+		t.Error("odob page missing content")
+	}
+	index := read("assets/search-index.js")
+	if !strings.HasPrefix(index, "var SEARCH_INDEX = ") || !strings.Contains(index, `"经济":[0]`) {
+		t.Errorf("search index = %s", index)
+	}
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+)
+
+// 模板文件名，放在模板目录中即可覆盖内置模板
+const (
+	TemplateMarkdown       = "article.md.tmpl"   // 文章 markdown
+	TemplateHTML           = "article.html.tmpl" // 文章 HTML，用于生成 PDF
+	TemplateEPUB           = "article.epub.tmpl" // EPUB 章节，未提供时与 HTML 相同
+	TemplateCourseMarkdown = "course.md.tmpl"    // markdown 合集开头
+)
+
+// 内置模板，与未使用模板时的排版一致
+const (
+	defaultMarkdownTemplate = `{{content .Document}}---
+{{with .Comments}}{{comments .}}---
+{{end}}`
+	defaultHTMLTemplate = `{{content .Document}}<hr>
+{{with .Comments}}{{comments .}}{{end}}`
+	defaultCourseMarkdownTemplate = ``
+)
+
+// ArticleData 文章模板的数据
+type ArticleData struct {
+	Class    *services.ClassInfo    // 课程信息，每天听本书为 nil
+	Article  *services.ArticleIntro // 课程文章信息，每天听本书为 nil
+	Book     *services.CourseV2     // 每天听本书信息，课程为 nil
+	Title    string                 // 文章标题或书名
+	Author   string                 // 讲师或作者
+	Document *services.Document
+	Comments []services.ArticleComment // 未开启留言时为空
+}
+
+// CourseData 课程模板的数据
+type CourseData struct {
+	Class    *services.ClassInfo
+	Articles []services.ArticleIntro
+}
+
+// Templates 文章和课程模板
+type Templates struct {
+	markdown *texttemplate.Template
+	course   *texttemplate.Template
+	html     *htmltemplate.Template
+	epub     *htmltemplate.Template
+	opts     config.MarkdownOptions
+}
+
+// LoadTemplates 加载 dir 中的模板，dir 中不存在的模板使用内置模板
+func LoadTemplates(dir string, opts config.MarkdownOptions) (t *Templates, err error) {
+	t = &Templates{opts: opts}
+	mdFuncs := t.markdownFuncs()
+	if t.markdown, err = parseText(dir, TemplateMarkdown, defaultMarkdownTemplate, mdFuncs); err != nil {
+		return nil, err
+	}
+	if t.course, err = parseText(dir, TemplateCourseMarkdown, defaultCourseMarkdownTemplate, mdFuncs); err != nil {
+		return nil, err
+	}
+	htmlText, err := readTemplate(dir, TemplateHTML, defaultHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if t.html, err = htmltemplate.New(TemplateHTML).Funcs(htmlFuncs()).Parse(htmlText); err != nil {
+		return nil, err
+	}
+	epubText, err := readTemplate(dir, TemplateEPUB, htmlText)
+	if err != nil {
+		return nil, err
+	}
+	if t.epub, err = htmltemplate.New(TemplateEPUB).Funcs(htmlFuncs()).Parse(epubText); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// currentTemplates 按 config.json 中的 TemplateDir 加载模板，每次下载重新加载，修改模板后无需重启
+func currentTemplates() (*Templates, error) {
+	dir := config.Instance.TemplateDir
+	if dir == "" {
+		dir = filepath.Join(config.GetConfigDir(), "templates")
+	}
+	return LoadTemplates(dir, config.Instance.Markdown)
+}
+
+// Markdown 渲染文章 markdown
+func (t *Templates) Markdown(data ArticleData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.markdown.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return MarkdownNewline(buf.String(), t.opts), nil
+}
+
+// CourseMarkdown 渲染 markdown 合集开头
+func (t *Templates) CourseMarkdown(data CourseData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.course.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return MarkdownNewline(buf.String(), t.opts), nil
+}
+
+// HTML 渲染生成 PDF 用的文章 HTML 片段
+func (t *Templates) HTML(data ArticleData) (string, error) {
+	var buf bytes.Buffer
+	err := t.html.Execute(&buf, data)
+	return buf.String(), err
+}
+
+// EPUB 渲染 EPUB 章节的 HTML 片段
+func (t *Templates) EPUB(data ArticleData) (string, error) {
+	var buf bytes.Buffer
+	err := t.epub.Execute(&buf, data)
+	return buf.String(), err
+}
+
+// markdownFuncs markdown 模板中可用的函数，输出统一使用 \n 换行，渲染完成后再按选项转换
+func (t *Templates) markdownFuncs() texttemplate.FuncMap {
+	funcs := texttemplate.FuncMap{
+		"content": func(doc *services.Document) string {
+			r := mdRenderer{opts: t.opts}
+			for _, block := range doc.Blocks {
+				r.block(block)
+			}
+			return r.b.String()
+		},
+		"render": func(block services.Block) string {
+			r := mdRenderer{opts: t.opts}
+			r.block(block)
+			return r.b.String()
+		},
+		"comments": func(comments []services.ArticleComment) string {
+			r := mdRenderer{opts: t.opts}
+			r.comments(comments)
+			return r.b.String()
+		},
+		"heading": func(level int, text string) string {
+			r := mdRenderer{opts: t.opts}
+			return r.heading(level, escapeInline(text))
+		},
+		"escape": escapeText,
+	}
+	for k, v := range commonFuncs() {
+		funcs[k] = v
+	}
+	return funcs
+}
+
+// htmlFuncs HTML 模板中可用的函数
+func htmlFuncs() htmltemplate.FuncMap {
+	funcs := htmltemplate.FuncMap{
+		"content": func(doc *services.Document) htmltemplate.HTML {
+			var b strings.Builder
+			for _, block := range doc.Blocks {
+				blockToHTML(&b, block)
+			}
+			return htmltemplate.HTML(b.String())
+		},
+		"render": func(block services.Block) htmltemplate.HTML {
+			var b strings.Builder
+			blockToHTML(&b, block)
+			return htmltemplate.HTML(b.String())
+		},
+		"comments": func(comments []services.ArticleComment) htmltemplate.HTML {
+			return htmltemplate.HTML(CommentsToHTML(comments))
+		},
+	}
+	for k, v := range commonFuncs() {
+		funcs[k] = v
+	}
+	return funcs
+}
+
+func commonFuncs() map[string]any {
+	return map[string]any{
+		// date 将时间戳格式化为 2006-01-02
+		"date": func(ts int) string {
+			if ts <= 0 {
+				return ""
+			}
+			return time.Unix(int64(ts), 0).Format(time.DateOnly)
+		},
+		// duration 将秒数格式化为 mm:ss 或 h:mm:ss
+		"duration": formatSeconds,
+		"join":     strings.Join,
+	}
+}
+
+func parseText(dir, name, def string, funcs texttemplate.FuncMap) (*texttemplate.Template, error) {
+	text, err := readTemplate(dir, name, def)
+	if err != nil {
+		return nil, err
+	}
+	return texttemplate.New(name).Funcs(funcs).Parse(text)
+}
+
+// readTemplate 读取模板文件，不存在时返回 def
+func readTemplate(dir, name, def string) (string, error) {
+	if dir == "" {
+		return def, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return def, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/services"
+)
+
+func TestTemplates(t *testing.T) {
+	doc := loadArticle(t)
+	data := ArticleData{
+		Class:    &services.ClassInfo{Name: "经济学通识课", LecturerName: "薛兆丰"},
+		Article:  &services.ArticleIntro{ArticleBase: services.ArticleBase{Title: "为什么要学习经济学", PublishTime: 1577880000}},
+		Title:    "为什么要学习经济学",
+		Author:   "薛兆丰",
+		Document: doc,
+	}
+
+	// 内置模板与直接渲染一致
+	tmpl, err := LoadTemplates("", config.MarkdownOptions{CRLF: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tmpl.Markdown(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := RenderMarkdown(doc, config.MarkdownOptions{CRLF: true}); got != want {
+		t.Errorf("default markdown template mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	html, err := tmpl.HTML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := DocumentToHTML(doc); html != want {
+		t.Errorf("default html template mismatch\ngot:\n%s\nwant:\n%s", html, want)
+	}
+
+	// 模板目录中的模板覆盖内置模板，EPUB 未提供时使用 HTML 模板
+	dir := t.TempDir()
+	files := map[string]string{
+		TemplateMarkdown: "{{heading 1 .Title}}\n\n> {{.Class.Name}} · {{.Author}} · {{date .Article.PublishTime}}\n\n{{range .Document.Blocks}}{{if eq .BlockType \"code\"}}{{render .}}{{end}}{{end}}",
+		TemplateHTML:     "<h1>{{.Title}}</h1>{{content .Document}}",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl, err = LoadTemplates(dir, config.MarkdownOptions{HeadingOffset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = tmpl.Markdown(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Unix(1577880000, 0).Format(time.DateOnly)
+	want := "## 为什么要学习经济学\n\n> 经济学通识课 · 薛兆丰 · " + date + "\n\n```python\ndef cost(x):\n    return x * 2\n```\n\n"
+	if got != want {
+		t.Errorf("custom markdown template = %q, want %q", got, want)
+	}
+	data.Title = "<b>标题</b>"
+	epub, err := tmpl.EPUB(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(epub, "<h1>&lt;b&gt;标题&lt;/b&gt;</h1><h1>") {
+		t.Errorf("epub template = %q", epub[:min(len(epub), 80)])
+	}
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// TranscriptCue 字幕中的一段文字
+type TranscriptCue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// transcriptFiles mp3 同目录的同名 LRC 和 SRT 文件
+func transcriptFiles(mp3 string) (lrc, srt string) {
+	base := strings.TrimSuffix(mp3, filepath.Ext(mp3))
+	return base + ".lrc", base + ".srt"
+}
+
+// TranscriptExists 字幕是否已生成，mp3 不存在时也返回 true，不需要生成
+func TranscriptExists(mp3 string) bool {
+	if !utils.CheckFileExist(mp3) {
+		return true
+	}
+	lrcFile, srtFile := transcriptFiles(mp3)
+	if utils.CheckFileExist(lrcFile) && utils.CheckFileExist(srtFile) {
+		fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 \033[33;1m%s\033[0m\n", filepath.Base(lrcFile), "已存在")
+		return true
+	}
+	return false
+}
+
+// WriteTranscripts 在 mp3 同目录生成同名的 LRC 和 SRT 字幕，每段文稿一条，duration 为音频时长
+// silence 为 true 时使用 ffmpeg 检测静音校准每段的时间，检测失败时使用按字数估算的时间
+func WriteTranscripts(mp3, title, author string, doc *services.Document, duration time.Duration, silence bool) error {
+	lrcFile, srtFile := transcriptFiles(mp3)
+	var silences []utils.Silence
+	if silence {
+		s, d, err := utils.DetectSilence(mp3)
+		if err != nil {
+			fmt.Printf("\033[33;1m检测静音失败，使用估算的时间：%v\033[0m\n", err)
+		} else {
+			silences = s
+			if d > 0 {
+				duration = d
+			}
+		}
+	}
+	if duration <= 0 {
+		return errors.New("音频时长未知: " + title)
+	}
+	cues := TranscriptCues(transcriptParagraphs(doc), duration)
+	if len(cues) == 0 {
+		return errors.New("文稿为空: " + title)
+	}
+	refineCues(cues, silences)
+
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", filepath.Base(lrcFile))
+	err := utils.WriteFileWithTrunc(lrcFile, transcriptLRC(title, author, duration, cues))
+	if err == nil {
+		err = utils.WriteFileWithTrunc(srtFile, transcriptSRT(cues))
+	}
+	return printResult(err)
+}
+
+// transcriptParagraphs 音频中朗读的文字，标题、段落、列表项、引用和划重点的每行各为一段
+func transcriptParagraphs(doc *services.Document) (paras []string) {
+	add := func(s string) {
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.Join(strings.Fields(line), " "); line != "" {
+				paras = append(paras, line)
+			}
+		}
+	}
+	var list func(l services.ListBlock)
+	list = func(l services.ListBlock) {
+		for _, item := range l.Items {
+			add(plainRuns(item.Runs))
+			if item.Children != nil {
+				list(*item.Children)
+			}
+		}
+	}
+	for _, block := range doc.Blocks {
+		switch b := block.(type) {
+		case services.HeadingBlock:
+			add(b.Text)
+		case services.ParagraphBlock:
+			add(plainRuns(b.Runs))
+		case services.ListBlock:
+			list(b)
+		case services.QuoteBlock:
+			add(b.Text)
+		case services.EliteBlock:
+			add(b.Text)
+		}
+	}
+	return
+}
+
+// TranscriptCues 按每段字数占总字数的比例分配音频时长
+func TranscriptCues(paras []string, duration time.Duration) []TranscriptCue {
+	counts := make([]int, len(paras))
+	total := 0
+	for i, p := range paras {
+		counts[i] = max(utf8.RuneCountInString(strings.ReplaceAll(p, " ", "")), 1)
+		total += counts[i]
+	}
+	cues := make([]TranscriptCue, 0, len(paras))
+	chars := 0
+	for i, p := range paras {
+		start := time.Duration(int64(duration) * int64(chars) / int64(total))
+		chars += counts[i]
+		end := time.Duration(int64(duration) * int64(chars) / int64(total))
+		cues = append(cues, TranscriptCue{Start: start, End: end, Text: p})
+	}
+	return cues
+}
+
+// refineCues 将相邻两段的分界移到附近静音的中点，附近没有静音时保持估算的时间
+// 只在两段中较短一段一半的范围内查找，避免把分界移到别的段落
+func refineCues(cues []TranscriptCue, silences []utils.Silence) {
+	if len(cues) == 0 || len(silences) == 0 {
+		return
+	}
+	// 开头的静音不计入第一段
+	if s := silences[0]; s.Start < 100*time.Millisecond && s.End < cues[0].End {
+		cues[0].Start = s.End
+	}
+	for i := 0; i+1 < len(cues); i++ {
+		boundary := cues[i].End
+		window := min(cues[i].End-cues[i].Start, cues[i+1].End-cues[i+1].Start) / 2
+		best, dist := time.Duration(-1), window
+		for _, s := range silences {
+			mid := (s.Start + s.End) / 2
+			if mid <= cues[i].Start || mid >= cues[i+1].End {
+				continue
+			}
+			if d := (mid - boundary).Abs(); d <= dist {
+				best, dist = mid, d
+			}
+		}
+		if best >= 0 {
+			cues[i].End, cues[i+1].Start = best, best
+		}
+	}
+}
+
+// transcriptLRC LRC 歌词，每段一行
+func transcriptLRC(title, author string, duration time.Duration, cues []TranscriptCue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[ti:%s]\n", title)
+	if author != "" {
+		fmt.Fprintf(&b, "[ar:%s]\n", author)
+	}
+	fmt.Fprintf(&b, "[length:%s]\n", strings.SplitN(lrcTime(duration), ".", 2)[0])
+	for _, c := range cues {
+		fmt.Fprintf(&b, "[%s]%s\n", lrcTime(c.Start), c.Text)
+	}
+	return b.String()
+}
+
+// transcriptSRT SRT 字幕
+func transcriptSRT(cues []TranscriptCue) string {
+	var b strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(c.Start), srtTime(c.End), c.Text)
+	}
+	return b.String()
+}
+
+// lrcTime mm:ss.xx，分钟可以超过 59
+func lrcTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// srtTime hh:mm:ss,mmm
+func srtTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yann0917/dedao-dl/services"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+func TestTranscriptCues(t *testing.T) {
+	doc := &services.Document{Blocks: []services.Block{
+		services.HeadingBlock{Text: "标题"},
+		services.ParagraphBlock{Runs: []services.Run{{Text: "第一段"}, {Text: "文字"}, {Type: runFootnote, Text: "注释"}}},
+		services.ImageBlock{URL: "a.png", Legend: "图片说明"},
+		services.ListBlock{Items: []services.ListItem{{Runs: []services.Run{{Text: "列表"}}, Children: &services.ListBlock{Items: []services.ListItem{{Runs: []services.Run{{Text: "子项"}}}}}}}},
+		services.EliteBlock{Text: "重点一\n\n重点二"},
+		services.UnknownBlock{Type: "video", Text: "视频暂不支持"},
+	}}
+	paras := transcriptParagraphs(doc)
+	want := []string{"标题", "第一段文字", "列表", "子项", "重点一", "重点二"}
+	if strings.Join(paras, "|") != strings.Join(want, "|") {
+		t.Fatalf("paragraphs = %q", paras)
+	}
+
+	cues := TranscriptCues([]string{"一二", "三四五六", "七八"}, 8*time.Second)
+	ends := []time.Duration{2 * time.Second, 6 * time.Second, 8 * time.Second}
+	for i, c := range cues {
+		if c.End != ends[i] || (i > 0 && c.Start != cues[i-1].End) {
+			t.Errorf("cue %d = %+v", i, c)
+		}
+	}
+
+	// 第一段分界附近有静音，第二段分界附近没有
+	refineCues(cues, []utils.Silence{
+		{Start: 0, End: 300 * time.Millisecond},
+		{Start: 2200 * time.Millisecond, End: 2600 * time.Millisecond},
+		{Start: 7900 * time.Millisecond, End: 8 * time.Second},
+	})
+	if cues[0].Start != 300*time.Millisecond || cues[0].End != 2400*time.Millisecond || cues[1].Start != 2400*time.Millisecond {
+		t.Errorf("refined = %+v", cues)
+	}
+	if cues[1].End != 6*time.Second {
+		t.Errorf("boundary without silence moved: %+v", cues[1])
+	}
+
+	lrc := transcriptLRC("标题", "作者", 75*time.Second+500*time.Millisecond, cues)
+	if !strings.Contains(lrc, "[length:01:15]\n") || !strings.Contains(lrc, "[00:02.40]三四五六\n") {
+		t.Errorf("lrc = %q", lrc)
+	}
+	srt := transcriptSRT(cues)
+	if !strings.HasPrefix(srt, "1\n00:00:00,300 --> 00:00:02,400\n一二\n\n2\n") {
+		t.Errorf("srt = %q", srt)
+	}
+	if got := srtTime(time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond); got != "01:02:03,004" {
+		t.Errorf("srtTime = %s", got)
+	}
+}
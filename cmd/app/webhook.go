@@ -0,0 +1,171 @@
+package app
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/config"
+)
+
+// 未配置 events 时推送的事件
+var defaultWebhookEvents = []string{
+	EventJobStarted,
+	EventJobFinished,
+	EventJobFailed,
+	EventItemFailed,
+}
+
+// Webhooks 将下载事件以 JSON POST 推送到配置的地址
+// 请求头 X-Dedao-Event 为事件类型，配置了 secret 时 X-Dedao-Signature 为 sha256=<请求体的 HMAC-SHA256>
+type Webhooks struct {
+	Hooks   []config.WebhookConfig
+	Client  *http.Client
+	Retries int           // 推送失败后的重试次数
+	Backoff time.Duration // 首次重试的等待时间，之后每次翻倍
+
+	wg sync.WaitGroup
+}
+
+// NewWebhooks 根据配置创建 webhook 推送
+func NewWebhooks(hooks []config.WebhookConfig) *Webhooks {
+	return &Webhooks{
+		Hooks:   hooks,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Retries: 5,
+		Backoff: 2 * time.Second,
+	}
+}
+
+// Start 订阅下载事件并开始推送，stop 取消订阅并等待队列中的事件推送完成
+// 每个地址按事件发生顺序逐个推送，推送过慢时丢弃新的事件，不会阻塞下载
+func (w *Webhooks) Start() (stop func()) {
+	var stops []func()
+	for _, hook := range w.Hooks {
+		if hook.URL == "" {
+			continue
+		}
+		events := hook.Events
+		if len(events) == 0 {
+			events = defaultWebhookEvents
+		}
+		accept := make(map[string]bool, len(events))
+		for _, e := range events {
+			accept[e] = true
+		}
+
+		queue := make(chan Event, 256)
+		w.wg.Add(1)
+		go func(hook config.WebhookConfig) {
+			defer w.wg.Done()
+			for e := range queue {
+				if err := w.deliver(hook, e); err != nil {
+					fmt.Printf("webhook 推送失败 %s: %v\n", hook.URL, err)
+				}
+			}
+		}(hook)
+
+		unsubscribe := Subscribe(func(e Event) {
+			if !accept[e.Type] {
+				return
+			}
+			select {
+			case queue <- e:
+			default:
+				fmt.Printf("webhook 推送过慢，丢弃事件 %s\n", e.Type)
+			}
+		})
+		// 取消订阅后再关闭队列，避免向已关闭的队列发送
+		stops = append(stops, func() {
+			unsubscribe()
+			close(queue)
+		})
+	}
+	return func() {
+		for _, fn := range stops {
+			fn()
+		}
+		w.wg.Wait()
+	}
+}
+
+// deliver 推送单个事件，网络错误、5xx 和 429 时按指数退避重试
+func (w *Webhooks) deliver(hook config.WebhookConfig, e Event) error {
+	body, err := jsoniter.Marshal(e)
+	if err != nil {
+		return err
+	}
+	backoff := w.Backoff
+	for i := 0; ; i++ {
+		retry, err := w.post(hook, e.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || i >= w.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *Webhooks) post(hook config.WebhookConfig, eventType string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dedao-dl-webhook")
+	req.Header.Set("X-Dedao-Event", eventType)
+	if hook.Secret != "" {
+		req.Header.Set("X-Dedao-Signature", "sha256="+WebhookSignature(hook.Secret, body))
+	}
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("HTTP %d", resp.StatusCode)
+}
+
+// WebhookSignature 请求体的 HMAC-SHA256 签名，十六进制编码
+func WebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	webhooksMu      sync.Mutex
+	webhooksRunning bool
+)
+
+// startWebhooks 按 config.json 中的 Webhooks 配置开始推送，未配置时不推送
+// api、daemon 已启动推送时，其中的每次下载不再重复推送
+func startWebhooks() (stop func()) {
+	hooks := config.Instance.Webhooks
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+	if len(hooks) == 0 || webhooksRunning {
+		return func() {}
+	}
+	webhooksRunning = true
+	fmt.Printf("已启用 %d 个 webhook\n", len(hooks))
+	stopHooks := NewWebhooks(hooks).Start()
+	return func() {
+		stopHooks()
+		webhooksMu.Lock()
+		webhooksRunning = false
+		webhooksMu.Unlock()
+	}
+}
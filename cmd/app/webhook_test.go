@@ -0,0 +1,91 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/config"
+)
+
+func TestWebhooks(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		received []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get("X-Dedao-Signature"), "sha256="+WebhookSignature("secret", body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// 第一次推送失败，验证重试
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var e Event
+		if err := jsoniter.Unmarshal(body, &e); err != nil {
+			t.Errorf("unmarshal: %v", err)
+		}
+		if got := r.Header.Get("X-Dedao-Event"); got != e.Type {
+			t.Errorf("X-Dedao-Event = %q, want %q", got, e.Type)
+		}
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	w := NewWebhooks([]config.WebhookConfig{{URL: srv.URL, Secret: "secret"}})
+	w.Backoff = time.Millisecond
+	stop := w.Start()
+
+	Publish(Event{Type: EventJobStarted, JobID: "1", Kind: KindCourse, ID: 1, Title: "课程"})
+	Publish(Event{Type: EventItemFinished, JobID: "1", Kind: KindCourse, Item: "第一讲"})
+	Publish(Event{Type: EventItemFailed, JobID: "1", Kind: KindCourse, Item: "第二讲", Error: "timeout"})
+	Publish(Event{Type: EventJobFinished, JobID: "1", Kind: KindCourse, ID: 1, Title: "课程", Path: "output/课程"})
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{EventJobStarted, EventItemFailed, EventJobFinished}
+	if len(received) != len(want) {
+		t.Fatalf("received %d events, want %d", len(received), len(want))
+	}
+	for i, e := range received {
+		if e.Type != want[i] {
+			t.Errorf("event %d = %s, want %s", i, e.Type, want[i])
+		}
+	}
+	if received[1].Error != "timeout" || received[2].Path != "output/课程" {
+		t.Errorf("payload = %+v", received)
+	}
+	if attempts != len(want)+1 {
+		t.Errorf("attempts = %d, want %d", attempts, len(want)+1)
+	}
+}
+
+func TestWebhooksNoRetryOnClientError(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	w := NewWebhooks([]config.WebhookConfig{{URL: srv.URL, Events: []string{EventItemFinished}}})
+	w.Backoff = time.Millisecond
+	if err := w.deliver(w.Hooks[0], Event{Type: EventItemFinished}); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"os"
 	"strconv"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/yann0917/dedao-dl/cmd/app"
@@ -77,9 +76,11 @@ func articleDetail(id, aid int) (err error) {
 	out := os.Stdout
 	table := tablewriter.NewWriter(out)
 
-	var content []services.Content
-	err = jsoniter.UnmarshalFromString(detail.Content, &content)
-	_, _ = fmt.Fprint(out, app.ContentsToMarkdown(content))
+	doc, err := services.ParseDocument(detail.Content)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprint(out, app.DocumentToMarkdown(doc))
 	_, _ = fmt.Fprintln(out)
 	table.Render()
 	return
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/cmd/app"
+)
+
+var commentsLimit, commentsSort, commentsFormat = "all", "", "md"
+
+var commentsCmd = &cobra.Command{
+	Use:   "comments <课程ID> [文章ID]",
+	Short: "导出课程留言",
+	Long: `使用 dedao-dl comments 导出课程每篇文章的留言，不包含文章内容
+留言保存在课程目录的 Comments 下，每篇文章一个文件，包含作者、点赞数、时间和讲师回复；留言会更新，已存在的文件也重新生成
+--comments 每篇文章的留言数量, all 为全部, 默认 all
+--comment-sort 留言排序, like:按点赞数, create:按时间, 默认 like
+-t 导出格式, md 或 json, 默认 md
+-o 文件名前缀加上序号`,
+	Example: "dedao-dl comments 123\ndedao-dl comments 123 --comments 100 --comment-sort create -t json",
+	Args:    cobra.RangeArgs(1, 2),
+	PreRunE: AuthFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.New("课程ID错误")
+		}
+		aid := 0
+		if len(args) > 1 {
+			if aid, err = strconv.Atoi(args[1]); err != nil {
+				return errors.New("文章ID错误")
+			}
+		}
+		limit, err := app.ParseCommentLimit(commentsLimit)
+		if err != nil {
+			return err
+		}
+		sort, err := app.ParseCommentSort(commentsSort)
+		if err != nil {
+			return err
+		}
+		if commentsFormat != "md" && commentsFormat != "json" {
+			return fmt.Errorf("导出格式错误: %s，可选 md、json", commentsFormat)
+		}
+		return app.Download(&app.CommentsExport{
+			ID:      id,
+			AID:     aid,
+			Limit:   limit,
+			Sort:    sort,
+			Format:  commentsFormat,
+			IsOrder: courseOrder,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(commentsCmd)
+	commentsCmd.Flags().StringVar(&commentsLimit, "comments", "all", "每篇文章的留言数量, all 或数字")
+	commentsCmd.Flags().StringVar(&commentsSort, "comment-sort", "", "留言排序, like 或 create, 默认 like")
+	commentsCmd.Flags().StringVarP(&commentsFormat, "type", "t", "md", "导出格式, md 或 json")
+	commentsCmd.Flags().BoolVarP(&courseOrder, "order", "o", false, "文件名前缀加上序号, 如 00x.")
+}
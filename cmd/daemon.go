@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/cmd/app"
+	"github.com/yann0917/dedao-dl/config"
+)
+
+var (
+	daemonNow      bool
+	daemonSchedule string
+	daemonMetrics  string
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "定时同步已购的课程、听书和电子书",
+	Long: `使用 dedao-dl daemon 常驻运行，按计划下载新发布的课程文章、听书书架和电子书架中新加入的书
+同步计划、静默时段、下载格式等在 config.json 的 Daemon 字段中配置
+已同步的内容会记录下来，不会重复下载`,
+	Example: "dedao-dl daemon --now\ndedao-dl daemon --schedule \"@every 6h\"",
+	Args:    cobra.NoArgs,
+	PreRunE: AuthFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d := &app.Daemon{
+			Config:  config.Instance.Daemon,
+			RunNow:  daemonNow,
+			Metrics: daemonMetrics,
+		}
+		if daemonSchedule != "" {
+			d.Config.Schedule = daemonSchedule
+		}
+		return d.Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().BoolVar(&daemonNow, "now", false, "启动时立即同步一次")
+	daemonCmd.Flags().StringVar(&daemonSchedule, "schedule", "", "同步计划, cron 表达式, 覆盖配置文件")
+	daemonCmd.Flags().StringVar(&daemonMetrics, "metrics", "", "Prometheus 指标监听地址, 如 127.0.0.1:9090, 为空时不启用")
+}
@@ -10,15 +10,54 @@ import (
 )
 
 var downloadType, courseMerge, courseComment, courseOrder = 1, false, false, false
+var offlineAssets, convertImages = false, ""
+var pdfEngine, pdfProfile = "", ""
+var mergeChapter, embedFonts = false, false
+var transcript, transcriptSilence = false, false
+var commentLimit, commentSort = "", ""
+var coursePoints = false
+
+// courseFormat -t 参数，支持数字和格式名称，如 -t 3、-t obsidian、-t epub、-t html
+type courseFormat struct {
+	p *int
+}
+
+func (f courseFormat) String() string {
+	return strconv.Itoa(*f.p)
+}
+
+func (f courseFormat) Set(s string) error {
+	t, err := app.ParseCourseFormat(s)
+	if err != nil {
+		return err
+	}
+	*f.p = t
+	return nil
+}
+
+func (f courseFormat) Type() string {
+	return "format"
+}
 
 var downloadCmd = &cobra.Command{
 	Use:   "dl",
 	Short: "下载已购买课程，并转换成 PDF & 音频",
 	Long: `使用 dedao-dl dl 下载已购买课程, 并转换成 PDF & 音频 & markdown
--t 指定下载格式, 1:mp3, 2:PDF文档, 3:markdown文档, 默认 mp3
--m 是否合并课程文稿(仅支持markdown), 默认不合并
--c 是否下载课程热门留言(仅支持markdown), 默认不下载`,
-	Example: "dedao-dl dl 123 -t 1 -m",
+-t 指定下载格式, 1:mp3, 2:PDF文档, 3:markdown文档, 4 或 obsidian:Obsidian 笔记, 5 或 epub:EPUB, 6 或 html:单文件 HTML, 7 或 docx:Word 文档, 8 或 json:JSON, 默认 mp3
+-m 是否合并课程文稿(支持markdown、PDF、Word和JSON), 默认不合并, 合并的 PDF 包含封面、目录和书签, 合并的 Word 文档包含封面和目录, 合并的 JSON 为每行一篇文章的 articles.jsonl
+--merge-chapter 合并 PDF 时每章生成一个文件
+-c 是否下载课程留言(支持markdown、PDF、Obsidian、EPUB、HTML、Word和JSON), 默认不下载
+--comments 每篇文章的留言数量, all 为全部, 默认 20, 指定时自动开启 -c
+--comment-sort 留言排序, like:按点赞数, create:按时间, 默认 like
+--points 在文章开头加入重点摘要(支持markdown、PDF、Obsidian、EPUB、HTML、Word和JSON), 没有重点时使用文中的划重点
+--offline-assets 下载文章图片到 assets 目录并改写图片地址(支持PDF、markdown、Obsidian和EPUB)
+--convert-images 配合 --offline-assets 或 -t html 将 webp、avif 图片转换为 jpg 或 png
+--pdf-engine PDF 生成方式, auto:有中文字体时使用内置引擎, native:内置引擎, wkhtmltopdf, 默认使用配置
+--pdf-profile PDF 打印配置, a4、a5、letter、6inch、large-print 或 config.json 中自定义的名称, 默认使用配置
+--embed-fonts 生成 EPUB 时嵌入文中使用的字体, 字体通过 dedao-dl fonts 管理
+--transcript 下载 mp3 时在同目录生成 LRC 和 SRT 字幕, 按字数估算每段文稿的时间
+--transcript-silence 配合 --transcript 使用 ffmpeg 检测音频中的静音校准字幕时间`,
+	Example: "dedao-dl dl 123 -t 1 -m\ndedao-dl dl 123 -t obsidian -o\ndedao-dl dl 123 -t epub -c\ndedao-dl dl 123 -t html\ndedao-dl dl 123 -t docx -m\ndedao-dl dl 123 -t json -c\ndedao-dl dl 123 -t 1 --transcript",
 	PreRunE: AuthFunc,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.Atoi(args[0])
@@ -33,13 +72,44 @@ var downloadCmd = &cobra.Command{
 			}
 		}
 
+		convert, err := app.ImageFormat(convertImages)
+		if err != nil {
+			return err
+		}
+		engine, err := app.ParsePdfEngine(pdfEngine)
+		if err != nil {
+			return err
+		}
+		limit, err := app.ParseCommentLimit(commentLimit)
+		if err != nil {
+			return err
+		}
+		sort, err := app.ParseCommentSort(commentSort)
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("comments") {
+			courseComment = true
+		}
+
 		d := &app.CourseDownload{
-			DownloadType: downloadType,
-			ID:           id,
-			AID:          aid,
-			IsMerge:      courseMerge,
-			IsComment:    courseComment,
-			IsOrder:      courseOrder,
+			DownloadType:      downloadType,
+			ID:                id,
+			AID:               aid,
+			IsMerge:           courseMerge,
+			MergeChapter:      mergeChapter,
+			IsComment:         courseComment,
+			CommentLimit:      limit,
+			CommentSort:       sort,
+			IsOrder:           courseOrder,
+			OfflineAssets:     offlineAssets,
+			ConvertImages:     convert,
+			PdfEngine:         engine,
+			PdfProfile:        pdfProfile,
+			EmbedFonts:        embedFonts,
+			Transcript:        transcript,
+			TranscriptSilence: transcriptSilence,
+			Points:            coursePoints,
 		}
 		err = app.Download(d)
 
@@ -51,8 +121,14 @@ var dlOdobCmd = &cobra.Command{
 	Use:   "dlo",
 	Short: "下载每天听本书音频 & 文稿",
 	Long: `使用 dedao-dl dlo 下载每天听本书音频, 并转换成 PDF & 音频 & markdown
--t 指定下载格式, 1:mp3, 2:PDF文档, 3:markdown文档, 默认 mp3`,
-	Example: "dedao-dl dlo 123 -t 1",
+-t 指定下载格式, 1:mp3, 2:PDF文档, 3:markdown文档, 4 或 obsidian:Obsidian 文献笔记, 5 或 epub:EPUB, 6 或 html:单文件 HTML, 7 或 docx:Word 文档, 8 或 json:JSON, 默认 mp3
+--offline-assets 下载文稿图片到 assets 目录并改写图片地址(支持PDF、markdown、Obsidian和EPUB)
+--pdf-engine PDF 生成方式, auto:有中文字体时使用内置引擎, native:内置引擎, wkhtmltopdf, 默认使用配置
+--pdf-profile PDF 打印配置, a4、a5、letter、6inch、large-print 或 config.json 中自定义的名称, 默认使用配置
+--embed-fonts 生成 EPUB 时嵌入文中使用的字体, 字体通过 dedao-dl fonts 管理
+--transcript 下载 mp3 时在同目录生成 LRC 和 SRT 字幕, 按字数估算每段文稿的时间
+--transcript-silence 配合 --transcript 使用 ffmpeg 检测音频中的静音校准字幕时间`,
+	Example: "dedao-dl dlo 123 -t 1\ndedao-dl dlo 123 -t 1 --transcript --transcript-silence",
 	PreRunE: AuthFunc,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
@@ -63,9 +139,24 @@ var dlOdobCmd = &cobra.Command{
 		if len(args) > 1 {
 			return errors.New("参数错误")
 		}
+		convert, err := app.ImageFormat(convertImages)
+		if err != nil {
+			return err
+		}
+		engine, err := app.ParsePdfEngine(pdfEngine)
+		if err != nil {
+			return err
+		}
 		d := &app.OdobDownload{
-			DownloadType: downloadType,
-			ID:           id,
+			DownloadType:      downloadType,
+			ID:                id,
+			OfflineAssets:     offlineAssets,
+			ConvertImages:     convert,
+			PdfEngine:         engine,
+			PdfProfile:        pdfProfile,
+			EmbedFonts:        embedFonts,
+			Transcript:        transcript,
+			TranscriptSilence: transcriptSilence,
 		}
 		err = app.Download(d)
 		return err
@@ -76,7 +167,9 @@ var dlEbookCmd = &cobra.Command{
 	Use:   "dle",
 	Short: "下载电子书",
 	Long: `使用 dedao-dl dle 下载电子书
--t 指定下载格式, 1:html, 2:PDF文档, 3:epub, 默认 html`,
+-t 指定下载格式, 1:html, 2:PDF文档, 3:epub, 4:Word 文档, 5:JSON, 默认 html
+--pdf-profile PDF 打印配置, a4、a5、letter、6inch、large-print 或 config.json 中自定义的名称, 默认使用配置
+--embed-fonts 生成 EPUB 时嵌入文中使用的字体, 字体通过 dedao-dl fonts 管理`,
 	Example: "dedao-dl dle 123 -t 1",
 	PreRunE: AuthFunc,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -92,12 +185,16 @@ var dlEbookCmd = &cobra.Command{
 			d = &app.EBookDownloadByEnID{
 				DownloadType: downloadType,
 				EnID:         args[0],
+				PdfProfile:   pdfProfile,
+				EmbedFonts:   embedFonts,
 			}
 		} else {
 			// args[0] is an integer ID
 			d = &app.EBookDownloadByID{
 				DownloadType: downloadType,
 				ID:           id,
+				PdfProfile:   pdfProfile,
+				EmbedFonts:   embedFonts,
 			}
 		}
 		err = app.Download(d)
@@ -109,11 +206,32 @@ func init() {
 	rootCmd.AddCommand(downloadCmd)
 	rootCmd.AddCommand(dlOdobCmd)
 	rootCmd.AddCommand(dlEbookCmd)
-	downloadCmd.PersistentFlags().IntVarP(&downloadType, "downloadType", "t", 1, "下载格式, 1:mp3, 2:PDF文档, 3:markdown文档")
+	downloadCmd.PersistentFlags().VarP(courseFormat{&downloadType}, "downloadType", "t", "下载格式, 1:mp3, 2:PDF文档, 3:markdown文档, 4 或 obsidian:Obsidian 笔记, 5 或 epub:EPUB, 6 或 html:单文件 HTML, 7 或 docx:Word 文档, 8 或 json:JSON")
 	downloadCmd.PersistentFlags().BoolVarP(&courseMerge, "merge", "m", false, "是否合并课程章节")
-	downloadCmd.PersistentFlags().BoolVarP(&courseComment, "comment", "c", false, "是否下载课程热门留言, 仅针对 markdown 文档")
+	downloadCmd.PersistentFlags().BoolVar(&mergeChapter, "merge-chapter", false, "合并 PDF 时每章生成一个文件")
+	downloadCmd.PersistentFlags().BoolVarP(&courseComment, "comment", "c", false, "是否下载课程留言")
+	downloadCmd.PersistentFlags().StringVar(&commentLimit, "comments", "", "每篇文章的留言数量, all 或数字, 默认 20")
+	downloadCmd.PersistentFlags().StringVar(&commentSort, "comment-sort", "", "留言排序, like 或 create, 默认 like")
 	downloadCmd.PersistentFlags().BoolVarP(&courseOrder, "order", "o", false, "是否按顺序展示, 如果为true, 则文件名前缀会加上序号, 如 00x.")
 
-	dlOdobCmd.PersistentFlags().IntVarP(&downloadType, "downloadType", "t", 1, "下载格式, 1:mp3, 2:PDF文档, 3:markdown文档")
-	dlEbookCmd.PersistentFlags().IntVarP(&downloadType, "downloadType", "t", 1, "下载格式, 1:html, 2:PDF文档, 3:epub")
+	downloadCmd.PersistentFlags().BoolVar(&offlineAssets, "offline-assets", false, "下载文章图片到 assets 目录并改写图片地址, 针对 PDF、markdown、Obsidian 和 EPUB")
+	downloadCmd.PersistentFlags().StringVar(&convertImages, "convert-images", "", "将 webp、avif 图片转换为 jpg 或 png, 需要 ffmpeg")
+	downloadCmd.PersistentFlags().StringVar(&pdfEngine, "pdf-engine", "", "PDF 生成方式, auto、native 或 wkhtmltopdf, 默认使用配置")
+	downloadCmd.PersistentFlags().StringVar(&pdfProfile, "pdf-profile", "", "PDF 打印配置, 如 a4、a5、letter、6inch、large-print, 默认使用配置")
+	downloadCmd.PersistentFlags().BoolVar(&embedFonts, "embed-fonts", false, "生成 EPUB 时嵌入文中使用的字体")
+	downloadCmd.PersistentFlags().BoolVar(&transcript, "transcript", false, "下载 mp3 时生成 LRC 和 SRT 字幕")
+	downloadCmd.PersistentFlags().BoolVar(&transcriptSilence, "transcript-silence", false, "检测静音校准字幕时间, 需要 ffmpeg")
+	downloadCmd.PersistentFlags().BoolVar(&coursePoints, "points", false, "在文章开头加入重点摘要")
+
+	dlOdobCmd.PersistentFlags().VarP(courseFormat{&downloadType}, "downloadType", "t", "下载格式, 1:mp3, 2:PDF文档, 3:markdown文档, 4 或 obsidian:Obsidian 文献笔记, 5 或 epub:EPUB, 6 或 html:单文件 HTML, 7 或 docx:Word 文档, 8 或 json:JSON")
+	dlOdobCmd.PersistentFlags().BoolVar(&offlineAssets, "offline-assets", false, "下载文稿图片到 assets 目录并改写图片地址, 针对 PDF、markdown、Obsidian 和 EPUB")
+	dlOdobCmd.PersistentFlags().StringVar(&convertImages, "convert-images", "", "将 webp、avif 图片转换为 jpg 或 png, 需要 ffmpeg")
+	dlOdobCmd.PersistentFlags().StringVar(&pdfEngine, "pdf-engine", "", "PDF 生成方式, auto、native 或 wkhtmltopdf, 默认使用配置")
+	dlOdobCmd.PersistentFlags().StringVar(&pdfProfile, "pdf-profile", "", "PDF 打印配置, 如 a4、a5、letter、6inch、large-print, 默认使用配置")
+	dlOdobCmd.PersistentFlags().BoolVar(&embedFonts, "embed-fonts", false, "生成 EPUB 时嵌入文中使用的字体")
+	dlOdobCmd.PersistentFlags().BoolVar(&transcript, "transcript", false, "下载 mp3 时生成 LRC 和 SRT 字幕")
+	dlOdobCmd.PersistentFlags().BoolVar(&transcriptSilence, "transcript-silence", false, "检测静音校准字幕时间, 需要 ffmpeg")
+	dlEbookCmd.PersistentFlags().IntVarP(&downloadType, "downloadType", "t", 1, "下载格式, 1:html, 2:PDF文档, 3:epub, 4:Word 文档, 5:JSON")
+	dlEbookCmd.PersistentFlags().StringVar(&pdfProfile, "pdf-profile", "", "PDF 打印配置, 如 a4、a5、letter、6inch、large-print, 默认使用配置")
+	dlEbookCmd.PersistentFlags().BoolVar(&embedFonts, "embed-fonts", false, "生成 EPUB 时嵌入文中使用的字体")
 }
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/cmd/app"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "导出已下载的内容",
+	Long:  `使用 dedao-dl export 将已下载的课程、听书和电子书导出为其他形式`,
+}
+
+var exportSiteCmd = &cobra.Command{
+	Use:   "site <dir>",
+	Short: "导出为静态网站",
+	Long: `使用 dedao-dl export site <dir> 将已下载的课程、听书文稿和电子书导出为静态网站
+网站包含目录导航、上一篇/下一篇链接和全文搜索，所有链接均为相对路径，可直接在浏览器中打开或部署到任意静态服务器
+课程文章页来自 markdown 文稿，请先使用 dedao-dl dl <id> -t 3 下载`,
+	Example: "dedao-dl export site ./site",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return app.ExportSite(args[0])
+	},
+}
+
+var exportIndexCmd = &cobra.Command{
+	Use:   "index [课程ID]",
+	Short: "生成课程首页",
+	Long: `使用 dedao-dl export index 为已下载的课程重新生成首页 index.md 和 index.html
+首页包含课程简介、亮点、讲师介绍、更新状态、文章数量、发刊词、按章节排列并链接到已导出文件的目录，以及课程封面和讲师头像
+下载课程时会自动更新首页，不指定课程ID时生成所有已下载的课程`,
+	Example: "dedao-dl export index\ndedao-dl export index 123",
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := 0
+		if len(args) > 0 {
+			var err error
+			if id, err = strconv.Atoi(args[0]); err != nil {
+				return errors.New("课程ID错误")
+			}
+		}
+		return app.ExportCourseIndex(id)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportSiteCmd)
+	exportCmd.AddCommand(exportIndexCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/config"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+var fontsForce = false
+
+var fontsCmd = &cobra.Command{
+	Use:   "fonts",
+	Short: "管理生成 PDF、HTML 和 EPUB 使用的字体",
+	Long: `使用 dedao-dl fonts 查看字体和缓存状态
+文稿样式引用的得到字体会在首次使用时下载到缓存目录并校验，之后生成文件时引用本地字体，离线也能使用`,
+	Example: "dedao-dl fonts\ndedao-dl fonts download\ndedao-dl fonts add \"FZKai-Z03\" ./fzkai.ttf",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fontsList()
+		return nil
+	},
+}
+
+var fontsDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "下载并校验字体",
+	Long: `使用 dedao-dl fonts download 下载缺失或校验失败的字体
+-f 重新下载全部字体`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := utils.Fonts.Download(fontsForce)
+		fontsList()
+		return err
+	},
+}
+
+var fontsAddCmd = &cobra.Command{
+	Use:   "add <family> <file>",
+	Short: "注册字体",
+	Long: `使用 dedao-dl fonts add <family> <file> 注册本地字体, 支持 ttf、otf、ttc、woff 和 woff2
+生成文件时 font-family 为 family 的文字使用该字体, 与得到字体同名时替换得到字体`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Instance.RegisterFont(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("已注册字体：【\033[37;1m%s\033[0m】\n", args[0])
+		return nil
+	},
+}
+
+var fontsRemoveCmd = &cobra.Command{
+	Use:   "remove <family>",
+	Short: "删除注册的字体",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ok, err := config.Instance.UnregisterFont(args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("字体未注册: " + args[0])
+		}
+		fmt.Printf("已删除字体：【\033[37;1m%s\033[0m】\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fontsCmd)
+	fontsCmd.AddCommand(fontsDownloadCmd, fontsAddCmd, fontsRemoveCmd)
+	fontsDownloadCmd.Flags().BoolVarP(&fontsForce, "force", "f", false, "重新下载全部字体")
+}
+
+func fontsList() {
+	fmt.Printf("缓存目录：%s\n", utils.Fonts.Dir())
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header([]string{"#", "字体", "来源", "状态"})
+	for i, f := range utils.Fonts.List() {
+		source, status := "得到", "未下载"
+		if f.File != "" {
+			source = "注册"
+		}
+		switch {
+		case f.Err != nil:
+			status = f.Err.Error()
+		case f.Path != "":
+			status = f.Path
+		case f.URL == "" && f.File == "":
+			status = "使用系统字体"
+		}
+		table.Append([]string{strconv.Itoa(i), f.Family, source, status})
+	}
+	table.Render()
+}
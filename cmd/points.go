@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/cmd/app"
+)
+
+var pointsFormat = "md"
+
+var pointsCmd = &cobra.Command{
+	Use:   "points <课程ID>",
+	Short: "导出课程重点摘要",
+	Long: `使用 dedao-dl points 按章节和文章顺序将课程每篇文章的重点合成一个重点摘要
+重点来自文章的划重点接口，没有重点时使用文中的划重点，保存在课程目录下，已存在的文件也重新生成
+-t 导出格式, md、pdf 或 epub, 默认 md
+--pdf-engine PDF 生成方式, auto:有中文字体时使用内置引擎, native:内置引擎, wkhtmltopdf, 默认使用配置
+--pdf-profile PDF 打印配置, a4、a5、letter、6inch、large-print 或 config.json 中自定义的名称, 默认使用配置
+--embed-fonts 生成 EPUB 时嵌入文中使用的字体`,
+	Example: "dedao-dl points 123\ndedao-dl points 123 -t pdf --pdf-profile a5\ndedao-dl points 123 -t epub",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: AuthFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.New("课程ID错误")
+		}
+		format, err := app.ParsePointsFormat(pointsFormat)
+		if err != nil {
+			return err
+		}
+		engine, err := app.ParsePdfEngine(pdfEngine)
+		if err != nil {
+			return err
+		}
+		return app.Download(&app.PointsExport{
+			ID:         id,
+			Format:     format,
+			PdfEngine:  engine,
+			PdfProfile: pdfProfile,
+			EmbedFonts: embedFonts,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pointsCmd)
+	pointsCmd.Flags().StringVarP(&pointsFormat, "type", "t", "md", "导出格式, md、pdf 或 epub")
+	pointsCmd.Flags().StringVar(&pdfEngine, "pdf-engine", "", "PDF 生成方式, auto、native 或 wkhtmltopdf, 默认使用配置")
+	pointsCmd.Flags().StringVar(&pdfProfile, "pdf-profile", "", "PDF 打印配置, 如 a4、a5、letter、6inch、large-print, 默认使用配置")
+	pointsCmd.Flags().BoolVar(&embedFonts, "embed-fonts", false, "生成 EPUB 时嵌入文中使用的字体")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yann0917/dedao-dl/cmd/app"
+)
+
+var serveListen, serveBaseURL, serveUser, servePassword string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动本地服务，浏览已下载的内容",
+	Long:  `使用 dedao-dl serve 启动本地服务，通过局域网访问已下载的内容`,
+}
+
+var servePodcastCmd = &cobra.Command{
+	Use:   "podcast",
+	Short: "启动本地播客订阅服务",
+	Long: `使用 dedao-dl serve podcast 将已下载的课程音频和每天听本书音频发布为播客订阅
+每门课程生成一个订阅源，听书书架生成一个订阅源，访问首页可查看所有订阅地址`,
+	Example: "dedao-dl serve podcast -l 0.0.0.0:8080 -u user -p password",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := &app.PodcastServer{
+			Listen:   serveListen,
+			BaseURL:  serveBaseURL,
+			Username: serveUser,
+			Password: servePassword,
+		}
+		return s.Run()
+	},
+}
+
+var serveOpdsCmd = &cobra.Command{
+	Use:   "opds",
+	Short: "启动本地 OPDS 电子书目录服务",
+	Long: `使用 dedao-dl serve opds 将已下载的电子书发布为 OPDS 1.2 目录
+KOReader、Moon+ Reader 等阅读器添加 http://<ip>:<port>/opds 即可浏览、搜索和下载电子书`,
+	Example: "dedao-dl serve opds -l 0.0.0.0:8080 -u user -p password",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := &app.OpdsServer{
+			Listen:   serveListen,
+			BaseURL:  serveBaseURL,
+			Username: serveUser,
+			Password: servePassword,
+		}
+		return s.Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.AddCommand(servePodcastCmd)
+	serveCmd.AddCommand(serveOpdsCmd)
+
+	serveCmd.PersistentFlags().StringVarP(&serveListen, "listen", "l", "0.0.0.0:8080", "监听地址")
+	serveCmd.PersistentFlags().StringVar(&serveBaseURL, "base-url", "", "对外访问地址, 如 http://192.168.1.2:8080, 默认使用请求的 Host")
+	serveCmd.PersistentFlags().StringVarP(&serveUser, "user", "u", "", "basic auth 用户名, 为空时不校验")
+	serveCmd.PersistentFlags().StringVarP(&servePassword, "password", "p", "", "basic auth 密码")
+}
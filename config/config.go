@@ -13,6 +13,8 @@ import (
 	"errors"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/mitchellh/mapstructure"
+	"github.com/yann0917/dedao-dl/metrics"
 	"github.com/yann0917/dedao-dl/services"
 	"github.com/yann0917/dedao-dl/utils"
 )
@@ -40,6 +42,13 @@ type ConfigsData struct {
 	ActiveUID      string
 	DownloadPath   string
 	Users          DedaoUsers
+	Daemon         DaemonConfig
+	Webhooks       []WebhookConfig
+	Hooks          []HookConfig
+	Markdown       MarkdownOptions
+	TemplateDir    string // 自定义模板目录，为空时使用配置目录下的 templates
+	PDF            PdfConfig
+	Fonts          FontsConfig
 	activeUser     *Dedao
 	configFilePath string
 	configFile     *os.File
@@ -52,6 +61,13 @@ type configJSONExport struct {
 	ActiveUID    string
 	Users        DedaoUsers
 	DownloadPath string
+	Daemon       DaemonConfig
+	Webhooks     []WebhookConfig
+	Hooks        []HookConfig
+	Markdown     MarkdownOptions
+	TemplateDir  string
+	PDF          PdfConfig
+	Fonts        FontsConfig
 }
 
 // Init 初始化配置
@@ -134,6 +150,13 @@ func (c *ConfigsData) Save() error {
 		ActiveUID:    c.ActiveUID,
 		Users:        c.Users,
 		DownloadPath: c.DownloadPath,
+		Daemon:       c.Daemon,
+		Webhooks:     c.Webhooks,
+		Hooks:        c.Hooks,
+		Markdown:     c.Markdown,
+		TemplateDir:  c.TemplateDir,
+		PDF:          c.PDF,
+		Fonts:        c.Fonts,
 	}
 
 	data, err := jsoniter.MarshalIndent(conf, "", " ")
@@ -189,12 +212,20 @@ func (c *ConfigsData) loadConfigFromFile() error {
 
 	// 从配置文件中加载配置
 	decoder := jsoniter.NewDecoder(c.configFile)
-	var conf configJSONExport
+	conf := configJSONExport{Daemon: DefaultDaemonConfig()}
 	_ = decoder.Decode(&conf)
 
 	c.ActiveUID = conf.ActiveUID
 	c.Users = conf.Users
 	c.DownloadPath = conf.DownloadPath
+	c.Daemon = conf.Daemon
+	c.Webhooks = conf.Webhooks
+	c.Hooks = conf.Hooks
+	c.Markdown = conf.Markdown
+	c.TemplateDir = conf.TemplateDir
+	c.PDF = conf.PDF
+	c.Fonts = conf.Fonts
+	c.applyFonts()
 	return nil
 }
 
@@ -227,6 +258,7 @@ func (c *ConfigsData) lazyOpenConfigFile() (err error) {
 func New(configFilePath string) *ConfigsData {
 	c := &ConfigsData{
 		configFilePath: configFilePath,
+		Daemon:         DefaultDaemonConfig(),
 	}
 
 	return c
@@ -252,6 +284,31 @@ func (c *ConfigsData) ActiveUserService() *services.Service {
 	return c.service
 }
 
+// RefreshSession 请求用户信息保持登录状态，服务端刷新的 cookie 会保存到配置文件
+func (c *ConfigsData) RefreshSession() error {
+	if c.ActiveUID == "" || c.activeUser == nil {
+		return errors.New("未登陆")
+	}
+	svc := c.ActiveUserService()
+	if _, err := svc.User(); err != nil {
+		return err
+	}
+	cookies := svc.SessionCookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	co := c.activeUser.CookieOptions
+	if err := mapstructure.Decode(cookies, &co); err != nil {
+		return err
+	}
+	co.CookieStr = services.MergeCookieStr(co.CookieStr, cookies)
+	if co == c.activeUser.CookieOptions {
+		return nil
+	}
+	c.activeUser.CookieOptions = co
+	return c.Save()
+}
+
 // SetUser set user
 func (c *ConfigsData) SetUser(u *Dedao) (*Dedao, error) {
 	ser := services.NewService(&u.CookieOptions)
@@ -330,6 +387,7 @@ func (c *ConfigsData) GetCourseCache(category string, id int) *services.CourseV2
 	key := utils.FormatKey(category, id)
 	var course services.CourseV2
 	err := c.badgerDB.Get(key, &course)
+	metrics.ObserveCache("course", err == nil)
 	if err != nil {
 		// 如果获取失败，返回空对象
 		return nil
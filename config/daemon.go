@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const syncKeyPrefix = "sync:"
+
+// DaemonConfig 定时同步配置，保存在 config.json 的 Daemon 字段
+type DaemonConfig struct {
+	Schedule      string `json:"schedule"`       // cron 表达式，如 "0 3 * * *"、"@every 6h"
+	QuietHours    string `json:"quiet_hours"`    // 静默时段，不发起下载，如 "23:00-07:00"
+	KeepAlive     string `json:"keep_alive"`     // 保持登录状态的检查间隔，如 "30m"
+	CourseFormats []int  `json:"course_formats"` // 课程下载格式，同 dl -t，为空时不同步课程
+	OdobFormats   []int  `json:"odob_formats"`   // 听书下载格式，同 dlo -t，为空时不同步听书
+	EbookFormats  []int  `json:"ebook_formats"`  // 电子书下载格式，同 dle -t，为空时不同步电子书
+	Comment       bool   `json:"comment"`        // 同 dl -c
+	Order         bool   `json:"order"`          // 同 dl -o
+	OfflineAssets bool   `json:"offline_assets"` // 同 --offline-assets
+	ConvertImages string `json:"convert_images"` // 同 --convert-images
+}
+
+// DefaultDaemonConfig 默认同步配置：每天凌晨 3 点同步
+// 默认不配置下载格式，避免首次运行时下载整个书架，需在 config.json 中按需开启
+func DefaultDaemonConfig() DaemonConfig {
+	return DaemonConfig{
+		Schedule:  "0 3 * * *",
+		KeepAlive: "1h",
+	}
+}
+
+// IsSynced 是否已同步
+func (c *ConfigsData) IsSynced(category string, id int, format string) bool {
+	ok, err := c.badgerDB.Exists(syncKey(category, id, format))
+	return err == nil && ok
+}
+
+// SetSynced 记录已同步
+func (c *ConfigsData) SetSynced(category string, id int, format string) error {
+	return c.badgerDB.Set(syncKey(category, id, format), time.Now().Unix())
+}
+
+func syncKey(category string, id int, format string) string {
+	return fmt.Sprintf("%s%s:%d:%s", syncKeyPrefix, category, id, format)
+}
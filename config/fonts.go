@@ -0,0 +1,67 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// FontsConfig 字体缓存和注册的字体，保存在 config.json 的 Fonts 字段
+type FontsConfig struct {
+	Dir       string `json:"dir"`        // 字体缓存目录，为空时使用配置目录下的 fonts
+	EmbedEpub bool   `json:"embed_epub"` // 生成 EPUB 时嵌入文中使用的字体，同 --embed-fonts
+	// Custom 注册的字体，与内置字体同名时替换内置字体
+	Custom []utils.WebFont `json:"custom"`
+}
+
+// CacheDir 字体缓存目录
+func (c FontsConfig) CacheDir() string {
+	if c.Dir != "" {
+		return c.Dir
+	}
+	return filepath.Join(GetConfigDir(), "fonts")
+}
+
+// applyFonts 按配置设置字体管理
+func (c *ConfigsData) applyFonts() {
+	utils.Fonts.Configure(c.Fonts.CacheDir(), c.Fonts.Custom)
+}
+
+// RegisterFont 注册字体并保存配置，同名字体替换原来的文件
+func (c *ConfigsData) RegisterFont(family, file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	font := utils.WebFont{Family: family, File: abs}
+	if err = utils.CheckFontFile(abs); err != nil {
+		return err
+	}
+	replaced := false
+	for i, f := range c.Fonts.Custom {
+		if f.Family == family {
+			c.Fonts.Custom[i], replaced = font, true
+		}
+	}
+	if !replaced {
+		c.Fonts.Custom = append(c.Fonts.Custom, font)
+	}
+	c.applyFonts()
+	return c.Save()
+}
+
+// UnregisterFont 删除注册的字体并保存配置，字体不存在时返回 false
+func (c *ConfigsData) UnregisterFont(family string) (bool, error) {
+	fonts := c.Fonts.Custom[:0]
+	for _, f := range c.Fonts.Custom {
+		if f.Family != family {
+			fonts = append(fonts, f)
+		}
+	}
+	if len(fonts) == len(c.Fonts.Custom) {
+		return false, nil
+	}
+	c.Fonts.Custom = fonts
+	c.applyFonts()
+	return true, c.Save()
+}
@@ -0,0 +1,9 @@
+package config
+
+// HookConfig 下载后执行的命令，保存在 config.json 的 Hooks 字段
+type HookConfig struct {
+	Command string   `json:"command"` // 通过 sh -c 执行，Windows 下为 cmd /C
+	On      string   `json:"on"`      // item 每个文件生成后执行，course 整门课程下载完成后执行，为空时为 item
+	Kinds   []string `json:"kinds"`   // 只对 course、odob、ebook 执行，为空时不限
+	Formats []string `json:"formats"` // 只对指定格式执行，如 md、mp3、epub，为空时不限
+}
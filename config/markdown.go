@@ -0,0 +1,7 @@
+package config
+
+// MarkdownOptions markdown 渲染选项，保存在 config.json 的 Markdown 字段
+type MarkdownOptions struct {
+	CRLF          bool `json:"crlf"`           // 使用 \r\n 换行，默认 \n
+	HeadingOffset int  `json:"heading_offset"` // 标题级别偏移，如 1 时一级标题渲染为二级标题，最多六级
+}
@@ -0,0 +1,21 @@
+package config
+
+import "github.com/yann0917/dedao-dl/utils"
+
+// PdfConfig PDF 生成选项，保存在 config.json 的 PDF 字段
+type PdfConfig struct {
+	Engine   string `json:"engine"`    // 生成方式：auto、native、wkhtmltopdf，为空时为 auto
+	Font     string `json:"font"`      // 内置引擎使用的中文字体，TrueType 轮廓的 ttf、otf 或 ttc，为空时查找系统字体
+	BoldFont string `json:"bold_font"` // 粗体字体，为空时加粗显示常规字体
+	Profile  string `json:"profile"`   // 打印配置名称：a4、a5、letter、6inch、large-print 或自定义的名称，为空时为 a4
+	// Profiles 自定义打印配置，与内置配置同名时只覆盖其中不为空的字段
+	Profiles map[string]utils.PrintProfile `json:"profiles"`
+}
+
+// PrintProfile 按名称查找打印配置，为空时使用 Profile
+func (c PdfConfig) PrintProfile(name string) (utils.PrintProfile, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	return utils.ResolvePrintProfile(name, c.Profiles)
+}
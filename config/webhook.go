@@ -0,0 +1,8 @@
+package config
+
+// WebhookConfig webhook 配置，保存在 config.json 的 Webhooks 字段
+type WebhookConfig struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"` // 签名密钥，为空时不签名
+	Events []string `json:"events"` // 推送的事件类型，为空时推送任务开始、结束、失败和文件下载失败
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/yann0917/dedao-dl/metrics"
 	"github.com/yann0917/dedao-dl/request"
 	"github.com/yann0917/dedao-dl/utils"
 )
@@ -119,6 +120,11 @@ func Download(v Datum, stream, path string) error {
 
 func downloadAudio(m3u8 string, fname string) (err error) {
 	err = utils.MergeAudio([]string{m3u8}, fname)
+	if err == nil {
+		if size, _, e := utils.FileSize(fname); e == nil {
+			metrics.DownloadedBytes.Add(float64(size))
+		}
+	}
 	return
 }
 
@@ -237,6 +243,7 @@ func writeFile(url string, file *os.File, headers map[string]string) (int, error
 	// Note that io.Copy reads 32kb(maximum) from input and writes them to output, then repeats.
 	// So don't worry about memory.
 	written, copyErr := io.Copy(writer, res)
+	metrics.DownloadedBytes.Add(float64(written))
 	if copyErr != nil && copyErr != io.EOF {
 		return int(written), fmt.Errorf("file copy error: %s", copyErr)
 	}
@@ -0,0 +1,123 @@
+// Package metrics Prometheus 监控指标，daemon、api 等常驻模式通过 --metrics 暴露 /metrics
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dedao"
+
+var (
+	// UpstreamRequests 请求得到接口的次数，endpoint 为接口地址，未知接口为 other
+	// status 为 HTTP 状态码，请求失败时为 error
+	UpstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests sent to the dedao API by endpoint and status.",
+	}, []string{"endpoint", "status"})
+
+	// UpstreamRequestDuration 请求得到接口的耗时
+	UpstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of requests sent to the dedao API.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// APIRequests 本地 REST API 的请求次数，route 为路由
+	APIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Requests served by the local REST API by route and status.",
+	}, []string{"route", "status"})
+
+	// APIRequestDuration 本地 REST API 的处理耗时，不含 Server-Sent Events
+	APIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of local REST API requests, excluding event streams.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	// Retries 重试次数，reason 为 antispider 或 error
+	Retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Retried requests by reason.",
+	}, []string{"reason"})
+
+	// AntispiderCooldowns 进入反爬虫冷却期的次数
+	AntispiderCooldowns = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "antispider_cooldowns_total",
+		Help:      "Times the anti-spider cooldown was entered.",
+	})
+
+	// AntispiderWait 因冷却期和限流等待的总时长
+	AntispiderWait = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "antispider_wait_seconds_total",
+		Help:      "Time spent waiting before requests by reason.",
+	}, []string{"reason"})
+
+	// DownloadedBytes 下载的字节数
+	DownloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes of media downloaded.",
+	})
+
+	// Items 处理的文件数，kind 为 course、odob、ebook，result 为 finished、failed、skipped
+	Items = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_total",
+		Help:      "Downloaded items by kind, format and result.",
+	}, []string{"kind", "format", "result"})
+
+	// ConversionDuration 格式转换耗时，如 md2pdf、svg2epub
+	ConversionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "conversion_duration_seconds",
+		Help:      "Duration of format conversions.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"conversion", "result"})
+
+	// CacheRequests 缓存读取次数，result 为 hit 或 miss
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Badger cache lookups by cache and result.",
+	}, []string{"cache", "result"})
+)
+
+// ObserveConversion 记录格式转换耗时，用法 defer metrics.ObserveConversion("md2pdf", time.Now(), &err)
+func ObserveConversion(conversion string, start time.Time, err *error) {
+	result := "ok"
+	if err != nil && *err != nil {
+		result = "error"
+	}
+	ConversionDuration.WithLabelValues(conversion, result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCache 记录缓存命中情况
+func ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// Serve 在 addr 上提供 /metrics，阻塞运行
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	fmt.Printf("监控指标：http://%s/metrics\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
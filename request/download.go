@@ -8,6 +8,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/yann0917/dedao-dl/metrics"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 )
@@ -111,7 +112,9 @@ func (g *GetDownload) DownloadWithContext(ctx context.Context, task *DownloadTas
 		return fmt.Errorf("invalid status code %d(%s)", rsp.StatusCode, rsp.Status)
 	}
 
-	if _, err := io.Copy(f, rsp.Body); err != nil {
+	written, err := io.Copy(f, rsp.Body)
+	metrics.DownloadedBytes.Add(float64(written))
+	if err != nil {
 		return fmt.Errorf("copy error: %s", err)
 	}
 
@@ -392,13 +392,13 @@ func (s *Service) ArticleDetail(token, id, appID string) (detail *ArticleDetail,
 }
 
 // ArticlePoint get article point
-func (s *Service) ArticlePoint(id, pType string) (detail *ArticleDetail, err error) {
+func (s *Service) ArticlePoint(id, pType string) (point *ArticlePoint, err error) {
 	body, err := s.reqArticlePoint(id, pType)
 	if err != nil {
 		return
 	}
 	defer body.Close()
-	if err = handleJSONParse(body, &detail); err != nil {
+	if err = handleJSONParse(body, &point); err != nil {
 		return
 	}
 	return
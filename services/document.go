@@ -0,0 +1,312 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/utils"
+)
+
+// 文章内容块类型，与 ArticleDetail.Content 中的 type 一致
+const (
+	BlockHeading    = "header"
+	BlockParagraph  = "paragraph"
+	BlockList       = "list"
+	BlockQuote      = "blockquote"
+	BlockImage      = "image"
+	BlockAudio      = "audio"
+	BlockElite      = "elite" // 划重点
+	BlockLabelGroup = "label-group"
+	BlockTable      = "table"
+	BlockCode       = "code"
+)
+
+// Document 文章内容的结构化表示，markdown、HTML、PDF 都由此渲染
+type Document struct {
+	Blocks []Block
+}
+
+// Block 文章内容块
+type Block interface {
+	BlockType() string
+}
+
+// HeadingBlock 标题
+type HeadingBlock struct {
+	Level int
+	Text  string
+}
+
+// ParagraphBlock 段落
+type ParagraphBlock struct {
+	Justify string
+	Runs    []Run
+}
+
+// Run 段落中的一段文字
+type Run struct {
+	Type      string // text 以外的类型原样保留
+	Text      string
+	Bold      bool
+	Highlight bool
+	Jump      string // 链接
+}
+
+// ListBlock 列表，Items 中的每一项可以包含子列表
+type ListBlock struct {
+	Ordered bool
+	Items   []ListItem
+}
+
+// ListItem 列表项
+type ListItem struct {
+	Runs     []Run
+	Children *ListBlock
+}
+
+// QuoteBlock 引用
+type QuoteBlock struct {
+	Text string
+}
+
+// ImageBlock 图片
+type ImageBlock struct {
+	URL    string
+	Legend string // 图片说明
+	Jump   string // 点击图片跳转的链接
+	Width  int64
+	Height int64
+}
+
+// AudioBlock 音频
+type AudioBlock struct {
+	Title    string
+	AliasID  string
+	Duration string
+	Size     int64
+}
+
+// EliteBlock 划重点
+type EliteBlock struct {
+	Text string
+}
+
+// LabelGroupBlock 标签组
+type LabelGroupBlock struct {
+	Text   string
+	Labels []string
+}
+
+// TableBlock 表格，第一行为表头
+type TableBlock struct {
+	Rows [][]TableCell
+}
+
+// TableCell 单元格
+type TableCell struct {
+	Runs []Run
+}
+
+// CodeBlock 代码
+type CodeBlock struct {
+	Language string
+	Text     string
+}
+
+// UnknownBlock 未识别的内容块，保留原始 JSON
+type UnknownBlock struct {
+	Type string
+	Text string
+	Raw  jsoniter.RawMessage
+}
+
+func (HeadingBlock) BlockType() string    { return BlockHeading }
+func (ParagraphBlock) BlockType() string  { return BlockParagraph }
+func (ListBlock) BlockType() string       { return BlockList }
+func (QuoteBlock) BlockType() string      { return BlockQuote }
+func (ImageBlock) BlockType() string      { return BlockImage }
+func (AudioBlock) BlockType() string      { return BlockAudio }
+func (EliteBlock) BlockType() string      { return BlockElite }
+func (LabelGroupBlock) BlockType() string { return BlockLabelGroup }
+func (TableBlock) BlockType() string      { return BlockTable }
+func (CodeBlock) BlockType() string       { return BlockCode }
+func (u UnknownBlock) BlockType() string  { return u.Type }
+
+// rawBlock 与 Content 相同，contents 延迟解析
+type rawBlock struct {
+	Content
+	Contents jsoniter.RawMessage `json:"contents"`
+	Language string              `json:"language"`
+}
+
+// rawRun 段落中的一段文字
+type rawRun struct {
+	Type string `json:"type"`
+	Text struct {
+		Bold      bool   `json:"bold"`
+		Content   string `json:"content"`
+		Highlight bool   `json:"highlight"`
+	} `json:"text"`
+	Jump string `json:"jump"`
+	// 嵌套列表
+	Ordered  bool                  `json:"ordered"`
+	Contents []jsoniter.RawMessage `json:"contents"`
+}
+
+// ParseDocument 解析 ArticleDetail.Content
+func ParseDocument(content string) (*Document, error) {
+	var raws []jsoniter.RawMessage
+	if err := utils.UnmarshalJSON([]byte(content), &raws); err != nil {
+		return nil, err
+	}
+	doc := &Document{Blocks: make([]Block, 0, len(raws))}
+	for _, raw := range raws {
+		block, err := parseBlock(raw)
+		if err != nil {
+			return nil, err
+		}
+		doc.Blocks = append(doc.Blocks, block)
+	}
+	return doc, nil
+}
+
+// UnknownTypes 未识别的内容块类型，去重
+func (d *Document) UnknownTypes() []string {
+	var types []string
+	seen := map[string]bool{}
+	for _, block := range d.Blocks {
+		if u, ok := block.(UnknownBlock); ok && !seen[u.Type] {
+			seen[u.Type] = true
+			types = append(types, u.Type)
+		}
+	}
+	return types
+}
+
+func parseBlock(raw jsoniter.RawMessage) (Block, error) {
+	var b rawBlock
+	if err := utils.UnmarshalJSON(raw, &b); err != nil {
+		return nil, err
+	}
+	switch b.Type {
+	case BlockHeading:
+		return HeadingBlock{Level: b.Level, Text: strings.TrimSpace(b.Text)}, nil
+	case BlockParagraph:
+		var runs []jsoniter.RawMessage
+		if err := unmarshalContents(b.Contents, &runs); err != nil {
+			return nil, err
+		}
+		p := ParagraphBlock{Justify: b.Justify}
+		for _, r := range runs {
+			run, _, err := parseRun(r)
+			if err != nil {
+				return nil, err
+			}
+			p.Runs = append(p.Runs, run)
+		}
+		return p, nil
+	case BlockList:
+		var items [][]jsoniter.RawMessage
+		if err := unmarshalContents(b.Contents, &items); err != nil {
+			return nil, err
+		}
+		return parseList(b.Ordered, items)
+	case BlockQuote:
+		return QuoteBlock{Text: b.Text}, nil
+	case BlockImage:
+		return ImageBlock{URL: b.URL, Legend: b.Legend, Jump: b.Jump, Width: b.Width, Height: b.Height}, nil
+	case BlockAudio:
+		return AudioBlock{Title: b.Title, AliasID: b.AliasID, Duration: durationString(b.Duration), Size: b.Size}, nil
+	case BlockElite:
+		return EliteBlock{Text: b.Text}, nil
+	case BlockLabelGroup:
+		return LabelGroupBlock{Text: b.Text, Labels: b.Labels}, nil
+	case BlockTable:
+		var rows [][][]jsoniter.RawMessage
+		if err := unmarshalContents(b.Contents, &rows); err != nil {
+			return nil, err
+		}
+		table := TableBlock{}
+		for _, row := range rows {
+			cells := make([]TableCell, 0, len(row))
+			for _, cell := range row {
+				var c TableCell
+				for _, r := range cell {
+					run, _, err := parseRun(r)
+					if err != nil {
+						return nil, err
+					}
+					c.Runs = append(c.Runs, run)
+				}
+				cells = append(cells, c)
+			}
+			table.Rows = append(table.Rows, cells)
+		}
+		return table, nil
+	case BlockCode:
+		return CodeBlock{Language: b.Language, Text: b.Text}, nil
+	}
+	return UnknownBlock{Type: b.Type, Text: b.Text, Raw: raw}, nil
+}
+
+func parseList(ordered bool, items [][]jsoniter.RawMessage) (ListBlock, error) {
+	list := ListBlock{Ordered: ordered}
+	for _, item := range items {
+		var li ListItem
+		for _, r := range item {
+			run, children, err := parseRun(r)
+			if err != nil {
+				return list, err
+			}
+			if children != nil {
+				li.Children = children
+				continue
+			}
+			li.Runs = append(li.Runs, run)
+		}
+		list.Items = append(list.Items, li)
+	}
+	return list, nil
+}
+
+// parseRun 解析一段文字，列表项中嵌套的列表通过 children 返回
+func parseRun(raw jsoniter.RawMessage) (run Run, children *ListBlock, err error) {
+	var r rawRun
+	if err = utils.UnmarshalJSON(raw, &r); err != nil {
+		return
+	}
+	if r.Type == BlockList {
+		var items [][]jsoniter.RawMessage
+		for _, c := range r.Contents {
+			var item []jsoniter.RawMessage
+			if err = utils.UnmarshalJSON(c, &item); err != nil {
+				return
+			}
+			items = append(items, item)
+		}
+		list, e := parseList(r.Ordered, items)
+		return run, &list, e
+	}
+	return Run{Type: r.Type, Text: r.Text.Content, Bold: r.Text.Bold, Highlight: r.Text.Highlight, Jump: r.Jump}, nil, nil
+}
+
+func unmarshalContents(raw jsoniter.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return utils.UnmarshalJSON(raw, v)
+}
+
+// durationString duration 可能是字符串或数字
+func durationString(v interface{}) string {
+	switch d := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return d
+	case float64:
+		return fmt.Sprintf("%.0f", d)
+	}
+	return fmt.Sprint(v)
+}
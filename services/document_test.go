@@ -0,0 +1,97 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDocument(t *testing.T) {
+	content := `[
+		{"type":"header","level":2,"text":" 标题 "},
+		{"type":"paragraph","justify":"center","contents":[
+			{"type":"text","text":{"content":"普通"}},
+			{"type":"text","text":{"content":"加粗","bold":true}},
+			{"type":"text","text":{"content":"高亮","highlight":true},"jump":"https://example.com"}
+		]},
+		{"type":"list","ordered":true,"contents":[
+			[{"type":"text","text":{"content":"第一项"}}],
+			[
+				{"type":"text","text":{"content":"第二项"}},
+				{"type":"list","ordered":false,"contents":[
+					[{"type":"text","text":{"content":"子项"}}],
+					[
+						{"type":"text","text":{"content":"子项二"}},
+						{"type":"list","ordered":true,"contents":[[{"type":"text","text":{"content":"孙项"}}]]}
+					]
+				]}
+			]
+		]},
+		{"type":"audio","title":"音频","aliasId":"a1","duration":65,"size":1024},
+		{"type":"paragraph","contents":null},
+		{"type":"video","text":"视频说明","url":"https://example.com/v.mp4"},
+		{"type":"video","text":"另一个视频"},
+		{"type":"poll"}
+	]`
+	doc, err := ParseDocument(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Blocks) != 8 {
+		t.Fatalf("blocks = %d, want 8", len(doc.Blocks))
+	}
+
+	if h, ok := doc.Blocks[0].(HeadingBlock); !ok || h != (HeadingBlock{Level: 2, Text: "标题"}) {
+		t.Errorf("heading = %#v", doc.Blocks[0])
+	}
+	wantParagraph := ParagraphBlock{Justify: "center", Runs: []Run{
+		{Type: "text", Text: "普通"},
+		{Type: "text", Text: "加粗", Bold: true},
+		{Type: "text", Text: "高亮", Highlight: true, Jump: "https://example.com"},
+	}}
+	if !reflect.DeepEqual(doc.Blocks[1], wantParagraph) {
+		t.Errorf("paragraph = %#v", doc.Blocks[1])
+	}
+
+	text := func(s string) []Run { return []Run{{Type: "text", Text: s}} }
+	wantList := ListBlock{Ordered: true, Items: []ListItem{
+		{Runs: text("第一项")},
+		{Runs: text("第二项"), Children: &ListBlock{Items: []ListItem{
+			{Runs: text("子项")},
+			{Runs: text("子项二"), Children: &ListBlock{Ordered: true, Items: []ListItem{{Runs: text("孙项")}}}},
+		}}},
+	}}
+	if !reflect.DeepEqual(doc.Blocks[2], wantList) {
+		t.Errorf("list = %#v", doc.Blocks[2])
+	}
+
+	if a, ok := doc.Blocks[3].(AudioBlock); !ok || a != (AudioBlock{Title: "音频", AliasID: "a1", Duration: "65", Size: 1024}) {
+		t.Errorf("audio = %#v", doc.Blocks[3])
+	}
+	if p, ok := doc.Blocks[4].(ParagraphBlock); !ok || len(p.Runs) != 0 {
+		t.Errorf("empty paragraph = %#v", doc.Blocks[4])
+	}
+
+	// 未识别的内容块保留类型、文字和原始 JSON
+	u, ok := doc.Blocks[5].(UnknownBlock)
+	if !ok || u.Type != "video" || u.Text != "视频说明" || u.BlockType() != "video" {
+		t.Errorf("unknown = %#v", doc.Blocks[5])
+	}
+	if string(u.Raw) != `{"type":"video","text":"视频说明","url":"https://example.com/v.mp4"}` {
+		t.Errorf("raw = %s", u.Raw)
+	}
+	if got := doc.UnknownTypes(); !reflect.DeepEqual(got, []string{"video", "poll"}) {
+		t.Errorf("UnknownTypes = %q", got)
+	}
+}
+
+func TestParseDocumentError(t *testing.T) {
+	for _, content := range []string{
+		`{"type":"paragraph"}`,
+		`[{"type":"paragraph","contents":"text"}]`,
+		`[{"type":"list","contents":[[{"type":"list","contents":[1]}]]}]`,
+	} {
+		if _, err := ParseDocument(content); err == nil {
+			t.Errorf("ParseDocument(%s) should fail", content)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/yann0917/dedao-dl/metrics"
 	"github.com/yann0917/dedao-dl/utils"
 )
 
@@ -114,6 +115,7 @@ func waitForNextRequest() {
 			waitTime := cooldownTime*time.Second - time.Since(lastRequestTime)
 			antispiderMutex.Unlock()
 			fmt.Printf("处于反爬虫冷却期，等待 %.1f 秒...\n", waitTime.Seconds())
+			metrics.AntispiderWait.WithLabelValues("cooldown").Add(waitTime.Seconds())
 			time.Sleep(waitTime)
 			return
 		}
@@ -124,6 +126,7 @@ func waitForNextRequest() {
 	// 从令牌桶获取令牌，可能会有等待时间
 	waitTime := globalLimiter.getToken()
 	if waitTime > 0 {
+		metrics.AntispiderWait.WithLabelValues("limiter").Add(waitTime.Seconds())
 		time.Sleep(waitTime)
 	}
 
@@ -133,6 +136,11 @@ func waitForNextRequest() {
 	antispiderMutex.Unlock()
 }
 
+// WaitForNextRequest 遵循全局请求限流和反爬虫冷却期，供批量下载在每个条目之间调用
+func WaitForNextRequest() {
+	waitForNextRequest()
+}
+
 // 记录请求失败
 func recordRequestFailure(err error) {
 	antispiderMutex.Lock()
@@ -145,6 +153,9 @@ func recordRequestFailure(err error) {
 		strings.Contains(err.Error(), "429") {
 		// 直接进入冷却期
 		fmt.Println("检测到可能的反爬虫限制，进入冷却期")
+		if !antispiderCooldown {
+			metrics.AntispiderCooldowns.Inc()
+		}
 		consecutiveFailures = maxConsecutiveFailures
 		antispiderCooldown = true
 		lastRequestTime = time.Now()
@@ -157,6 +168,9 @@ func recordRequestFailure(err error) {
 	// 如果连续失败次数超过阈值，启动冷却期
 	if consecutiveFailures >= maxConsecutiveFailures {
 		fmt.Printf("连续请求失败 %d 次，可能触发了反爬虫机制，进入冷却期\n", consecutiveFailures)
+		if !antispiderCooldown {
+			metrics.AntispiderCooldowns.Inc()
+		}
 		antispiderCooldown = true
 		lastRequestTime = time.Now()
 	}
@@ -188,6 +202,7 @@ func LoadFromCache(enid, chapterID string) ([]string, bool) {
 	cacheKey := getEbookPageCacheKey(enid, chapterID)
 
 	err = db.Get(cacheKey, &pages)
+	metrics.ObserveCache("ebook_page", err == nil)
 	if err != nil {
 		return nil, false
 	}
@@ -252,13 +267,16 @@ func withRetry[T any](operation func() (T, error), chapterID string) (result T,
 		fmt.Printf("错误: %v\n", err)
 
 		if i < maxRetries-1 {
+			reason := "error"
 			// 如果是反爬虫错误，使用更长的退避时间
 			if isAntiSpider {
+				reason = "antispider"
 				backoff = backoff * 3 // 更激进的退避
 				fmt.Printf("检测到可能的反爬虫限制，使用更长的等待时间\n")
 			}
 
 			fmt.Printf("将在 %v 后重试...\n", backoff)
+			metrics.Retries.WithLabelValues(reason).Inc()
 			time.Sleep(backoff)
 
 			// 指数退避策略
@@ -7,10 +7,13 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/mitchellh/mapstructure"
+	"github.com/yann0917/dedao-dl/metrics"
 	"github.com/yann0917/dedao-dl/utils"
 )
 
@@ -125,6 +128,7 @@ func (r *Response) isSuccess() bool {
 }
 
 func handleHTTPResponse(resp *resty.Response, err error) (io.ReadCloser, error) {
+	observeResponse(resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("request to %s failed: %w", resp.Request.URL, err)
 	}
@@ -165,6 +169,65 @@ func handleHTTPResponse(resp *resty.Response, err error) (io.ReadCloser, error)
 	return result, nil
 }
 
+// upstreamEndpoints 请求的接口，作为监控指标的 endpoint 标签
+// 不在列表中的地址记为 other，避免地址中含有 ID 等参数时标签数量无限增长
+var upstreamEndpoints = map[string]bool{
+	"/":                          true,
+	"/api/hades/v1/index/detail": true,
+	"/api/hades/v1/product/list": true,
+	"/api/hades/v2/product/list": true,
+	"/api/pc/bauhinia/pc/class/purchase/article_list":        true,
+	"/api/pc/ebook2/v1/pc/read/token":                        true,
+	"/api/pc/ebook2/v1/vip/info":                             true,
+	"/api/pc/user/info":                                      true,
+	"/ddph/v2/token/create":                                  true,
+	"/ebk_web/v1/get_book_info":                              true,
+	"/ebk_web_go/v2/get_pages":                               true,
+	"/loginapi/getAccessToken":                               true,
+	"/oauth/api/embedded/qrcode":                             true,
+	"/oauth/api/embedded/qrcode/check_login":                 true,
+	"/pc/bauhinia/pc/article/info":                           true,
+	"/pc/bauhinia/pc/class/info":                             true,
+	"/pc/bauhinia/v1/audio/mutiget_by_alias":                 true,
+	"/pc/ddarticle/v1/article/get/v2":                        true,
+	"/pc/ebook2/v1/pc/detail":                                true,
+	"/pc/ledgers/notes/article_comment_list":                 true,
+	"/pc/ledgers/topic/all":                                  true,
+	"/pc/ledgers/topic/detail":                               true,
+	"/pc/ledgers/topic/notes/list":                           true,
+	"/pc/odob/pc/audio/detail/alias":                         true,
+	"/pc/odob/v2/vipuser/vip_card_info":                      true,
+	"/pc/sunflower/v1/depot/outside/detail":                  true,
+	"/pc/sunflower/v1/depot/vip-user/topic-pkg/odob/details": true,
+}
+
+// endpointLabel 接口地址对应的监控标签，忽略域名和查询参数
+func endpointLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "other"
+	}
+	endpoint := "/" + strings.Trim(u.Path, "/")
+	if !upstreamEndpoints[endpoint] {
+		return "other"
+	}
+	return endpoint
+}
+
+// observeResponse 记录接口请求的监控指标
+func observeResponse(resp *resty.Response, err error) {
+	if resp == nil || resp.Request == nil {
+		return
+	}
+	endpoint := endpointLabel(resp.Request.URL)
+	status := strconv.Itoa(resp.StatusCode())
+	if err != nil {
+		status = "error"
+	}
+	metrics.UpstreamRequests.WithLabelValues(endpoint, status).Inc()
+	metrics.UpstreamRequestDuration.WithLabelValues(endpoint).Observe(resp.Time().Seconds())
+}
+
 type responseWrapper struct {
 	io.ReadCloser
 	rawData []byte
@@ -229,3 +292,50 @@ func ParseCookies(cookie string, v interface{}) (err error) {
 	err = mapstructure.Decode(cookieM, v)
 	return
 }
+
+// SessionCookies 服务端通过 Set-Cookie 刷新的会话 cookie
+func (s *Service) SessionCookies() map[string]string {
+	jar := s.client.GetClient().Jar
+	if jar == nil {
+		return nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+	cookies := jar.Cookies(u)
+	m := make(map[string]string, len(cookies))
+	for _, c := range cookies {
+		if c.Value != "" {
+			m[c.Name] = c.Value
+		}
+	}
+	return m
+}
+
+// MergeCookieStr 用新的 cookie 值替换 cookie 字符串中的同名项，新增的 cookie 追加在末尾
+func MergeCookieStr(cookie string, cookies map[string]string) string {
+	var list []string
+	seen := make(map[string]bool, len(cookies))
+	if cookie != "" {
+		for _, item := range strings.Split(cookie, "; ") {
+			name, _, _ := strings.Cut(item, "=")
+			if v, ok := cookies[name]; ok {
+				item = name + "=" + v
+				seen[name] = true
+			}
+			list = append(list, item)
+		}
+	}
+	names := make([]string, 0, len(cookies))
+	for name := range cookies {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		list = append(list, name+"="+cookies[name])
+	}
+	return strings.Join(list, "; ")
+}
@@ -177,3 +177,38 @@ func TestTopicNotesList(t *testing.T) {
 	}
 	fmt.Printf("result:=%v \n", result)
 }
+
+func TestMergeCookieStr(t *testing.T) {
+	cases := []struct {
+		cookie  string
+		cookies map[string]string
+		want    string
+	}{
+		{"", nil, ""},
+		{"a=1; b=2", nil, "a=1; b=2"},
+		{"a=1; GAT=old; b=2", map[string]string{"GAT": "new"}, "a=1; GAT=new; b=2"},
+		{"a=1", map[string]string{"c": "3", "b": "2", "a": "0"}, "a=0; b=2; c=3"},
+		{"", map[string]string{"token": "t"}, "token=t"},
+	}
+	for _, c := range cases {
+		if got := MergeCookieStr(c.cookie, c.cookies); got != c.want {
+			t.Errorf("MergeCookieStr(%q, %v) = %q, want %q", c.cookie, c.cookies, got, c.want)
+		}
+	}
+}
+
+func TestEndpointLabel(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://www.dedao.cn/api/pc/user/info":                            "/api/pc/user/info",
+		"https://www.dedao.cn/pc/ddarticle/v1/article/get/v2?token=abc":    "/pc/ddarticle/v1/article/get/v2",
+		"https://www.dedao.cn/pc/odob/pc/audio/detail/alias/":              "/pc/odob/pc/audio/detail/alias",
+		"https://www.dedao.cn":                                             "/",
+		"https://www.dedao.cn/pc/bauhinia/pc/class/info/123456":            "other",
+		"https://m.igetget.com/ddmedia/public/v1/m3u8/3368680087879724/52": "other",
+		"%zz": "other",
+	} {
+		if got := endpointLabel(rawURL); got != want {
+			t.Errorf("endpointLabel(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
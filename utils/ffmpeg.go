@@ -3,8 +3,14 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/yann0917/dedao-dl/metrics"
 )
 
 func runMergeCmd(cmd *exec.Cmd, paths []string, mergeFilePath string) error {
@@ -26,7 +32,8 @@ func runMergeCmd(cmd *exec.Cmd, paths []string, mergeFilePath string) error {
 }
 
 // MergeAudio merge audio
-func MergeAudio(paths []string, mergedFilePath string) error {
+func MergeAudio(paths []string, mergedFilePath string) (err error) {
+	defer metrics.ObserveConversion("ffmpeg", time.Now(), &err)
 	cmds := []string{
 		"-y",
 	}
@@ -68,3 +75,72 @@ func MergeToMP4(paths []string, mergedFilePath string, filename string) error {
 	)
 	return runMergeCmd(cmd, paths, mergeFilePath)
 }
+
+// ConvertImage 转换图片格式，如 webp、avif 转为 jpg、png，输出格式由 dst 扩展名决定
+func ConvertImage(src, dst string) (err error) {
+	defer metrics.ObserveConversion("image", time.Now(), &err)
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-i", src, "-frames:v", "1", dst)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%s\n%s", err, stderr.String())
+	}
+	return nil
+}
+
+// Silence 音频中的一段静音
+type Silence struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+var (
+	silenceRegexp  = regexp.MustCompile(`silence_(start|end): (-?[\d.]+)`)
+	durationRegexp = regexp.MustCompile(`Duration: (\d+):(\d+):([\d.]+)`)
+)
+
+// DetectSilence 使用 ffmpeg silencedetect 检测音频中的静音，同时返回音频时长
+func DetectSilence(file string) (silences []Silence, duration time.Duration, err error) {
+	defer metrics.ObserveConversion("silence", time.Now(), &err)
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-nostats", "-i", file,
+		"-af", "silencedetect=noise=-35dB:d=0.35", "-f", "null", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return nil, 0, fmt.Errorf("%s\n%s", err, stderr.String())
+	}
+	silences, duration = parseSilence(stderr.String())
+	return
+}
+
+// parseSilence 解析 silencedetect 的输出，音频结尾的静音没有 silence_end，以音频时长结束
+func parseSilence(output string) (silences []Silence, duration time.Duration) {
+	if m := durationRegexp.FindStringSubmatch(output); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		sec, _ := strconv.ParseFloat(m[3], 64)
+		duration = time.Duration(h)*time.Hour + time.Duration(minute)*time.Minute + seconds(sec)
+	}
+	var start *time.Duration
+	for _, m := range silenceRegexp.FindAllStringSubmatch(output, -1) {
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		t := seconds(max(v, 0))
+		if m[1] == "start" {
+			start = &t
+		} else if start != nil {
+			silences = append(silences, Silence{Start: *start, End: t})
+			start = nil
+		}
+	}
+	if start != nil && duration > *start {
+		silences = append(silences, Silence{Start: *start, End: duration})
+	}
+	return
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(math.Round(v * float64(time.Second)))
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSilence(t *testing.T) {
+	output := `Input #0, mp3, from 'a.mp3':
+  Duration: 00:01:05.50, start: 0.025057, bitrate: 64 kb/s
+[silencedetect @ 0x1] silence_start: -0.01
+[silencedetect @ 0x1] silence_end: 0.5 | silence_duration: 0.51
+[silencedetect @ 0x1] silence_start: 10.25
+[silencedetect @ 0x1] silence_end: 11 | silence_duration: 0.75
+[silencedetect @ 0x1] silence_start: 64.9
+`
+	silences, duration := parseSilence(output)
+	if duration != 65500*time.Millisecond {
+		t.Errorf("duration = %v", duration)
+	}
+	want := []Silence{
+		{0, 500 * time.Millisecond},
+		{10250 * time.Millisecond, 11 * time.Second},
+		{64900 * time.Millisecond, 65500 * time.Millisecond},
+	}
+	if len(silences) != len(want) {
+		t.Fatalf("silences = %v", silences)
+	}
+	for i := range want {
+		if silences[i] != want[i] {
+			t.Errorf("silence %d = %v, want %v", i, silences[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// cjkFontCandidates 常见系统自带的 TrueType 轮廓中文字体，按顺序查找
+var cjkFontCandidates = map[string][]string{
+	"windows": {
+		`C:\Windows\Fonts\msyh.ttc`,
+		`C:\Windows\Fonts\msyh.ttf`,
+		`C:\Windows\Fonts\simhei.ttf`,
+		`C:\Windows\Fonts\simsun.ttc`,
+	},
+	"darwin": {
+		"/System/Library/Fonts/STHeiti Medium.ttc",
+		"/System/Library/Fonts/STHeiti Light.ttc",
+		"/System/Library/Fonts/Supplemental/Songti.ttc",
+		"/Library/Fonts/Arial Unicode.ttf",
+		"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	},
+	"linux": {
+		"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+		"/usr/share/fonts/wqy-microhei/wqy-microhei.ttc",
+		"/usr/share/fonts/truetype/wqy/wqy-zenhei.ttc",
+		"/usr/share/fonts/wqy-zenhei/wqy-zenhei.ttc",
+		"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+		"/usr/share/fonts/google-droid/DroidSansFallbackFull.ttf",
+		"/usr/share/fonts/truetype/arphic/uming.ttc",
+	},
+}
+
+// FindCJKFont 查找系统中可用于生成 PDF 的中文字体，找不到时返回空
+func FindCJKFont() string {
+	for _, file := range cjkFontCandidates[runtime.GOOS] {
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// LoadTrueTypeFont 读取 TrueType 轮廓的字体，ttc 字体集合取第一个字体
+// CFF 轮廓的 OpenType 字体（如思源黑体 otf）不支持嵌入
+func LoadTrueTypeFont(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 {
+		return nil, fmt.Errorf("字体文件无效: %s", filepath.Base(file))
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+		return data, nil
+	case "ttcf":
+		if data, err = ttcFont(data, 0); err != nil {
+			return nil, fmt.Errorf("字体文件无效: %s, %w", filepath.Base(file), err)
+		}
+		if string(data[:4]) == "OTTO" {
+			break
+		}
+		return data, nil
+	case "OTTO":
+	default:
+		return nil, fmt.Errorf("字体文件无效: %s", filepath.Base(file))
+	}
+	return nil, fmt.Errorf("不支持 CFF 轮廓的 OpenType 字体: %s，请使用 TrueType 轮廓的字体", filepath.Base(file))
+}
+
+// ttcFont 从 ttc 字体集合中取出第 index 个字体，生成独立的字体文件
+func ttcFont(data []byte, index int) ([]byte, error) {
+	be := binary.BigEndian
+	if len(data) < 16 || int(be.Uint32(data[8:])) <= index || len(data) < 16+4*index {
+		return nil, errors.New("ttc header")
+	}
+	offset := int(be.Uint32(data[12+4*index:]))
+	if len(data) < offset+12 {
+		return nil, errors.New("ttc offset")
+	}
+	numTables := int(be.Uint16(data[offset+4:]))
+	headerSize := 12 + 16*numTables
+	if len(data) < offset+headerSize {
+		return nil, errors.New("ttc table directory")
+	}
+
+	out := make([]byte, headerSize)
+	copy(out, data[offset:offset+headerSize])
+	for i := 0; i < numTables; i++ {
+		record := out[12+16*i:]
+		start, length := int(be.Uint32(record[8:])), int(be.Uint32(record[12:]))
+		if start+length > len(data) {
+			return nil, errors.New("ttc table")
+		}
+		be.PutUint32(record[8:], uint32(len(out)))
+		out = append(out, data[start:start+length]...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
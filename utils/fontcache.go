@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yann0917/dedao-dl/request"
+)
+
+// WebFont 文章样式中引用的字体
+type WebFont struct {
+	Family string `json:"family"`          // font-family 名称
+	Local  string `json:"local,omitempty"` // 系统中已安装时优先使用的字体名称
+	URL    string `json:"url,omitempty"`   // 下载地址
+	File   string `json:"file,omitempty"`  // 本地字体文件，注册的字体直接使用
+}
+
+// dedaoFonts 得到文章样式中使用的字体
+var dedaoFonts = []WebFont{
+	{Family: "FZFangSong-Z02", Local: "FZFangSong-Z02", URL: "https://imgcdn.umiwi.com/ttf/fangzhengfangsong_gbk.ttf"},
+	{Family: "FZKai-Z03", Local: "FZFangSong-Z02S", URL: "https://imgcdn.umiwi.com/ttf/0315911813008928624065681028886857980055.ttf"},
+	{Family: "FZKai-Z03", Local: "FZKai-Z03", URL: "https://imgcdn.umiwi.com/ttf/fangzhengkaiti_gbk.ttf"},
+	{Family: "PingFang SC", Local: "PingFang SC"},
+	{Family: "DeDaoJinKai", Local: "DeDaoJinKai", URL: "https://imgcdn.umiwi.com/ttf/dedaojinkaiw03.ttf"},
+	{Family: "Source Code Pro", Local: "Source Code Pro", URL: "https://imgcdn.umiwi.com/ttf/0315911806889993935644188722660020367983.ttf"},
+}
+
+// fontManifest 缓存目录中记录已下载字体的文件
+const fontManifest = "fonts.json"
+
+// fontEntry 已下载的字体，用于校验缓存文件是否完整
+type fontEntry struct {
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// FontManager 将样式中的远程字体下载到缓存目录，校验后改写为本地文件
+type FontManager struct {
+	mu       sync.Mutex
+	dir      string
+	custom   []WebFont
+	checked  bool                 // 本次运行是否已下载、校验过
+	verified map[string]string    // 下载地址 -> 校验通过的本地文件
+	manifest map[string]fontEntry // 下载地址 -> 已下载的字体
+}
+
+// Fonts 全局字体管理，下载前按配置设置缓存目录和注册的字体
+var Fonts = &FontManager{dir: "fonts"}
+
+// Configure 设置缓存目录和注册的字体，与内置字体同名的注册字体替换内置字体
+func (m *FontManager) Configure(dir string, custom []WebFont) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if dir != "" && dir != m.dir {
+		m.dir, m.checked, m.manifest = dir, false, nil
+	}
+	m.custom = custom
+}
+
+// Dir 缓存目录
+func (m *FontManager) Dir() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dir
+}
+
+// fonts 注册的字体和内置字体
+func (m *FontManager) fonts() []WebFont {
+	fonts := append([]WebFont{}, m.custom...)
+	registered := make(map[string]bool, len(m.custom))
+	for _, f := range m.custom {
+		registered[f.Family] = true
+	}
+	for _, f := range dedaoFonts {
+		if !registered[f.Family] {
+			fonts = append(fonts, f)
+		}
+	}
+	return fonts
+}
+
+// FontStatus 字体和本地文件的状态
+type FontStatus struct {
+	WebFont
+	Path string // 本地文件，未缓存时为空
+	Err  error  // 注册的字体无效或缓存文件校验失败
+}
+
+// List 字体和缓存状态，校验已缓存的文件，不下载
+func (m *FontManager) List() []FontStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []FontStatus
+	for _, f := range m.fonts() {
+		s := FontStatus{WebFont: f}
+		switch {
+		case f.File != "":
+			if s.Err = CheckFontFile(f.File); s.Err == nil {
+				s.Path = f.File
+			}
+		case f.URL != "":
+			s.Path, s.Err = m.cached(f.URL)
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// Download 下载缺失或校验失败的远程字体，force 时全部重新下载
+func (m *FontManager) Download(force bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.download(force)
+}
+
+func (m *FontManager) download(force bool) error {
+	m.checked = true
+	m.verified = make(map[string]string)
+	var errs []error
+	for _, f := range m.fonts() {
+		if f.URL == "" || m.verified[f.URL] != "" {
+			continue
+		}
+		if !force {
+			if file, err := m.cached(f.URL); err == nil && file != "" {
+				m.verified[f.URL] = file
+				continue
+			}
+		}
+		fmt.Printf("正在下载字体：【\033[37;1m%s\033[0m】 ", f.Family)
+		file, err := m.fetch(f.URL)
+		if err != nil {
+			fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+			errs = append(errs, fmt.Errorf("%s: %w", f.Family, err))
+			continue
+		}
+		fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+		m.verified[f.URL] = file
+	}
+	return errors.Join(errs...)
+}
+
+// cached 已下载且校验通过的文件，未下载时返回空
+func (m *FontManager) cached(link string) (string, error) {
+	if file, ok := m.verified[link]; ok {
+		return file, nil
+	}
+	entry, ok := m.loadManifest()[link]
+	if !ok {
+		return "", nil
+	}
+	file := filepath.Join(m.dir, entry.File)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) != entry.Size || sha256Hex(data) != entry.SHA256 {
+		return "", fmt.Errorf("字体文件校验失败: %s", file)
+	}
+	return file, nil
+}
+
+// fetch 下载字体，校验文件格式后保存并记录 sha256
+func (m *FontManager) fetch(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(m.dir, 0755); err != nil {
+		return "", err
+	}
+	name := MD5str(link) + path.Ext(u.Path)
+	file := filepath.Join(m.dir, name)
+	part := file + ".part"
+	_ = os.Remove(part + ".ok")
+	if err = request.Download(request.NewDownloadTask(link, part), 5*time.Minute); err != nil {
+		return "", err
+	}
+	_ = os.Remove(part + ".ok")
+	data, err := os.ReadFile(part)
+	if err != nil {
+		return "", err
+	}
+	if !isFontData(data) {
+		_ = os.Remove(part)
+		return "", errors.New("下载的文件不是字体")
+	}
+	if err = os.Rename(part, file); err != nil {
+		return "", err
+	}
+
+	manifest := m.loadManifest()
+	manifest[link] = fontEntry{File: name, Size: int64(len(data)), SHA256: sha256Hex(data)}
+	out, err := jsoniter.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return file, os.WriteFile(filepath.Join(m.dir, fontManifest), out, 0644)
+}
+
+func (m *FontManager) loadManifest() map[string]fontEntry {
+	if m.manifest != nil {
+		return m.manifest
+	}
+	m.manifest = make(map[string]fontEntry)
+	if data, err := os.ReadFile(filepath.Join(m.dir, fontManifest)); err == nil {
+		_ = UnmarshalJSON(data, &m.manifest)
+	}
+	return m.manifest
+}
+
+// resolved 首次使用时下载缺失的字体，返回各字体可用的本地文件
+// 下载失败时不再重试，样式中保留远程地址
+func (m *FontManager) resolved() []FontStatus {
+	if !m.checked {
+		_ = m.download(false)
+	}
+	var list []FontStatus
+	for _, f := range m.fonts() {
+		s := FontStatus{WebFont: f}
+		switch {
+		case f.File != "":
+			if s.Err = CheckFontFile(f.File); s.Err == nil {
+				s.Path = f.File
+			}
+		case f.URL != "":
+			s.Path = m.verified[f.URL]
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// FontFaceCSS 生成 @font-face 规则，已下载和注册的字体引用本地文件，未下载的字体保留远程地址
+func (m *FontManager) FontFaceCSS() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	for _, f := range m.resolved() {
+		var src []string
+		if f.Local != "" {
+			src = append(src, fmt.Sprintf("local(%q)", f.Local))
+		}
+		switch {
+		case f.Path != "":
+			src = append(src, fmt.Sprintf("url(%q)", fileURL(f.Path)))
+		case f.URL != "" && f.File == "":
+			src = append(src, fmt.Sprintf("url(%q)", f.URL))
+		}
+		if len(src) > 0 {
+			fmt.Fprintf(&b, "\n\t\t@font-face { font-family: %q; src: %s; }", f.Family, strings.Join(src, ", "))
+		}
+	}
+	return b.String()
+}
+
+// Used content 中引用到的有本地文件的字体，用于嵌入 EPUB
+func (m *FontManager) Used(content string) []FontStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var used []FontStatus
+	for _, f := range m.resolved() {
+		if f.Path != "" && strings.Contains(content, f.Family) {
+			used = append(used, f)
+		}
+	}
+	return used
+}
+
+// fileURL 本地文件的 file:// 地址，wkhtmltopdf 和浏览器可直接读取
+func fileURL(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	p := filepath.ToSlash(file)
+	if runtime.GOOS == "windows" {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// CheckFontFile 校验字体文件格式，支持 ttf、otf、ttc、woff 和 woff2
+func CheckFontFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	head := make([]byte, 4)
+	if _, err = f.Read(head); err != nil || !isFontData(head) {
+		return fmt.Errorf("字体文件无效: %s", filepath.Base(file))
+	}
+	return nil
+}
+
+// isFontData 是否为 ttf、otf、ttc、woff 或 woff2 字体
+func isFontData(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true", "OTTO", "ttcf", "wOFF", "wOF2":
+		return true
+	}
+	return false
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFontManager(t *testing.T) {
+	dir := t.TempDir()
+	font := filepath.Join(dir, "kai.ttf")
+	if err := os.WriteFile(font, []byte("\x00\x01\x00\x00glyf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckFontFile(filepath.Join("..", "go.mod")); err == nil {
+		t.Error("want error for non-font file")
+	}
+
+	link := dedaoFonts[0].URL
+	m := &FontManager{dir: dir, checked: true, verified: map[string]string{}}
+	m.manifest = map[string]fontEntry{link: {File: "kai.ttf", Size: 8, SHA256: sha256Hex([]byte("\x00\x01\x00\x00glyf"))}}
+	if file, err := m.cached(link); err != nil || file != font {
+		t.Errorf("cached = %q, %v", file, err)
+	}
+	m.manifest[link] = fontEntry{File: "kai.ttf", Size: 8, SHA256: "0"}
+	if _, err := m.cached(link); err == nil {
+		t.Error("want error for checksum mismatch")
+	}
+
+	m.Configure("", []WebFont{{Family: "FZKai-Z03", File: font}})
+	css := m.FontFaceCSS()
+	if strings.Count(css, `font-family: "FZKai-Z03"`) != 1 || !strings.Contains(css, fileURL(font)) {
+		t.Errorf("registered font not used: %s", css)
+	}
+	if !strings.Contains(css, dedaoFonts[len(dedaoFonts)-1].URL) {
+		t.Errorf("uncached font should keep remote url: %s", css)
+	}
+	if used := m.Used(`<p style="font-family: FZKai-Z03">`); len(used) != 1 || used[0].Path != font {
+		t.Errorf("Used = %+v", used)
+	}
+}
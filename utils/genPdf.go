@@ -6,22 +6,27 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
 )
 
 type PdfOption struct {
-	FileName  string
-	CoverPath string
-	PageSize  string
-	Toc       bool
+	FileName     string
+	CoverPath    string       // 封面 HTML 文件，相对路径时相对于程序所在目录
+	Title        string       // 书名，用于页眉页脚的 {book}
+	Profile      PrintProfile // 打印配置，为空时使用 a4
+	Toc          bool
+	OutlineDepth uint // 书签层级，0 时使用 wkhtmltopdf 默认值
 }
 
 func (p *PdfOption) GenPdf(buf *bytes.Buffer) (err error) {
+	if p.Profile.Width == 0 {
+		p.Profile, _ = ResolvePrintProfile("", nil)
+	}
 	pdfg, _ := wkhtmltopdf.NewPDFGenerator()
 	page := wkhtmltopdf.NewPageReader(buf)
-	page.FooterFontSize.Set(10)
-	page.FooterRight.Set("[page]")
+	p.setHeaderFooter(page)
 	page.DisableSmartShrinking.Set(true)
 
 	page.EnableLocalFileAccess.Set(true)
@@ -34,6 +39,9 @@ func (p *PdfOption) GenPdf(buf *bytes.Buffer) (err error) {
 			pdfg.Cover.EnableLocalFileAccess.Set(false)
 		}
 		dir = filepath.Join(dir, p.CoverPath)
+		if filepath.IsAbs(p.CoverPath) {
+			dir = p.CoverPath
+		}
 		if runtime.GOOS == "windows" {
 			pdfg.Cover.Input = dir
 		} else {
@@ -41,7 +49,10 @@ func (p *PdfOption) GenPdf(buf *bytes.Buffer) (err error) {
 		}
 	}
 
-	pdfg.Dpi.Set(300)
+	pdfg.Dpi.Set(p.Profile.Dpi)
+	if p.OutlineDepth > 0 {
+		pdfg.OutlineDepth.Set(p.OutlineDepth)
+	}
 	if p.Toc {
 		pdfg.TOC.Include = true
 		pdfg.TOC.TocHeaderText.Set("目 录")
@@ -53,12 +64,14 @@ func (p *PdfOption) GenPdf(buf *bytes.Buffer) (err error) {
 		pdfg.TOC.EnableTocBackLinks.Set(true)
 	}
 
-	pdfg.PageSize.Set(wkhtmltopdf.PageSizeA4)
+	pdfg.PageWidthUnit.Set(fmt.Sprintf("%gmm", p.Profile.Width))
+	pdfg.PageHeightUnit.Set(fmt.Sprintf("%gmm", p.Profile.Height))
 
-	pdfg.MarginTop.Set(15)
-	pdfg.MarginBottom.Set(15)
-	pdfg.MarginLeft.Set(15)
-	pdfg.MarginRight.Set(15)
+	top, right, bottom, left := p.Profile.Margins()
+	pdfg.MarginTop.Set(uint(top))
+	pdfg.MarginBottom.Set(uint(bottom))
+	pdfg.MarginLeft.Set(uint(left))
+	pdfg.MarginRight.Set(uint(right))
 	err = pdfg.Create()
 	if err != nil {
 		fmt.Printf("pdfg create err: %#v\n", err)
@@ -77,3 +90,36 @@ func (p *PdfOption) GenPdf(buf *bytes.Buffer) (err error) {
 	}
 	return
 }
+
+// setHeaderFooter 页眉页脚，模板中的 {section}、{page}、{pages} 替换为 wkhtmltopdf 的变量
+func (p *PdfOption) setHeaderFooter(page *wkhtmltopdf.PageReader) {
+	vars := map[string]string{
+		"{book}":    p.Title,
+		"{section}": "[section]",
+		"{page}":    "[page]",
+		"{pages}":   "[topage]",
+	}
+	size := uint(p.Profile.FontSize*0.8 + 0.5)
+	font := strings.Trim(strings.TrimSpace(strings.Split(p.Profile.FontFamily, ",")[0]), `"'`)
+
+	header := PrintTemplate(p.Profile.Header, vars)
+	if header != [3]string{} {
+		page.HeaderFontSize.Set(size)
+		if font != "" {
+			page.HeaderFontName.Set(font)
+		}
+		page.HeaderLeft.Set(header[0])
+		page.HeaderCenter.Set(header[1])
+		page.HeaderRight.Set(header[2])
+	}
+	footer := PrintTemplate(p.Profile.Footer, vars)
+	if footer != [3]string{} {
+		page.FooterFontSize.Set(size)
+		if font != "" {
+			page.FooterFontName.Set(font)
+		}
+		page.FooterLeft.Set(footer[0])
+		page.FooterCenter.Set(footer[1])
+		page.FooterRight.Set(footer[2])
+	}
+}
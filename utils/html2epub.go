@@ -14,6 +14,7 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/bmaupin/go-epub"
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/yann0917/dedao-dl/metrics"
 	"github.com/yann0917/dedao-dl/request"
 )
 
@@ -24,7 +25,7 @@ type EpubOptions struct {
 	Description string
 	Output      string
 	ImagesDir   string
-	FontsDir    string
+	EmbedFonts  bool // 嵌入文中使用的已下载或注册的字体
 	HTML        []HtmlContent
 	Verbose     bool
 	PTitle      map[int]string
@@ -46,6 +47,24 @@ type HtmlToEpub struct {
 	imgIdx       int
 }
 
+// Html2Epub 将 HTML 章节生成 EPUB，cover 为封面图片地址，为空或下载失败时不设置封面
+func Html2Epub(opt EpubOptions, cover string) (err error) {
+	defer metrics.ObserveConversion("html2epub", time.Now(), &err)
+	h2e := HtmlToEpub{EpubOptions: opt}
+	if cover != "" {
+		if coverByte, err := request.HTTPGet(cover); err == nil {
+			h2e.DefaultCover = coverByte
+		}
+	}
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", filepath.Base(opt.Output))
+	if err = h2e.Run(); err != nil {
+		fmt.Printf("\033[31;1m%s\033[0m\n", "失败"+err.Error())
+		return
+	}
+	fmt.Printf("\033[32;1m%s\033[0m\n", "完成")
+	return
+}
+
 func (h *HtmlToEpub) Run() (err error) {
 	if len(h.HTML) == 0 {
 		return errors.New("no .html file given")
@@ -59,8 +78,21 @@ func (h *HtmlToEpub) run() (err error) {
 		return
 	}
 
+	css := ""
+	if h.EmbedFonts {
+		var cssFile string
+		css, cssFile, err = h.embedFonts()
+		if cssFile != "" {
+			// 样式文件在写入 EPUB 时才读取
+			defer os.Remove(cssFile)
+		}
+		if err != nil {
+			return
+		}
+	}
+
 	for _, html := range h.HTML {
-		err = h.add(html)
+		err = h.add(html, css)
 		if err != nil {
 			err = fmt.Errorf("parse %#v failed: %s", html, err)
 			return
@@ -83,6 +115,9 @@ func (h *HtmlToEpub) genBook() error {
 }
 
 func (h *HtmlToEpub) setCover() (err error) {
+	if h.Cover == "" && len(h.DefaultCover) == 0 {
+		return
+	}
 	if h.Cover == "" {
 		temp, err := os.CreateTemp("", "html-to-epub")
 		if err != nil {
@@ -110,7 +145,39 @@ func (h *HtmlToEpub) setCover() (err error) {
 	return
 }
 
-func (h *HtmlToEpub) add(html HtmlContent) (err error) {
+// embedFonts 嵌入章节中使用的字体，返回引用字体的样式在 EPUB 中的路径和临时文件
+func (h *HtmlToEpub) embedFonts() (css, file string, err error) {
+	var content strings.Builder
+	for _, html := range h.HTML {
+		content.WriteString(html.Content)
+	}
+	used := Fonts.Used(content.String())
+	if len(used) == 0 {
+		return
+	}
+	var rules strings.Builder
+	for i, f := range used {
+		ref, err := h.book.AddFont(f.Path, fmt.Sprintf("font_%02d%s", i, filepath.Ext(f.Path)))
+		if err != nil {
+			return "", "", fmt.Errorf("can't add font %s: %s", f.Family, err)
+		}
+		fmt.Fprintf(&rules, "@font-face { font-family: %q; src: url(%q); }\n", f.Family, ref)
+	}
+	temp, err := os.CreateTemp("", "html-to-epub-*.css")
+	if err != nil {
+		return "", "", fmt.Errorf("can't create tempfile: %s", err)
+	}
+	file = temp.Name()
+	_, err = temp.WriteString(rules.String())
+	_ = temp.Close()
+	if err != nil {
+		return "", file, fmt.Errorf("can't write tempfile: %s", err)
+	}
+	css, err = h.book.AddCSS(file, "fonts.css")
+	return
+}
+
+func (h *HtmlToEpub) add(html HtmlContent, css string) (err error) {
 	refs := make(map[string]string)
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html.Content))
 	if err != nil {
@@ -140,35 +207,19 @@ func (h *HtmlToEpub) add(html HtmlContent) (err error) {
 	if err != nil {
 		return
 	}
-	if html.ChapterID != "cover.xhtml" {
-		if len(html.Toc) > 0 {
-			_, err = h.book.AddSection(content, html.Toc[0].Text, html.ChapterID, "")
-			if err != nil {
-				return
-			}
-		} else {
-			_, err = h.book.AddSection(content, "", html.ChapterID, "")
-			if err != nil {
-				return
-			}
-		}
+	if html.ChapterID == "cover.xhtml" {
+		return
+	}
+	title := html.TocText
+	if title == "" && len(html.Toc) > 0 {
+		title = html.Toc[0].Text
+	}
+	// TocLevel 大于 1 时作为上一级目录的子章节，如课程的章节和文章
+	if parent := h.PTitle[html.TocLevel-1]; html.TocLevel > 1 && parent != "" {
+		h.PTitle[html.TocLevel], err = h.book.AddSubSection(parent, content, title, html.ChapterID, css)
+	} else {
+		h.PTitle[1], err = h.book.AddSection(content, title, html.ChapterID, css)
 	}
-
-	// // FIXME: bug
-	// switch html.TocLevel {
-	// case 0, 1:
-	// 	if html.ChapterID != "cover.xhtml" {
-	// 		h.PTitle[html.TocLevel], err = h.book.AddSection(content, html.TocText, html.ChapterID, "")
-	// 		if err != nil {
-	// 			return
-	// 		}
-	// 	}
-	// case 2, 3, 4, 5, 6:
-	// 	h.PTitle[html.TocLevel], err = h.book.AddSubSection(h.PTitle[html.TocLevel-1], content, html.TocText, html.ChapterID, "")
-	// 	if err != nil {
-	// 		return
-	// 	}
-	// }
 	return
 }
 
@@ -207,42 +258,6 @@ func (h *HtmlToEpub) saveImages(doc *goquery.Document) map[string]string {
 	return downloads
 }
 
-// TODO:
-func (h *HtmlToEpub) getFontURLs(html HtmlContent) (downloads map[string]string, err error) {
-	downloads = make(map[string]string)
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html.Content))
-	if err != nil {
-		return
-	}
-
-	doc.Find("head>style").Each(func(i int, font *goquery.Selection) {
-		fmt.Printf("%#v\n", font.Text())
-		val, ok := font.Attr("font-family")
-		fmt.Printf("%#v, %#v\n", val, ok)
-		src, _ := font.Attr("url")
-		if !strings.HasPrefix(src, "http") {
-			return
-		}
-
-		_, exist := downloads[src]
-		if exist {
-			return
-		}
-
-		uri, err := url.Parse(src)
-		if err != nil {
-			log.Printf("parse %s fail: %s", src, err)
-			return
-		}
-		_ = os.MkdirAll(h.FontsDir, 0766)
-		localFile := filepath.Join(h.FontsDir, fmt.Sprintf("%s%s", MD5str(src), filepath.Ext(uri.Path)))
-
-		downloads[src] = localFile
-	})
-
-	return
-}
-
 func (h *HtmlToEpub) changeRef(htmlFile string, img *goquery.Selection, refs, downloads map[string]string) {
 	img.RemoveAttr("loading")
 	img.RemoveAttr("srcset")
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// navItem nav.xhtml 中的目录项，子目录嵌套在 ol 中
+type navItem struct {
+	A struct {
+		Href string `xml:"href,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"a"`
+	Items []navItem `xml:"ol>li"`
+}
+
+func TestHtml2EpubTocLevel(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "a.epub")
+	err := Html2Epub(EpubOptions{
+		Title:  "课程",
+		Output: output,
+		HTML: []HtmlContent{
+			{Content: "<h1>第一章</h1>", ChapterID: "chapter_001.xhtml", TocLevel: 1, TocText: "第一章"},
+			{Content: "<h1>第一讲</h1>", ChapterID: "article_1.xhtml", TocLevel: 2, TocText: "第一讲"},
+			{Content: "<h1>第二讲</h1>", ChapterID: "article_2.xhtml", TocLevel: 2, TocText: "第二讲"},
+			{Content: "<h1>第二章</h1>", ChapterID: "chapter_002.xhtml", TocLevel: 1, TocText: "第二章"},
+			{Content: "<h1>第三讲</h1>", ChapterID: "article_3.xhtml", TocLevel: 2, TocText: "第三讲"},
+			// 没有章节的文章为一级目录
+			{Content: "<p>附录</p>", ChapterID: "appendix.xhtml", TocLevel: 1, TocText: "附录"},
+		},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// toc.ncx 由 go-epub 生成为一级，层级只体现在 nav.xhtml 中
+	var nav struct {
+		Items []navItem `xml:"body>nav>ol>li"`
+	}
+	for _, f := range r.File {
+		if filepath.Base(f.Name) != "nav.xhtml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if err := xml.Unmarshal(data, &nav); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	var walk func(items []navItem, indent string)
+	walk = func(items []navItem, indent string) {
+		for _, item := range items {
+			got = append(got, indent+item.A.Text+" "+filepath.Base(item.A.Href))
+			walk(item.Items, indent+"  ")
+		}
+	}
+	walk(nav.Items, "")
+	want := []string{
+		"第一章 chapter_001.xhtml",
+		"  第一讲 article_1.xhtml",
+		"  第二讲 article_2.xhtml",
+		"第二章 chapter_002.xhtml",
+		"  第三讲 article_3.xhtml",
+		"附录 appendix.xhtml",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("toc =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -3,14 +3,24 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/html"
 	"github.com/gomarkdown/markdown/parser"
+	"github.com/yann0917/dedao-dl/metrics"
 )
 
-func Md2Pdf(path, title string, md []byte) (err error) {
+func Md2Pdf(path, title string, md []byte, profile PrintProfile) (err error) {
+	return Html2Pdf(path, title, MdToHTML(md), profile)
+}
+
+// Html2Pdf 将 HTML 片段生成 PDF，profile 为打印配置
+func Html2Pdf(path, title string, body []byte, profile PrintProfile) (err error) {
+	defer metrics.ObserveConversion("html2pdf", time.Now(), &err)
 	title = FileName(title, "pdf")
 	filePreName := filepath.Join(path, title)
 	fileName, err := FilePath(filePreName, "", false)
@@ -19,14 +29,14 @@ func Md2Pdf(path, title string, md []byte) (err error) {
 	}
 	buf := new(bytes.Buffer)
 
-	h := mdToHTML(md)
-	article := genHeadHtml() + string(h) + `
+	article := genHeadHtml(profile) + string(body) + `
 </body>
 </html>`
 	buf.Write([]byte(article))
 	pdf := PdfOption{
 		FileName: fileName,
-		PageSize: "A4",
+		Title:    strings.TrimSuffix(title, ".pdf"),
+		Profile:  profile,
 		Toc:      false,
 	}
 	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", title)
@@ -34,27 +44,56 @@ func Md2Pdf(path, title string, md []byte) (err error) {
 	return
 }
 
-func genHeadHtml() (result string) {
-	result = `<!DOCTYPE html>
-<html>
-<head>
-	<meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<meta http-equiv="Content-Type" content="text/html; charset=utf-8" />
-	<style>
-		@font-face { font-family: "FZFangSong-Z02"; src:local("FZFangSong-Z02"), url("https://imgcdn.umiwi.com/ttf/fangzhengfangsong_gbk.ttf"); }
-		@font-face { font-family: "FZKai-Z03"; src:local("FZFangSong-Z02S"), url("https://imgcdn.umiwi.com/ttf/0315911813008928624065681028886857980055.ttf"); }
-		@font-face { font-family: "FZKai-Z03"; src:local("FZKai-Z03"), url("https://imgcdn.umiwi.com/ttf/fangzhengkaiti_gbk.ttf"); }
-		@font-face { font-family: "PingFang SC"; src:local("PingFang SC"); }
-		@font-face { font-family: "DeDaoJinKai"; src:local("DeDaoJinKai"), url("https://imgcdn.umiwi.com/ttf/dedaojinkaiw03.ttf");}
-		@font-face { font-family: "Source Code Pro"; src:local("Source Code Pro"), url("https://imgcdn.umiwi.com/ttf/0315911806889993935644188722660020367983.ttf"); }
+// HtmlBook2Pdf 将合并后的文章 HTML 生成一个 PDF，cover 为封面 HTML 片段，为空时不生成封面
+// 目录和书签由 h1、h2 生成，如 h1 为章节、h2 为文章
+func HtmlBook2Pdf(path, title string, cover, body []byte, profile PrintProfile) (err error) {
+	bookTitle := title
+	defer metrics.ObserveConversion("html2pdf", time.Now(), &err)
+	title = FileName(title, "pdf")
+	fileName, err := FilePath(filepath.Join(path, title), "", false)
+	if err != nil {
+		return err
+	}
+	pdf := PdfOption{
+		FileName:     fileName,
+		Title:        bookTitle,
+		Profile:      profile,
+		Toc:          true,
+		OutlineDepth: 2,
+	}
+	if len(cover) > 0 {
+		coverFile := filepath.Join(path, "."+title+".cover.html")
+		if pdf.CoverPath, err = filepath.Abs(coverFile); err != nil {
+			return err
+		}
+		if err = os.WriteFile(pdf.CoverPath, []byte(genHeadHtml(profile)+string(cover)+"\n</body>\n</html>"), 0644); err != nil {
+			return err
+		}
+		defer os.Remove(pdf.CoverPath) // nolint
+	}
+	buf := bytes.NewBufferString(genHeadHtml(profile) + string(body) + "\n</body>\n</html>")
+	fmt.Printf("正在生成文件：【\033[37;1m%s\033[0m】 ", title)
+	return pdf.GenPdf(buf)
+}
+
+// ArticleCSS 文章排版样式，PDF 和单文件 HTML 共用
+const ArticleCSS = `
 		table, tr, td, th, tbody, thead, tfoot {page-break-inside: avoid !important;}
 		img { page-break-inside: avoid; max-width: 100% !important;}
 		img.epub-footnote { margin-right:5px;display: inline;font-size: 12px;}
 		body {font-family: PingFang SC,Arial,sans-serif,Source Code Pro;color: #333;text-align: left;line-height: 1.8;}
 		em {font-style: normal;}
 		h2>code { background-color: rgb(255, 96, 2);padding: 0.5%;border-radius: 10%;color: white;}
-		p>em {color: rgb(255, 96, 2);}
+		p>em {color: rgb(255, 96, 2);}`
+
+func genHeadHtml(profile PrintProfile) (result string) {
+	result = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<meta http-equiv="Content-Type" content="text/html; charset=utf-8" />
+	<style>` + Fonts.FontFaceCSS() + ArticleCSS + profile.StyleCSS() + `
 	</style>
 </head>
 <body>
@@ -62,7 +101,8 @@ func genHeadHtml() (result string) {
 	return
 }
 
-func mdToHTML(md []byte) []byte {
+// MdToHTML markdown 转换为 html 片段
+func MdToHTML(md []byte) []byte {
 	// create markdown parser with extensions
 	extensions := parser.CommonExtensions | parser.AutoHeadingIDs | parser.NoEmptyLineBeforeBlock
 	p := parser.NewWithExtensions(extensions)
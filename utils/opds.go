@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	atomNS = "http://www.w3.org/2005/Atom"
+	dcNS   = "http://purl.org/dc/terms/"
+	opdsNS = "http://opds-spec.org/2010/catalog"
+	osNS   = "http://a9.com/-/spec/opensearch/1.1/"
+
+	// OpdsAcquisitionType 书籍目录的 Content-Type
+	OpdsAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	// OpdsNavigationType 导航目录的 Content-Type
+	OpdsNavigationType = "application/atom+xml;profile=opds-catalog;kind=navigation"
+
+	// OpdsRelAcquisition 下载链接
+	OpdsRelAcquisition = "http://opds-spec.org/acquisition"
+	// OpdsRelImage 封面
+	OpdsRelImage = "http://opds-spec.org/image"
+	// OpdsRelThumbnail 封面缩略图
+	OpdsRelThumbnail = "http://opds-spec.org/image/thumbnail"
+	// OpdsRelSubsection 子目录
+	OpdsRelSubsection = "subsection"
+)
+
+// OpdsFeed OPDS 1.2 目录
+type OpdsFeed struct {
+	ID          string
+	Title       string
+	Updated     time.Time
+	Acquisition bool   // true: 书籍列表, false: 导航目录
+	Self        string // 当前目录地址
+	Start       string // 根目录地址
+	Search      string // OpenSearch 描述文件地址
+	Entries     []OpdsEntry
+}
+
+// OpdsEntry OPDS 条目，书籍或导航
+type OpdsEntry struct {
+	ID         string
+	Title      string
+	Updated    time.Time
+	Authors    []string
+	Summary    string
+	Content    string
+	Publisher  string
+	Issued     string
+	Language   string
+	Categories []string
+	Links      []OpdsLink
+}
+
+// OpdsLink OPDS 链接
+type OpdsLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Dc      string      `xml:"xmlns:dc,attr"`
+	Opds    string      `xml:"xmlns:opds,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Links   []OpdsLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Publisher  string         `xml:"dc:publisher,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Language   string         `xml:"dc:language,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Links      []OpdsLink     `xml:"link"`
+}
+
+// WriteOPDS 输出 OPDS 1.2 Atom 目录
+func (f *OpdsFeed) WriteOPDS(w io.Writer) error {
+	kind := OpdsNavigationType
+	if f.Acquisition {
+		kind = OpdsAcquisitionType
+	}
+	feed := atomFeed{
+		Xmlns:   atomNS,
+		Dc:      dcNS,
+		Opds:    opdsNS,
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: atomTime(f.Updated),
+		Author:  &atomPerson{Name: "dedao-dl"},
+	}
+	if f.Self != "" {
+		feed.Links = append(feed.Links, OpdsLink{Rel: "self", Href: f.Self, Type: kind})
+	}
+	if f.Start != "" {
+		feed.Links = append(feed.Links, OpdsLink{Rel: "start", Href: f.Start, Type: OpdsNavigationType})
+	}
+	if f.Search != "" {
+		feed.Links = append(feed.Links, OpdsLink{Rel: "search", Href: f.Search, Type: "application/opensearchdescription+xml"})
+	}
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Updated:   atomTime(e.Updated),
+			Publisher: e.Publisher,
+			Issued:    e.Issued,
+			Language:  e.Language,
+			Links:     e.Links,
+		}
+		for _, author := range e.Authors {
+			entry.Authors = append(entry.Authors, atomPerson{Name: author})
+		}
+		for _, c := range e.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: c, Label: c})
+		}
+		if e.Summary != "" {
+			entry.Summary = &atomText{Type: "text", Value: e.Summary}
+		}
+		if e.Content != "" {
+			entry.Content = &atomText{Type: "text", Value: e.Content}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(feed)
+}
+
+type openSearch struct {
+	XMLName        xml.Name      `xml:"OpenSearchDescription"`
+	Xmlns          string        `xml:"xmlns,attr"`
+	ShortName      string        `xml:"ShortName"`
+	Description    string        `xml:"Description"`
+	InputEncoding  string        `xml:"InputEncoding"`
+	OutputEncoding string        `xml:"OutputEncoding"`
+	URL            openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// WriteOpenSearch 输出 OpenSearch 描述文件，template 中使用 {searchTerms} 作为关键词占位
+func WriteOpenSearch(w io.Writer, name, template string) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(openSearch{
+		Xmlns:          osNS,
+		ShortName:      name,
+		Description:    name,
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URL:            openSearchURL{Type: OpdsAcquisitionType, Template: template},
+	})
+}
+
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteOPDS(t *testing.T) {
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CST", 8*3600))
+	feed := &OpdsFeed{
+		ID:          "urn:dedao-dl:all",
+		Title:       "全部电子书",
+		Updated:     updated,
+		Acquisition: true,
+		Self:        "http://localhost/opds/all",
+		Start:       "http://localhost/opds",
+		Search:      "http://localhost/opds/opensearch.xml",
+		Entries: []OpdsEntry{
+			{
+				ID:         "urn:dedao-dl:ebook:1",
+				Title:      "书名",
+				Updated:    updated,
+				Authors:    []string{"作者甲", "作者乙"},
+				Summary:    "摘要",
+				Content:    "<简介>",
+				Publisher:  "出版社",
+				Issued:     "2020-01-01",
+				Language:   "zh",
+				Categories: []string{"历史"},
+				Links: []OpdsLink{
+					{Rel: OpdsRelImage, Href: "http://img/cover.jpg", Type: "image/jpeg"},
+					{Rel: OpdsRelAcquisition, Href: "http://localhost/books/a.epub", Type: "application/epub+zip", Title: "EPUB"},
+				},
+			},
+			{ID: "urn:dedao-dl:ebook:2", Title: "无作者"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := feed.WriteOPDS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("missing xml header: %s", out[:100])
+	}
+	for _, want := range []string{
+		`xmlns="` + atomNS + `"`,
+		`xmlns:dc="` + dcNS + `"`,
+		`xmlns:opds="` + opdsNS + `"`,
+		`<updated>2024-01-01T19:04:05Z</updated>`,
+		`<dc:publisher>出版社</dc:publisher>`,
+		`<dc:issued>2020-01-01</dc:issued>`,
+		`<category term="历史" label="历史"></category>`,
+		`<content type="text">&lt;简介&gt;</content>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("opds missing %s", want)
+		}
+	}
+
+	var parsed struct {
+		ID    string     `xml:"id"`
+		Links []OpdsLink `xml:"link"`
+		Entry []struct {
+			Title   string `xml:"title"`
+			Authors []struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			Summary *struct{}  `xml:"summary"`
+			Links   []OpdsLink `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ID != feed.ID {
+		t.Errorf("id = %q", parsed.ID)
+	}
+	wantLinks := []OpdsLink{
+		{Rel: "self", Href: feed.Self, Type: OpdsAcquisitionType},
+		{Rel: "start", Href: feed.Start, Type: OpdsNavigationType},
+		{Rel: "search", Href: feed.Search, Type: "application/opensearchdescription+xml"},
+	}
+	if len(parsed.Links) != len(wantLinks) {
+		t.Fatalf("feed links = %+v", parsed.Links)
+	}
+	for i, want := range wantLinks {
+		if parsed.Links[i] != want {
+			t.Errorf("feed link %d = %+v, want %+v", i, parsed.Links[i], want)
+		}
+	}
+	if len(parsed.Entry) != 2 {
+		t.Fatalf("entries = %d", len(parsed.Entry))
+	}
+	book := parsed.Entry[0]
+	if len(book.Authors) != 2 || book.Authors[0].Name != "作者甲" || book.Authors[1].Name != "作者乙" {
+		t.Errorf("authors = %+v", book.Authors)
+	}
+	if len(book.Links) != 2 || book.Links[1] != feed.Entries[0].Links[1] {
+		t.Errorf("entry links = %+v", book.Links)
+	}
+	if empty := parsed.Entry[1]; len(empty.Authors) != 0 || empty.Summary != nil || len(empty.Links) != 0 {
+		t.Errorf("empty entry = %+v", empty)
+	}
+}
+
+func TestWriteOPDSNavigation(t *testing.T) {
+	var buf bytes.Buffer
+	feed := &OpdsFeed{ID: "urn:dedao-dl:opds", Title: "得到电子书", Self: "http://localhost/opds"}
+	if err := feed.WriteOPDS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<link rel="self" href="http://localhost/opds" type="`+OpdsNavigationType+`"></link>`) {
+		t.Errorf("navigation self link: %s", out)
+	}
+	if strings.Contains(out, `rel="start"`) || strings.Contains(out, `rel="search"`) {
+		t.Errorf("unexpected start/search link: %s", out)
+	}
+}
+
+func TestWriteOpenSearch(t *testing.T) {
+	var buf bytes.Buffer
+	template := "http://localhost/opds/search?q={searchTerms}"
+	if err := WriteOpenSearch(&buf, "得到电子书", template); err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		XMLName   xml.Name
+		ShortName string `xml:"ShortName"`
+		URL       struct {
+			Type     string `xml:"type,attr"`
+			Template string `xml:"template,attr"`
+		} `xml:"Url"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.XMLName.Space != osNS || parsed.XMLName.Local != "OpenSearchDescription" {
+		t.Errorf("root = %+v", parsed.XMLName)
+	}
+	if parsed.ShortName != "得到电子书" || parsed.URL.Template != template || parsed.URL.Type != OpdsAcquisitionType {
+		t.Errorf("opensearch = %+v", parsed)
+	}
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// PodcastFeed podcast channel
+type PodcastFeed struct {
+	Title       string
+	Link        string
+	Description string
+	Author      string
+	Image       string
+	Language    string
+	Episodes    []PodcastEpisode
+}
+
+// PodcastEpisode podcast item
+type PodcastEpisode struct {
+	GUID        string
+	Title       string
+	Description string
+	URL         string
+	Size        int64
+	MimeType    string
+	Duration    int // 秒
+	PubDate     time.Time
+	Episode     int
+	Image       string
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Itunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string       `xml:"title"`
+	Link           string       `xml:"link"`
+	Description    string       `xml:"description"`
+	Language       string       `xml:"language,omitempty"`
+	LastBuildDate  string       `xml:"lastBuildDate"`
+	ItunesAuthor   string       `xml:"itunes:author,omitempty"`
+	ItunesSummary  string       `xml:"itunes:summary,omitempty"`
+	ItunesImage    *itunesImage `xml:"itunes:image,omitempty"`
+	Image          *rssImage    `xml:"image,omitempty"`
+	ItunesType     string       `xml:"itunes:type"`
+	ItunesExplicit string       `xml:"itunes:explicit"`
+	Items          []rssItem    `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	GUID           rssGUID      `xml:"guid"`
+	Title          string       `xml:"title"`
+	Description    cdata        `xml:"description"`
+	PubDate        string       `xml:"pubDate,omitempty"`
+	Enclosure      rssEnclosure `xml:"enclosure"`
+	ItunesDuration string       `xml:"itunes:duration,omitempty"`
+	ItunesEpisode  int          `xml:"itunes:episode,omitempty"`
+	ItunesSummary  string       `xml:"itunes:summary,omitempty"`
+	ItunesImage    *itunesImage `xml:"itunes:image,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// WriteRSS 输出 RSS 2.0 + iTunes 播客订阅
+func (f *PodcastFeed) WriteRSS(w io.Writer) error {
+	channel := rssChannel{
+		Title:          f.Title,
+		Link:           f.Link,
+		Description:    f.Description,
+		Language:       f.Language,
+		LastBuildDate:  time.Now().Format(time.RFC1123Z),
+		ItunesAuthor:   f.Author,
+		ItunesSummary:  f.Description,
+		ItunesType:     "serial",
+		ItunesExplicit: "false",
+	}
+	if f.Image != "" {
+		channel.ItunesImage = &itunesImage{Href: f.Image}
+		channel.Image = &rssImage{URL: f.Image, Title: f.Title, Link: f.Link}
+	}
+	for _, e := range f.Episodes {
+		mimeType := e.MimeType
+		if mimeType == "" {
+			mimeType = "audio/mpeg"
+		}
+		item := rssItem{
+			GUID:           rssGUID{Value: e.GUID},
+			Title:          e.Title,
+			Description:    cdata{Value: e.Description},
+			Enclosure:      rssEnclosure{URL: e.URL, Length: e.Size, Type: mimeType},
+			ItunesDuration: FormatDuration(e.Duration),
+			ItunesEpisode:  e.Episode,
+			ItunesSummary:  e.Description,
+		}
+		if !e.PubDate.IsZero() {
+			item.PubDate = e.PubDate.Format(time.RFC1123Z)
+		}
+		if e.Image != "" {
+			item.ItunesImage = &itunesImage{Href: e.Image}
+		}
+		channel.Items = append(channel.Items, item)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(rss{Version: "2.0", Itunes: itunesNS, Channel: channel})
+}
+
+// FormatDuration 秒数转换为 HH:MM:SS
+func FormatDuration(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+}
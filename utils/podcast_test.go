@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	for seconds, want := range map[int]string{0: "", -1: "", 59: "00:00:59", 61: "00:01:01", 3725: "01:02:05"} {
+		if got := FormatDuration(seconds); got != want {
+			t.Errorf("FormatDuration(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestWriteRSS(t *testing.T) {
+	feed := &PodcastFeed{
+		Title:       "课程",
+		Link:        "https://example.com",
+		Description: "简介",
+		Author:      "讲师",
+		Image:       "https://example.com/logo.png",
+		Episodes: []PodcastEpisode{
+			{GUID: "e1", Title: "第一讲", Description: "<b>摘要</b>", URL: "https://example.com/1.mp3", Size: 1024, Duration: 65, Episode: 1, PubDate: time.Unix(0, 0)},
+			{GUID: "e2", Title: "第二讲", URL: "https://example.com/2.m4a", MimeType: "audio/mp4"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := feed.WriteRSS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) || !strings.Contains(out, `xmlns:itunes="`+itunesNS+`"`) {
+		t.Errorf("header = %s", out[:200])
+	}
+	for _, want := range []string{
+		`<itunes:image href="https://example.com/logo.png"></itunes:image>`,
+		`<enclosure url="https://example.com/1.mp3" length="1024" type="audio/mpeg"></enclosure>`,
+		`<enclosure url="https://example.com/2.m4a" length="0" type="audio/mp4"></enclosure>`,
+		`<description><![CDATA[<b>摘要</b>]]></description>`,
+		`<itunes:duration>00:01:05</itunes:duration>`,
+		`<itunes:episode>1</itunes:episode>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rss missing %s", want)
+		}
+	}
+
+	var parsed struct {
+		Channel struct {
+			Items []struct {
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if items := parsed.Channel.Items; len(items) != 2 || items[0].GUID != "e1" || items[0].PubDate == "" || items[1].PubDate != "" {
+		t.Errorf("items = %+v", items)
+	}
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PrintProfile PDF 打印配置：纸张、页边距、字体、页眉页脚和自定义样式
+type PrintProfile struct {
+	PageSize   string    `json:"page_size"`   // 纸张：A3、A4、A5、B5、Letter、Legal，width、height 不为 0 时忽略
+	Width      float64   `json:"width"`       // 自定义纸张宽度，单位 mm
+	Height     float64   `json:"height"`      // 自定义纸张高度，单位 mm
+	Margin     []float64 `json:"margin"`      // 页边距，单位 mm，同 CSS 可写 1、2 或 4 个值（上 右 下 左）
+	Dpi        uint      `json:"dpi"`         // wkhtmltopdf 分辨率
+	FontFamily string    `json:"font_family"` // 正文字体，同 CSS font-family，wkhtmltopdf 使用，内置引擎使用 PDF.font
+	FontSize   float64   `json:"font_size"`   // 正文字号，单位 pt
+	LineHeight float64   `json:"line_height"` // 行高倍数
+	Header     string    `json:"header"`      // 页眉模板，"左|中|右"，设为空格时不显示
+	Footer     string    `json:"footer"`      // 页脚模板，同页眉
+	CSS        string    `json:"css"`         // 追加的 CSS，以 .css 结尾时读取该文件
+}
+
+// DefaultPrintProfile 默认的打印配置名称
+const DefaultPrintProfile = "a4"
+
+// printProfiles 内置打印配置
+var printProfiles = map[string]PrintProfile{
+	"a4":          {PageSize: "A4", Margin: []float64{15}, Dpi: 300, FontSize: 12, LineHeight: 1.8, Footer: "||{page}"},
+	"a5":          {PageSize: "A5", Margin: []float64{12}, Dpi: 300, FontSize: 11, LineHeight: 1.7, Footer: "||{page}"},
+	"letter":      {PageSize: "Letter", Margin: []float64{15}, Dpi: 300, FontSize: 12, LineHeight: 1.8, Footer: "||{page}"},
+	"6inch":       {Width: 90, Height: 120, Margin: []float64{6, 5, 10, 5}, Dpi: 300, FontSize: 10, LineHeight: 1.6, Footer: "{page}"},
+	"large-print": {PageSize: "A4", Margin: []float64{20}, Dpi: 300, FontSize: 16, LineHeight: 2, Footer: "{section}||{page}"},
+}
+
+// pageSizes 纸张尺寸，单位 mm
+var pageSizes = map[string][2]float64{
+	"a3":     {297, 420},
+	"a4":     {210, 297},
+	"a5":     {148, 210},
+	"b5":     {176, 250},
+	"letter": {215.9, 279.4},
+	"legal":  {215.9, 355.6},
+}
+
+// PrintProfileNames 内置和自定义的打印配置名称
+func PrintProfileNames(custom map[string]PrintProfile) []string {
+	var names []string
+	for name := range printProfiles {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := printProfiles[strings.ToLower(name)]; !ok {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolvePrintProfile 按名称查找打印配置，名称为空时使用 a4
+// custom 中与内置配置同名的配置只覆盖其中不为空的字段，其他自定义配置以 a4 为基础
+func ResolvePrintProfile(name string, custom map[string]PrintProfile) (p PrintProfile, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultPrintProfile
+	}
+	p, builtin := printProfiles[name]
+	if !builtin {
+		p = printProfiles[DefaultPrintProfile]
+	}
+	found := builtin
+	for k, v := range custom {
+		if strings.ToLower(k) == name {
+			p, found = p.merge(v), true
+		}
+	}
+	if !found {
+		return p, fmt.Errorf("打印配置不存在: %s，可选 %s", name, strings.Join(PrintProfileNames(custom), "、"))
+	}
+
+	if p.Width <= 0 || p.Height <= 0 {
+		size, ok := pageSizes[strings.ToLower(p.PageSize)]
+		if !ok {
+			return p, fmt.Errorf("不支持的纸张: %s", p.PageSize)
+		}
+		p.Width, p.Height = size[0], size[1]
+	}
+	switch len(p.Margin) {
+	case 1, 2, 4:
+	default:
+		return p, fmt.Errorf("页边距应为 1、2 或 4 个值: %v", p.Margin)
+	}
+	if strings.HasSuffix(strings.ToLower(p.CSS), ".css") {
+		css, err := os.ReadFile(p.CSS)
+		if err != nil {
+			return p, err
+		}
+		p.CSS = string(css)
+	}
+	return p, nil
+}
+
+// merge 用 o 中不为空的字段覆盖
+func (p PrintProfile) merge(o PrintProfile) PrintProfile {
+	if o.PageSize != "" {
+		p.PageSize, p.Width, p.Height = o.PageSize, 0, 0
+	}
+	if o.Width > 0 && o.Height > 0 {
+		p.Width, p.Height = o.Width, o.Height
+	}
+	if len(o.Margin) > 0 {
+		p.Margin = o.Margin
+	}
+	if o.Dpi > 0 {
+		p.Dpi = o.Dpi
+	}
+	if o.FontFamily != "" {
+		p.FontFamily = o.FontFamily
+	}
+	if o.FontSize > 0 {
+		p.FontSize = o.FontSize
+	}
+	if o.LineHeight > 0 {
+		p.LineHeight = o.LineHeight
+	}
+	if o.Header != "" {
+		p.Header = o.Header
+	}
+	if o.Footer != "" {
+		p.Footer = o.Footer
+	}
+	if o.CSS != "" {
+		p.CSS = o.CSS
+	}
+	return p
+}
+
+// Margins 上、右、下、左页边距，单位 mm
+func (p PrintProfile) Margins() (top, right, bottom, left float64) {
+	switch len(p.Margin) {
+	case 1:
+		return p.Margin[0], p.Margin[0], p.Margin[0], p.Margin[0]
+	case 2:
+		return p.Margin[0], p.Margin[1], p.Margin[0], p.Margin[1]
+	case 4:
+		return p.Margin[0], p.Margin[1], p.Margin[2], p.Margin[3]
+	}
+	return 15, 15, 15, 15
+}
+
+// PrintTemplate 将页眉页脚模板拆分为左、中、右三部分，并替换其中的变量
+// 只有一部分时居中，两部分时为左、右；vars 如 {book}、{section}、{page}、{pages}
+func PrintTemplate(tmpl string, vars map[string]string) (parts [3]string) {
+	if strings.TrimSpace(tmpl) == "" {
+		return
+	}
+	var pairs []string
+	for k, v := range vars {
+		pairs = append(pairs, k, v)
+	}
+	r := strings.NewReplacer(pairs...)
+	s := strings.SplitN(tmpl, "|", 3)
+	switch len(s) {
+	case 1:
+		parts[1] = s[0]
+	case 2:
+		parts[0], parts[2] = s[0], s[1]
+	default:
+		copy(parts[:], s)
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(r.Replace(parts[i]))
+	}
+	return
+}
+
+// StyleCSS 正文字体、字号、行高和自定义 CSS
+func (p PrintProfile) StyleCSS() string {
+	var b strings.Builder
+	b.WriteString("\n\t\tbody {")
+	if p.FontFamily != "" {
+		fmt.Fprintf(&b, "font-family: %s;", p.FontFamily)
+	}
+	if p.FontSize > 0 {
+		fmt.Fprintf(&b, "font-size: %gpt;", p.FontSize)
+	}
+	if p.LineHeight > 0 {
+		fmt.Fprintf(&b, "line-height: %g;", p.LineHeight)
+	}
+	b.WriteString("}")
+	if css := strings.TrimSpace(p.CSS); css != "" {
+		b.WriteString("\n" + css)
+	}
+	return b.String()
+}
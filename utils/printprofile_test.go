@@ -0,0 +1,52 @@
+package utils
+
+import "testing"
+
+func TestResolvePrintProfile(t *testing.T) {
+	custom := map[string]PrintProfile{
+		"A5":     {FontSize: 13},
+		"kindle": {Width: 91, Height: 122, Margin: []float64{5, 4}},
+	}
+
+	p, err := ResolvePrintProfile("a5", custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FontSize != 13 || p.Width != 148 || p.Height != 210 || p.Footer != "||{page}" {
+		t.Errorf("a5 = %+v", p)
+	}
+
+	p, err = ResolvePrintProfile("kindle", custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top, right, bottom, left := p.Margins(); top != 5 || right != 4 || bottom != 5 || left != 4 {
+		t.Errorf("kindle margins = %v %v %v %v", top, right, bottom, left)
+	}
+	if p.Width != 91 || p.FontSize != 12 {
+		t.Errorf("kindle = %+v", p)
+	}
+
+	if _, err = ResolvePrintProfile("b6", custom); err == nil {
+		t.Error("want error for unknown profile")
+	}
+}
+
+func TestPrintTemplate(t *testing.T) {
+	vars := map[string]string{"{book}": "经济学", "{page}": "3"}
+	tests := []struct {
+		tmpl string
+		want [3]string
+	}{
+		{"", [3]string{}},
+		{" ", [3]string{}},
+		{"{page}", [3]string{"", "3", ""}},
+		{"{book}|{page}", [3]string{"经济学", "", "3"}},
+		{"{book}||第 {page} 页", [3]string{"经济学", "", "第 3 页"}},
+	}
+	for _, tt := range tests {
+		if got := PrintTemplate(tt.tmpl, vars); got != tt.want {
+			t.Errorf("PrintTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// SearchDoc 搜索索引中的文档
+type SearchDoc struct {
+	Title   string `json:"t"`
+	URL     string `json:"u"`
+	Path    string `json:"p"` // 面包屑，如 课程名 / 章节名
+	Snippet string `json:"s"`
+}
+
+// SearchIndex 静态站点使用的倒排索引，中日韩文字按相邻两字切分
+type SearchIndex struct {
+	Docs  []SearchDoc      `json:"docs"`
+	Index map[string][]int `json:"index"`
+}
+
+// NewSearchIndex new search index
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{Index: make(map[string][]int)}
+}
+
+// Add 添加文档，title 和 text 都参与索引
+func (s *SearchIndex) Add(doc SearchDoc, text string) {
+	id := len(s.Docs)
+	if doc.Snippet == "" {
+		doc.Snippet = Snippet(text, 120)
+	}
+	s.Docs = append(s.Docs, doc)
+	seen := make(map[string]bool)
+	for _, token := range SearchTokens(doc.Title + "\n" + text) {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		s.Index[token] = append(s.Index[token], id)
+	}
+}
+
+// WriteJS 以 js 脚本输出索引，file:// 协议下无法 fetch json，因此赋值给全局变量
+func (s *SearchIndex) WriteJS(w io.Writer, name string) error {
+	data, err := jsoniter.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(w, "var "+name+" = "); err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, ";\n")
+	return err
+}
+
+// SearchTokens 切分搜索词
+// 英文和数字按单词切分并转为小写，中日韩文字输出相邻两字，单独的一个字输出单字
+// 搜索单字时由页面脚本匹配包含该字的词
+func SearchTokens(text string) (tokens []string) {
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Snippet 截取文本摘要，合并连续空白
+func Snippet(text string, length int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > length {
+		return string(runes[:length]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTokens(t *testing.T) {
+	for text, want := range map[string][]string{
+		"经济学":              {"经济", "济学"},
+		"学":                {"学"},
+		"Hello, 世界 Go1.23": {"hello", "世界", "go1", "23"},
+		"选择A的学问":           {"选择", "a", "的学", "学问"},
+		"，。！":              nil,
+	} {
+		if got := SearchTokens(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchTokens(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	s := NewSearchIndex()
+	s.Add(SearchDoc{Title: "经济学", URL: "a.html"}, "经济学是一门研究选择的学问")
+	s.Add(SearchDoc{Title: "选择", URL: "b.html", Snippet: "摘要"}, "学习")
+
+	if got := s.Index["经济"]; !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("index[经济] = %v", got)
+	}
+	if got := s.Index["选择"]; !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("index[选择] = %v", got)
+	}
+	if _, ok := s.Index["经"]; ok {
+		t.Error("unigram should not be indexed")
+	}
+	if s.Docs[0].Snippet != "经济学是一门研究选择的学问" || s.Docs[1].Snippet != "摘要" {
+		t.Errorf("snippets = %q, %q", s.Docs[0].Snippet, s.Docs[1].Snippet)
+	}
+}
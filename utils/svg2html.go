@@ -11,10 +11,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/JoshVarga/svgparser"
 	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+	"github.com/yann0917/dedao-dl/metrics"
 	"github.com/yann0917/dedao-dl/request"
 )
 
@@ -89,6 +91,7 @@ var fnA, fnB = "", ""
 var tocLevel map[string]int
 
 func Svg2Html(title string, svgContents []*SvgContent, toc []EbookToc) (err error) {
+	defer metrics.ObserveConversion("svg2html", time.Now(), &err)
 	tocLevel = make(map[string]int, len(toc))
 	for _, ebookToc := range toc {
 		tocLevel[ebookToc.Text] = ebookToc.Level
@@ -117,7 +120,9 @@ func Svg2Html(title string, svgContents []*SvgContent, toc []EbookToc) (err erro
 	return
 }
 
-func Svg2Pdf(title string, svgContents []*SvgContent, toc []EbookToc) (err error) {
+// Svg2Pdf 将电子书生成 PDF，profile 为打印配置，字号、行高只影响原书未指定样式的文字
+func Svg2Pdf(title string, svgContents []*SvgContent, toc []EbookToc, profile PrintProfile) (err error) {
+	defer metrics.ObserveConversion("svg2pdf", time.Now(), &err)
 
 	path, err := Mkdir(OutputDir, "Ebook")
 	if err != nil {
@@ -148,6 +153,7 @@ func Svg2Pdf(title string, svgContents []*SvgContent, toc []EbookToc) (err error
 		buf.Write([]byte(chapter))
 		buf.WriteString(`<P style="page-break-before: always">`)
 	}
+	buf.WriteString("\n<style>" + profile.StyleCSS() + "\n</style>")
 
 	// write cover into cover.html file
 	coverPath, _ := FilePath(filepath.Join(path, FileName("cover", "")), "html", false)
@@ -157,7 +163,8 @@ func Svg2Pdf(title string, svgContents []*SvgContent, toc []EbookToc) (err error
 	pdf := PdfOption{
 		FileName:  fileName,
 		CoverPath: coverPath,
-		PageSize:  "A4",
+		Title:     title,
+		Profile:   profile,
 		Toc:       true,
 	}
 	err = pdf.GenPdf(buf)
@@ -165,34 +172,10 @@ func Svg2Pdf(title string, svgContents []*SvgContent, toc []EbookToc) (err error
 }
 
 func Svg2Epub(title string, svgContents []*SvgContent, opt EpubOptions) (err error) {
-	var htmlAll []HtmlContent
-	cover := ""
-	tocLevel = make(map[string]int, len(opt.Toc))
-	chapterToc := make(map[string][]EbookToc, len(opt.Toc))
-	for _, ebookToc := range opt.Toc {
-		tocLevel[ebookToc.Text] = ebookToc.Level
-		tagArr := strings.Split(ebookToc.Href, "#")
-		// footnote jump back and forth
-		if len(tagArr) > 0 {
-			chapterToc[tagArr[0]] = append(chapterToc[tagArr[0]], ebookToc)
-		}
-	}
-	// fmt.Println(chapterToc)
-
-	for k, svgContent := range svgContents {
-		chapter, coverUrl, err1 := OneByOneHtml(eBookTypeEpub, k, svgContent, opt.Toc)
-		if err1 != nil {
-			err = err1
-			return
-		}
-		if k == 0 {
-			cover = coverUrl
-		}
-		htmlAll = append(htmlAll, HtmlContent{
-			Content:   chapter,
-			ChapterID: svgContent.ChapterID,
-			Toc:       chapterToc[svgContent.ChapterID],
-		})
+	defer metrics.ObserveConversion("svg2epub", time.Now(), &err)
+	htmlAll, cover, err := EbookChapters(svgContents, opt.Toc)
+	if err != nil {
+		return err
 	}
 
 	path, err := Mkdir(OutputDir, "Ebook")
@@ -227,6 +210,36 @@ func Svg2Epub(title string, svgContents []*SvgContent, opt EpubOptions) (err err
 	return err
 }
 
+// EbookChapters 电子书各章节的 HTML 和封面图片地址，用于生成 EPUB、DOCX
+func EbookChapters(svgContents []*SvgContent, toc []EbookToc) (chapters []HtmlContent, cover string, err error) {
+	tocLevel = make(map[string]int, len(toc))
+	chapterToc := make(map[string][]EbookToc, len(toc))
+	for _, ebookToc := range toc {
+		tocLevel[ebookToc.Text] = ebookToc.Level
+		tagArr := strings.Split(ebookToc.Href, "#")
+		// footnote jump back and forth
+		if len(tagArr) > 0 {
+			chapterToc[tagArr[0]] = append(chapterToc[tagArr[0]], ebookToc)
+		}
+	}
+
+	for k, svgContent := range svgContents {
+		chapter, coverUrl, err1 := OneByOneHtml(eBookTypeEpub, k, svgContent, toc)
+		if err1 != nil {
+			return nil, "", err1
+		}
+		if k == 0 {
+			cover = coverUrl
+		}
+		chapters = append(chapters, HtmlContent{
+			Content:   chapter,
+			ChapterID: svgContent.ChapterID,
+			Toc:       chapterToc[svgContent.ChapterID],
+		})
+	}
+	return
+}
+
 func genPdf(buf *bytes.Buffer, fileName, coverPath string) (err error) {
 	pdfg, _ := wkhtmltopdf.NewPDFGenerator()
 
@@ -679,13 +692,7 @@ func GenHeadHtml() (result string) {
 	<meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
 	<meta http-equiv="Content-Type" content="text/html; charset=utf-8" />
-	<style>
-		@font-face { font-family: "FZFangSong-Z02"; src:local("FZFangSong-Z02"), url("https://imgcdn.umiwi.com/ttf/fangzhengfangsong_gbk.ttf"); }
-		@font-face { font-family: "FZKai-Z03"; src:local("FZFangSong-Z02S"), url("https://imgcdn.umiwi.com/ttf/0315911813008928624065681028886857980055.ttf"); }
-		@font-face { font-family: "FZKai-Z03"; src:local("FZKai-Z03"), url("https://imgcdn.umiwi.com/ttf/fangzhengkaiti_gbk.ttf"); }
-		@font-face { font-family: "PingFang SC"; src:local("PingFang SC"); }
-		@font-face { font-family: "DeDaoJinKai"; src:local("DeDaoJinKai"), url("https://imgcdn.umiwi.com/ttf/dedaojinkaiw03.ttf");}
-		@font-face { font-family: "Source Code Pro"; src:local("Source Code Pro"), url("https://imgcdn.umiwi.com/ttf/0315911806889993935644188722660020367983.ttf"); }
+	<style>` + Fonts.FontFaceCSS() + `
 		table, tr, td, th, tbody, thead, tfoot {page-break-inside: avoid !important;}
 		img { page-break-inside: avoid; max-width: 100% !important;}
 		img.epub-footnote { margin-right:5px;display: inline;font-size: 12px;}